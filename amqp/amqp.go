@@ -1,16 +1,27 @@
 package amqp
 
 import (
+	"sync"
+
 	"github.com/streadway/amqp"
 )
 
+// Connection lazily dials Address and opens a single channel. It is safe for
+// concurrent use.
 type Connection struct {
 	Address    string
+	mu         sync.Mutex
 	connection *amqp.Connection
 	channel    *amqp.Channel
 }
 
 func (con *Connection) Connection() (c *amqp.Connection, e error) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+	return con.connect()
+}
+
+func (con *Connection) connect() (c *amqp.Connection, e error) {
 	if con.connection != nil {
 		return con.connection, nil
 	}
@@ -22,10 +33,12 @@ func (con *Connection) Connection() (c *amqp.Connection, e error) {
 }
 
 func (con *Connection) Channel() (c *amqp.Channel, e error) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
 	if con.channel != nil {
 		return con.channel, nil
 	}
-	connection, e := con.Connection()
+	connection, e := con.connect()
 	if e != nil {
 		return nil, e
 	}
@@ -37,12 +50,16 @@ func (con *Connection) Channel() (c *amqp.Channel, e error) {
 }
 
 func (con *Connection) Close() {
+	con.mu.Lock()
+	defer con.mu.Unlock()
 	if con.channel != nil {
 		con.channel.Close()
+		con.channel = nil
 	}
 
 	if con.connection != nil {
 		con.connection.Close()
+		con.connection = nil
 	}
 }
 
@@ -68,6 +85,18 @@ func (con *Connection) QueueDeclare(q *Queue) (e error) {
 	return e
 }
 
+func (con *Connection) ExchangeDeclare(ex *Exchange) error {
+	c, e := con.Channel()
+	if e != nil {
+		return e
+	}
+	kind := ex.Type
+	if kind == "" {
+		kind = "topic"
+	}
+	return c.ExchangeDeclare(ex.Name, kind, ex.Durable, ex.AutoDelete, ex.Internal, ex.NoWait, ex.Args)
+}
+
 type Consumer struct {
 	*Connection
 	*Queue
@@ -97,7 +126,13 @@ type Queue struct {
 }
 
 type Exchange struct {
-	Name string
+	Name       string
+	Type       string // defaults to "topic"
+	Durable    bool
+	AutoDelete bool
+	Internal   bool
+	NoWait     bool
+	Args       amqp.Table
 }
 
 type Binding struct {
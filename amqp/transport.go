@@ -0,0 +1,93 @@
+package amqp
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dynport/dgtk/pubsub"
+	"github.com/streadway/amqp"
+)
+
+// Transport implements pubsub.Transport. Messages are published to Exchange
+// with the message key as routing key. Received messages are consumed from
+// Queue which is bound to Exchange with every pattern in Patterns. The
+// exchange is declared before the first message is sent or received.
+type Transport struct {
+	*Connection
+	*Exchange
+	Queue    *Queue
+	Patterns []string // defaults to "#"
+
+	mu       sync.Mutex
+	declared bool
+}
+
+func (t *Transport) Send(m *pubsub.RemoteMessage) error {
+	if e := t.declareExchange(); e != nil {
+		return e
+	}
+	c, e := t.Channel()
+	if e != nil {
+		return e
+	}
+	return c.Publish(t.Exchange.Name, m.Key, false, false, publishing(m))
+}
+
+// declareExchange declares the exchange once, failed declarations are
+// retried with the next call.
+func (t *Transport) declareExchange() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.declared {
+		return nil
+	}
+	if e := t.ExchangeDeclare(t.Exchange); e != nil {
+		return e
+	}
+	t.declared = true
+	return nil
+}
+
+func publishing(m *pubsub.RemoteMessage) amqp.Publishing {
+	return amqp.Publishing{ContentType: m.ContentType, Body: m.Body}
+}
+
+func remoteMessage(del *amqp.Delivery) *pubsub.RemoteMessage {
+	return &pubsub.RemoteMessage{Key: del.RoutingKey, ContentType: del.ContentType, Body: del.Body}
+}
+
+func (t *Transport) Receive(handler func(m *pubsub.RemoteMessage)) error {
+	if t.Queue == nil {
+		return fmt.Errorf("queue must be set to receive messages")
+	}
+	e := t.declareExchange()
+	if e != nil {
+		return e
+	}
+	patterns := t.Patterns
+	if len(patterns) == 0 {
+		patterns = []string{"#"}
+	}
+	for _, p := range patterns {
+		e = t.BindingBind(&Binding{Queue: t.Queue, Exchange: t.Exchange, Pattern: p})
+		if e != nil {
+			return e
+		}
+	}
+	consumer := &Consumer{Connection: t.Connection, Queue: t.Queue, AutoAck: true}
+	c, e := consumer.Consume()
+	if e != nil {
+		return e
+	}
+	go func() {
+		for del := range c {
+			handler(remoteMessage(&del))
+		}
+	}()
+	return nil
+}
+
+func (t *Transport) Close() error {
+	t.Connection.Close()
+	return nil
+}
@@ -1,21 +1,19 @@
 module github.com/dynport/dgtk
 
-go 1.12
+go 1.19
 
 require (
 	github.com/aws/aws-sdk-go v1.19.5
-	github.com/codegangsta/inject v0.0.0-20150114235600-33e0aa1cb7c0 // indirect
 	github.com/codegangsta/martini v0.0.0-20170121215854-22fa46961aab
 	github.com/dynport/gocli v0.0.0-20160202104821-6d0b942bcd33
 	github.com/dynport/gocloud v0.0.0-20170424201259-f0f9b94f5d9c
 	github.com/dynport/gossh v0.0.0-20170809141523-122e3ee2a6b0
 	github.com/fsnotify/fsnotify v1.4.7
-	github.com/golang/protobuf v1.2.0 // indirect
+	github.com/go-sql-driver/mysql v1.7.1
 	github.com/google/go-github v17.0.0+incompatible
-	github.com/google/go-querystring v0.0.0-20170111101155-53e6ce116135 // indirect
 	github.com/julienschmidt/httprouter v1.2.0
 	github.com/lib/pq v1.0.0
-	github.com/mattn/go-runewidth v0.0.2 // indirect
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/moovweb/gokogiri v0.0.0-20180713195410-a1a828153468
 	github.com/olekukonko/tablewriter v0.0.1
 	github.com/pkg/errors v0.8.1
@@ -23,12 +21,26 @@ require (
 	github.com/smartystreets/goconvey v0.0.0-20190306220146-200a235640ff
 	github.com/streadway/amqp v0.0.0-20190312223743-14f78b41ce6d
 	github.com/stretchr/testify v1.3.0
-	golang.org/x/crypto v0.0.0-20190325154230-a5d413f7728c // indirect
-	golang.org/x/net v0.0.0-20190327214358-63eda1eb0650 // indirect
+	golang.org/x/crypto v0.0.0-20190325154230-a5d413f7728c
 	golang.org/x/oauth2 v0.0.0-20181203162652-d668ce993890
+	labix.org/v2/mgo v0.0.0-20140701140051-000000000287
+)
+
+require (
+	github.com/codegangsta/inject v0.0.0-20150114235600-33e0aa1cb7c0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/golang/protobuf v1.2.0 // indirect
+	github.com/google/go-querystring v0.0.0-20170111101155-53e6ce116135 // indirect
+	github.com/gopherjs/gopherjs v0.0.0-20181017120253-0766667cb4d1 // indirect
+	github.com/jmespath/go-jmespath v0.0.0-20180206201540-c2b33e8439af // indirect
+	github.com/jtolds/gls v4.20.0+incompatible // indirect
+	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
+	github.com/mattn/go-runewidth v0.0.2 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/smartystreets/assertions v0.0.0-20180927180507-b2de0cb4f26d // indirect
+	golang.org/x/net v0.0.0-20190327214358-63eda1eb0650 // indirect
 	golang.org/x/sync v0.0.0-20190227155943-e225da77a7e6 // indirect
 	golang.org/x/sys v0.0.0-20190322080309-f49334f85ddc // indirect
 	google.golang.org/appengine v1.0.0 // indirect
-	labix.org/v2/mgo v0.0.0-20140701140051-000000000287
 	launchpad.net/gocheck v0.0.0-20140225173054-000000000087 // indirect
 )
@@ -1,6 +1,10 @@
 package gosql
 
-import "database/sql"
+import (
+	"database/sql"
+
+	"github.com/dynport/dgtk/gosql/dialect"
+)
 
 // Dbi behaves like a db or transaction
 type Dbi interface {
@@ -9,3 +13,26 @@ type Dbi interface {
 	Prepare(query string) (*sql.Stmt, error)
 	Exec(query string, args ...interface{}) (sql.Result, error)
 }
+
+// WithDialect sets the dialect used for db, e.g. for transactions which do
+// not expose their driver. The dialect of a *sql.DB is detected otherwise.
+func WithDialect(db Dbi, d dialect.Dialect) Dbi {
+	return &dialectDbi{Dbi: db, dialect: d}
+}
+
+type dialectDbi struct {
+	Dbi
+	dialect dialect.Dialect
+}
+
+func (db *dialectDbi) Dialect() dialect.Dialect {
+	return db.dialect
+}
+
+// unwrap returns the connection wrapped by WithDialect.
+func unwrap(db Dbi) Dbi {
+	if w, ok := db.(*dialectDbi); ok {
+		return w.Dbi
+	}
+	return db
+}
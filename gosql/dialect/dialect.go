@@ -0,0 +1,327 @@
+// Package dialect contains the SQL differences between Postgres, MySQL and
+// SQLite needed by gosql and migrations.
+package dialect
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect describes how to talk to a specific database.
+type Dialect interface {
+	Name() string
+	// Placeholder returns the bind parameter of the i-th (1 based) argument.
+	Placeholder(i int) string
+	// Type returns the column type used to store values like v. A
+	// json.RawMessage is stored as JSON where supported.
+	Type(v interface{}) (string, error)
+	// MigrationsTable returns the statement creating the table used to keep
+	// track of executed migrations.
+	MigrationsTable(name string) string
+	// TableExistsQuery returns a query counting the tables in the current
+	// schema named like its only argument.
+	TableExistsQuery() string
+	// InformationSchema returns the relation to select from for the
+	// information_schema view with the given name (e.g. "tables"), using an
+	// emulation with the Postgres column names where necessary.
+	InformationSchema(view string) string
+	// Returning is true when INSERT and UPDATE support RETURNING.
+	Returning() bool
+	// OnConflict returns the clause of an INSERT updating the given columns
+	// with the inserted values when a row with the same conflict columns
+	// exists.
+	OnConflict(conflict, update []string) string
+}
+
+var (
+	Postgres Dialect = &postgres{}
+	MySQL    Dialect = &mysql{}
+	SQLite   Dialect = &sqlite{}
+)
+
+// Default is used when the dialect of a connection can not be detected.
+var Default = Postgres
+
+// ByName returns the dialect with the given name or driver name, e.g.
+// "postgres", "mysql" or "sqlite3".
+func ByName(name string) (Dialect, error) {
+	switch strings.ToLower(name) {
+	case "postgres", "postgresql", "pq":
+		return Postgres, nil
+	case "mysql":
+		return MySQL, nil
+	case "sqlite", "sqlite3":
+		return SQLite, nil
+	}
+	return nil, fmt.Errorf("dialect %q not supported", name)
+}
+
+// Detect returns the dialect of the driver of lib/pq, go-sql-driver/mysql or
+// mattn/go-sqlite3 and Default for all others.
+func Detect(drv driver.Driver) Dialect {
+	if d := detect(drv); d != nil {
+		return d
+	}
+	return Default
+}
+
+func detect(drv driver.Driver) Dialect {
+	name := fmt.Sprintf("%T", drv)
+	switch {
+	case strings.Contains(name, "pq."):
+		return Postgres
+	case strings.Contains(name, "mysql."):
+		return MySQL
+	case strings.Contains(name, "sqlite3."):
+		return SQLite
+	}
+	return nil
+}
+
+// Of returns the dialect of con. These are checked in order:
+// a Dialect() method (see gosql.WithDialect), the driver of a *sql.DB and
+// Default.
+//
+// A *sql.Tx does not expose its driver, so Of returns Default (Postgres) for
+// every transaction which is not wrapped with gosql.WithDialect. Wrap
+// transactions of MySQL and SQLite, or use Lookup to get an error instead of
+// the fallback.
+func Of(con interface{}) Dialect {
+	if d, err := Lookup(con); err == nil {
+		return d
+	}
+	return Default
+}
+
+// Lookup returns the dialect of con like Of, but returns an error instead of
+// falling back to Default when the dialect is unknown, e.g. for a *sql.Tx not
+// wrapped with gosql.WithDialect.
+func Lookup(con interface{}) (Dialect, error) {
+	switch c := con.(type) {
+	case interface{ Dialect() Dialect }:
+		return c.Dialect(), nil
+	case *sql.DB:
+		if d := detect(c.Driver()); d != nil {
+			return d, nil
+		}
+		return nil, fmt.Errorf("dialect of driver %T unknown, use gosql.WithDialect", c.Driver())
+	}
+	return nil, fmt.Errorf("dialect of %T unknown, use gosql.WithDialect", con)
+}
+
+var placeholderRegexp = regexp.MustCompile(`\$(\d+)`)
+
+// Rebind replaces the Postgres style placeholders ($1, $2, ...) of q with the
+// ones of d. The placeholders must be used in order and only once with
+// dialects using "?" and must not be part of string literals.
+func Rebind(d Dialect, q string) string {
+	if d == Postgres {
+		return q
+	}
+	return placeholderRegexp.ReplaceAllStringFunc(q, func(s string) string {
+		i, _ := strconv.Atoi(s[1:])
+		return d.Placeholder(i)
+	})
+}
+
+type postgres struct{}
+
+func (*postgres) Name() string {
+	return "postgres"
+}
+
+func (*postgres) Placeholder(i int) string {
+	return "$" + strconv.Itoa(i)
+}
+
+func (*postgres) Type(v interface{}) (string, error) {
+	return columnType(v, map[string]string{
+		"json":   "JSON",
+		"string": "VARCHAR",
+		"int":    "INTEGER",
+		"int64":  "BIGINT",
+		"float":  "DOUBLE PRECISION",
+		"bool":   "BOOLEAN",
+		"time":   "TIMESTAMP WITH TIME ZONE",
+	})
+}
+
+func (*postgres) MigrationsTable(name string) string {
+	return "CREATE TABLE " + name + " (idx INTEGER PRIMARY KEY NOT NULL, md5 UUID NOT NULL, statement VARCHAR NOT NULL, created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL)"
+}
+
+func (*postgres) TableExistsQuery() string {
+	return "SELECT COUNT(1) FROM pg_tables WHERE schemaname = current_schema() AND tablename = $1"
+}
+
+func (*postgres) InformationSchema(view string) string {
+	return "information_schema." + view
+}
+
+func (*postgres) Returning() bool {
+	return true
+}
+
+func (*postgres) OnConflict(conflict, update []string) string {
+	return onConflict(conflict, update)
+}
+
+// onConflict is supported by Postgres 9.5+ and SQLite 3.24+
+func onConflict(conflict, update []string) string {
+	set := make([]string, 0, len(update))
+	for _, c := range update {
+		set = append(set, c+" = EXCLUDED."+c)
+	}
+	return "ON CONFLICT (" + strings.Join(conflict, ", ") + ") DO UPDATE SET " + strings.Join(set, ", ")
+}
+
+type mysql struct{}
+
+func (*mysql) Name() string {
+	return "mysql"
+}
+
+func (*mysql) Placeholder(int) string {
+	return "?"
+}
+
+func (*mysql) Type(v interface{}) (string, error) {
+	return columnType(v, map[string]string{
+		"json":   "JSON",
+		"string": "VARCHAR(255)",
+		"int":    "INTEGER",
+		"int64":  "BIGINT",
+		"float":  "DOUBLE",
+		"bool":   "BOOLEAN",
+		"time":   "DATETIME(6)",
+	})
+}
+
+func (*mysql) MigrationsTable(name string) string {
+	return "CREATE TABLE " + name + " (idx INTEGER PRIMARY KEY NOT NULL, md5 CHAR(32) NOT NULL, statement TEXT NOT NULL, created_at DATETIME(6) NOT NULL)"
+}
+
+func (*mysql) TableExistsQuery() string {
+	return "SELECT COUNT(1) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+}
+
+// the information_schema of MySQL uses upper case column names and lacks
+// some of the columns of Postgres
+var mysqlInformationSchema = map[string]string{
+	"tables": "(SELECT table_catalog AS table_catalog, table_schema AS table_schema, table_name AS table_name, table_type AS table_type, " +
+		"NULL AS self_referencing_column_name, NULL AS reference_generation, NULL AS user_defined_type_catalog, NULL AS user_defined_type_schema, NULL AS user_defined_type_name, " +
+		"CASE table_type WHEN 'BASE TABLE' THEN 'YES' ELSE 'NO' END AS is_insertable_into, 'NO' AS is_typed, NULL AS commit_action " +
+		"FROM information_schema.tables) AS tables",
+	"views": "(SELECT table_catalog AS table_catalog, table_schema AS table_schema, table_name AS table_name, view_definition AS view_definition, " +
+		"check_option AS check_option, is_updatable AS is_updatable FROM information_schema.views) AS views",
+	"columns": "(SELECT table_catalog AS table_catalog, table_schema AS table_schema, table_name AS table_name, column_name AS column_name, " +
+		"ordinal_position AS ordinal_position, column_default AS column_default, is_nullable AS is_nullable, data_type AS data_type, " +
+		"character_maximum_length AS character_maximum_length, character_octet_length AS character_octet_length, numeric_precision AS numeric_precision, " +
+		"numeric_scale AS numeric_scale, datetime_precision AS datetime_precision, character_set_name AS character_set_name, collation_name AS collation_name, " +
+		"column_type AS udt_name FROM information_schema.columns) AS columns",
+}
+
+func (*mysql) InformationSchema(view string) string {
+	if rel, ok := mysqlInformationSchema[view]; ok {
+		return rel
+	}
+	return "information_schema." + view
+}
+
+func (*mysql) Returning() bool {
+	return false
+}
+
+// OnConflict ignores conflict as MySQL checks all unique keys.
+func (*mysql) OnConflict(conflict, update []string) string {
+	set := make([]string, 0, len(update))
+	for _, c := range update {
+		set = append(set, c+" = VALUES("+c+")")
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(set, ", ")
+}
+
+type sqlite struct{}
+
+func (*sqlite) Name() string {
+	return "sqlite3"
+}
+
+func (*sqlite) Placeholder(int) string {
+	return "?"
+}
+
+func (*sqlite) Type(v interface{}) (string, error) {
+	return columnType(v, map[string]string{
+		"json":   "TEXT",
+		"string": "VARCHAR",
+		"int":    "INTEGER",
+		"int64":  "INTEGER",
+		"float":  "REAL",
+		"bool":   "BOOLEAN",
+		"time":   "TIMESTAMP",
+	})
+}
+
+func (*sqlite) MigrationsTable(name string) string {
+	return "CREATE TABLE " + name + " (idx INTEGER PRIMARY KEY NOT NULL, md5 VARCHAR(32) NOT NULL, statement TEXT NOT NULL, created_at TIMESTAMP NOT NULL)"
+}
+
+func (*sqlite) TableExistsQuery() string {
+	return "SELECT COUNT(1) FROM sqlite_master WHERE type = 'table' AND name = ?"
+}
+
+// SQLite has no information_schema, the emulation is based on sqlite_master
+var sqliteInformationSchema = map[string]string{
+	"tables": "(SELECT '' AS table_catalog, 'main' AS table_schema, name AS table_name, CASE type WHEN 'view' THEN 'VIEW' ELSE 'BASE TABLE' END AS table_type, " +
+		"NULL AS self_referencing_column_name, NULL AS reference_generation, NULL AS user_defined_type_catalog, NULL AS user_defined_type_schema, NULL AS user_defined_type_name, " +
+		"CASE type WHEN 'view' THEN 'NO' ELSE 'YES' END AS is_insertable_into, 'NO' AS is_typed, NULL AS commit_action " +
+		"FROM sqlite_master WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%') AS tables",
+	"views": "(SELECT '' AS table_catalog, 'main' AS table_schema, name AS table_name, sql AS view_definition, " +
+		"'NONE' AS check_option, 'NO' AS is_updatable FROM sqlite_master WHERE type = 'view') AS views",
+	"columns": "(SELECT '' AS table_catalog, 'main' AS table_schema, m.name AS table_name, p.name AS column_name, p.cid + 1 AS ordinal_position, " +
+		"p.dflt_value AS column_default, CASE WHEN p.\"notnull\" = 0 AND p.pk = 0 THEN 'YES' ELSE 'NO' END AS is_nullable, lower(p.type) AS data_type, lower(p.type) AS udt_name " +
+		"FROM sqlite_master m, pragma_table_info(m.name) p WHERE m.type IN ('table', 'view') AND m.name NOT LIKE 'sqlite_%') AS columns",
+}
+
+func (*sqlite) InformationSchema(view string) string {
+	if rel, ok := sqliteInformationSchema[view]; ok {
+		return rel
+	}
+	return "information_schema." + view
+}
+
+// Returning is supported since SQLite 3.35.
+func (*sqlite) Returning() bool {
+	return true
+}
+
+func (*sqlite) OnConflict(conflict, update []string) string {
+	return onConflict(conflict, update)
+}
+
+func columnType(v interface{}, types map[string]string) (string, error) {
+	switch v.(type) {
+	case json.RawMessage:
+		return types["json"], nil
+	case string:
+		return types["string"], nil
+	case int, int32:
+		return types["int"], nil
+	case int64:
+		return types["int64"], nil
+	case float32, float64:
+		return types["float"], nil
+	case bool:
+		return types["bool"], nil
+	case time.Time:
+		return types["time"], nil
+	}
+	return "", fmt.Errorf("unable to handle type %T", v)
+}
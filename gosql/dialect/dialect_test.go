@@ -0,0 +1,118 @@
+package dialect
+
+import (
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestType(t *testing.T) {
+	tests := []struct {
+		Dialect Dialect
+		Value   interface{}
+		Want    string
+	}{
+		{Postgres, "a", "VARCHAR"},
+		{Postgres, time.Time{}, "TIMESTAMP WITH TIME ZONE"},
+		{Postgres, json.RawMessage(nil), "JSON"},
+		{MySQL, "a", "VARCHAR(255)"},
+		{MySQL, time.Time{}, "DATETIME(6)"},
+		{MySQL, int64(1), "BIGINT"},
+		{SQLite, json.RawMessage(nil), "TEXT"},
+		{SQLite, 1.5, "REAL"},
+		{SQLite, true, "BOOLEAN"},
+	}
+	for _, tc := range tests {
+		has, err := tc.Dialect.Type(tc.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if has != tc.Want {
+			t.Errorf("%s %T: want=%q has=%q", tc.Dialect.Name(), tc.Value, tc.Want, has)
+		}
+	}
+	if _, err := SQLite.Type(struct{}{}); err == nil {
+		t.Errorf("expected error for unsupported type")
+	}
+}
+
+func TestRebind(t *testing.T) {
+	q := "SELECT 1 FROM migrations WHERE idx = $1 AND md5 = $2"
+	tests := []struct {
+		Dialect Dialect
+		Want    string
+	}{
+		{Postgres, q},
+		{MySQL, "SELECT 1 FROM migrations WHERE idx = ? AND md5 = ?"},
+		{SQLite, "SELECT 1 FROM migrations WHERE idx = ? AND md5 = ?"},
+	}
+	for _, tc := range tests {
+		if has := Rebind(tc.Dialect, q); has != tc.Want {
+			t.Errorf("%s: want=%q has=%q", tc.Dialect.Name(), tc.Want, has)
+		}
+	}
+}
+
+func TestByName(t *testing.T) {
+	for name, want := range map[string]Dialect{"postgres": Postgres, "mysql": MySQL, "sqlite3": SQLite, "SQLite": SQLite} {
+		d, err := ByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d != want {
+			t.Errorf("%s: want=%s has=%s", name, want.Name(), d.Name())
+		}
+	}
+	if _, err := ByName("oracle"); err == nil {
+		t.Errorf("expected error for unknown dialect")
+	}
+}
+
+type withDialect struct {
+	*sql.Tx
+	d Dialect
+}
+
+func (w *withDialect) Dialect() Dialect {
+	return w.d
+}
+
+func TestLookup(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	tests := []struct {
+		Name  string
+		Con   interface{}
+		Want  Dialect
+		Error bool
+	}{
+		{"db", db, SQLite, false},
+		{"tx", tx, nil, true},
+		{"wrapped tx", &withDialect{Tx: tx, d: SQLite}, SQLite, false},
+	}
+	for _, tc := range tests {
+		d, err := Lookup(tc.Con)
+		if (err != nil) != tc.Error {
+			t.Errorf("%s: want error=%t has=%v", tc.Name, tc.Error, err)
+		}
+		if d != tc.Want {
+			t.Errorf("%s: want=%v has=%v", tc.Name, tc.Want, d)
+		}
+	}
+	if d := Of(tx); d != Default {
+		t.Errorf("expected Of to fall back to Default for a transaction, was %s", d.Name())
+	}
+}
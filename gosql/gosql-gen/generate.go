@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/dynport/dgtk/gosql"
+	"github.com/dynport/dgtk/gosql/dialect"
+	"github.com/dynport/dgtk/txt"
+)
+
+type generator struct {
+	Pkg    string
+	Schema string
+	Tables []string // all tables when empty
+}
+
+type table struct {
+	Name    string
+	Type    string // name of the struct
+	Plural  string // used for the select helper
+	Columns []*column
+	PK      *column // set for tables with a single column primary key
+	Select  string
+	ByPK    string // query selecting a single row by primary key
+}
+
+type column struct {
+	Name  string
+	Field string
+	Param string // name of the column as function parameter
+	Type  string
+	Tag   string
+}
+
+// generate returns the formatted source with structs and helpers for all
+// tables of db.
+func (g *generator) generate(db gosql.Dbi) ([]byte, error) {
+	d := dialect.Of(db)
+	tables, err := g.load(db, d)
+	if err != nil {
+		return nil, err
+	}
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("no tables found")
+	}
+	imports := map[string]bool{}
+	for _, t := range tables {
+		for _, c := range t.Columns {
+			switch {
+			case strings.Contains(c.Type, "time."):
+				imports["time"] = true
+			case strings.Contains(c.Type, "json."):
+				imports["encoding/json"] = true
+			}
+		}
+	}
+	sorted := []string{}
+	for i := range imports {
+		sorted = append(sorted, i)
+	}
+	sort.Strings(sorted)
+	if len(sorted) > 0 {
+		sorted = append(sorted, "") // separates the standard library
+	}
+	sorted = append(sorted, "github.com/dynport/dgtk/gosql")
+	buf := &bytes.Buffer{}
+	err = tpl.Execute(buf, map[string]interface{}{"Pkg": g.Pkg, "Imports": sorted, "Tables": tables})
+	if err != nil {
+		return nil, err
+	}
+	b, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %s\n%s", err, buf.String())
+	}
+	return b, nil
+}
+
+func (g *generator) load(db gosql.Dbi, d dialect.Dialect) ([]*table, error) {
+	schema := g.Schema
+	if schema == "" {
+		schema = defaultSchema(d)
+	}
+	all, err := gosql.Tables(db, gosql.WithSchema(schema))
+	if err != nil {
+		return nil, err
+	}
+	include := map[string]bool{}
+	for _, t := range g.Tables {
+		include[t] = true
+	}
+	pks := map[string][]string{}
+	if d == dialect.Postgres || d == dialect.SQLite {
+		s, err := gosql.LoadSchema(db, gosql.WithSchema(schema))
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range s.Tables {
+			pks[t.Name] = t.PrimaryKey
+		}
+	}
+	out := []*table{}
+	for _, info := range all {
+		if info.TableType != "BASE TABLE" || (len(include) > 0 && !include[info.TableName]) {
+			continue
+		}
+		cols, err := gosql.Columns(db, gosql.WithSchema(schema), gosql.WithName(info.TableName))
+		if err != nil {
+			return nil, err
+		}
+		t := newTable(info.TableName, cols, pks[info.TableName], d)
+		delete(include, info.TableName)
+		out = append(out, t)
+	}
+	for name := range include {
+		return nil, fmt.Errorf("table %s not found in schema %s", name, schema)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Name < out[b].Name })
+	return out, nil
+}
+
+func defaultSchema(d dialect.Dialect) string {
+	if d == dialect.SQLite {
+		return "main"
+	}
+	return "public"
+}
+
+func newTable(name string, cols []*gosql.Column, pk []string, d dialect.Dialect) *table {
+	t := &table{Name: name, Type: goName(singular(name)), Plural: goName(name)}
+	if t.Plural == t.Type {
+		t.Plural += "List"
+	}
+	isPK := map[string]bool{}
+	for _, c := range pk {
+		isPK[c] = true
+	}
+	names := []string{}
+	for _, c := range cols {
+		col := &column{Name: c.ColumnName, Field: goName(c.ColumnName), Type: goType(c)}
+		tag := c.ColumnName
+		if isPK[c.ColumnName] {
+			tag += ",pk"
+		} else if c.ColumnDefault != nil {
+			tag += ",default"
+		}
+		col.Tag = fmt.Sprintf("`sql:%q`", tag)
+		if len(pk) == 1 && isPK[c.ColumnName] {
+			col.Param = "pk"
+			if token.IsIdentifier(c.ColumnName) && !token.IsKeyword(c.ColumnName) && !reservedParams[c.ColumnName] {
+				col.Param = c.ColumnName
+			}
+			t.PK = col
+		}
+		t.Columns = append(t.Columns, col)
+		names = append(names, c.ColumnName)
+	}
+	t.Select = "SELECT " + strings.Join(names, ", ") + " FROM " + name
+	if t.PK != nil {
+		t.ByPK = t.Select + " WHERE " + t.PK.Name + " = " + d.Placeholder(1)
+	}
+	return t
+}
+
+// reservedParams are the identifiers used in the generated functions which
+// can not be used as name of the primary key parameter.
+var reservedParams = map[string]bool{"db": true, "row": true, "err": true, "gosql": true, "query": true, "args": true, "list": true}
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// goName returns the exported Go name of a table or column.
+func goName(s string) string {
+	n := txt.CamelCase(invalidNameChars.ReplaceAllString(s, "_"))
+	switch {
+	case n == "Id":
+		return "ID"
+	case n == "" || (n[0] >= '0' && n[0] <= '9'):
+		return "C" + n
+	}
+	return n
+}
+
+func singular(s string) string {
+	switch {
+	case strings.HasSuffix(s, "ies"):
+		return strings.TrimSuffix(s, "ies") + "y"
+	case strings.HasSuffix(s, "sses"), strings.HasSuffix(s, "xes"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(s, "s") && !strings.HasSuffix(s, "ss") && !strings.HasSuffix(s, "us") && !strings.HasSuffix(s, "is"):
+		return strings.TrimSuffix(s, "s")
+	}
+	return s
+}
+
+var typeRegexps = []struct {
+	re     *regexp.Regexp
+	goType string
+}{
+	{regexp.MustCompile(`^(bigint|int8|bigserial)\b`), "int64"},
+	{regexp.MustCompile(`^(int|integer|int2|int4|smallint|tinyint|mediumint|serial|smallserial)\b`), "int"},
+	{regexp.MustCompile(`^(bool)`), "bool"},
+	{regexp.MustCompile(`^(real|float4|float$)`), "float32"},
+	{regexp.MustCompile(`^(double|float|numeric|decimal)`), "float64"},
+	{regexp.MustCompile(`^(timestamp|datetime|date$)`), "time.Time"},
+	{regexp.MustCompile(`^(json)`), "json.RawMessage"},
+	{regexp.MustCompile(`^(bytea|blob|binary|varbinary)`), "[]byte"},
+	{regexp.MustCompile(`^(char|varchar|character|text|uuid|citext|time|interval|enum|clob)`), "string"},
+}
+
+// goType maps the data type of a column to a Go type. Types of nullable
+// columns are pointers, except for slices.
+func goType(c *gosql.Column) string {
+	dataType := strings.ToLower(c.DataType)
+	if dataType == "user-defined" && c.UdtName != nil {
+		dataType = *c.UdtName
+	}
+	t := "interface{}"
+	for _, r := range typeRegexps {
+		if r.re.MatchString(dataType) {
+			t = r.goType
+			break
+		}
+	}
+	if bool(c.IsNullable) && t != "interface{}" && !strings.HasPrefix(t, "[]") && t != "json.RawMessage" {
+		t = "*" + t
+	}
+	return t
+}
+
+var tpl = template.Must(template.New("gen").Parse(`// Code generated by gosql-gen; DO NOT EDIT.
+
+package {{ .Pkg }}
+
+import (
+{{ range .Imports }}{{ if . }}	"{{ . }}"{{ end }}
+{{ end }})
+{{ range .Tables }}
+// {{ .Type }} is a row of table {{ .Name }}.
+type {{ .Type }} struct {
+{{ range .Columns }}	{{ .Field }} {{ .Type }} {{ .Tag }}
+{{ end }}}
+
+// Select{{ .Plural }} selects rows of table {{ .Name }}, query is appended to
+// the statement, e.g. "WHERE ... ORDER BY ...".
+func Select{{ .Plural }}(db gosql.Dbi, query string, args ...interface{}) ([]*{{ .Type }}, error) {
+	list := []*{{ .Type }}{}
+	err := gosql.SelectStructs(db, {{ printf "%q" (print .Select " ") }}+query, &list, args...)
+	return list, err
+}
+{{ if .PK }}
+// Select{{ .Type }} selects the row of table {{ .Name }} with the given primary key.
+func Select{{ .Type }}(db gosql.Dbi, {{ .PK.Param }} {{ .PK.Type }}) (*{{ .Type }}, error) {
+	row := &{{ .Type }}{}
+	err := gosql.SelectStruct(db, row, {{ printf "%q" .ByPK }}, {{ .PK.Param }})
+	return row, err
+}
+{{ end }}
+// Insert{{ .Type }} inserts row into table {{ .Name }} and reads back the
+// primary key and columns with defaults.
+func Insert{{ .Type }}(db gosql.Dbi, row *{{ .Type }}) error {
+	return gosql.Insert(db, {{ printf "%q" .Name }}, row)
+}
+{{ end }}`))
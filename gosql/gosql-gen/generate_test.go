@@ -0,0 +1,162 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dynport/dgtk/gosql"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestGoName(t *testing.T) {
+	tests := []struct {
+		Value    string
+		Expected string
+	}{
+		{"id", "ID"},
+		{"user_id", "UserID"},
+		{"created_at", "CreatedAt"},
+		{"2fa", "C2fa"},
+		{"first name", "FirstName"},
+	}
+	for _, tst := range tests {
+		if v := goName(tst.Value); v != tst.Expected {
+			t.Errorf("expected goName(%q) to be %q, was %q", tst.Value, tst.Expected, v)
+		}
+	}
+}
+
+func TestSingular(t *testing.T) {
+	tests := []struct {
+		Value    string
+		Expected string
+	}{
+		{"users", "user"},
+		{"categories", "category"},
+		{"boxes", "box"},
+		{"addresses", "address"},
+		{"status", "status"},
+		{"news", "new"},
+		{"person", "person"},
+	}
+	for _, tst := range tests {
+		if v := singular(tst.Value); v != tst.Expected {
+			t.Errorf("expected singular(%q) to be %q, was %q", tst.Value, tst.Expected, v)
+		}
+	}
+}
+
+func TestGoType(t *testing.T) {
+	udt := "hstore"
+	tests := []struct {
+		Value    *gosql.Column
+		Expected string
+	}{
+		{&gosql.Column{DataType: "integer"}, "int"},
+		{&gosql.Column{DataType: "integer", IsNullable: true}, "*int"},
+		{&gosql.Column{DataType: "bigint"}, "int64"},
+		{&gosql.Column{DataType: "int(11)"}, "int"},
+		{&gosql.Column{DataType: "INTEGER"}, "int"},
+		{&gosql.Column{DataType: "interval"}, "string"},
+		{&gosql.Column{DataType: "interval", IsNullable: true}, "*string"},
+		{&gosql.Column{DataType: "character varying", IsNullable: true}, "*string"},
+		{&gosql.Column{DataType: "VARCHAR(255)"}, "string"},
+		{&gosql.Column{DataType: "timestamp with time zone"}, "time.Time"},
+		{&gosql.Column{DataType: "time without time zone"}, "string"},
+		{&gosql.Column{DataType: "double precision"}, "float64"},
+		{&gosql.Column{DataType: "boolean", IsNullable: true}, "*bool"},
+		{&gosql.Column{DataType: "jsonb", IsNullable: true}, "json.RawMessage"},
+		{&gosql.Column{DataType: "bytea", IsNullable: true}, "[]byte"},
+		{&gosql.Column{DataType: "USER-DEFINED", UdtName: &udt}, "interface{}"},
+	}
+	for _, tst := range tests {
+		if v := goType(tst.Value); v != tst.Expected {
+			t.Errorf("expected goType(%q) to be %q, was %q", tst.Value.DataType, tst.Expected, v)
+		}
+	}
+}
+
+func TestGenerateSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, q := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email VARCHAR NOT NULL, name VARCHAR, created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)",
+		"CREATE TABLE categories (id INTEGER PRIMARY KEY, parent_id INTEGER, data BLOB)",
+		"CREATE VIEW user_names AS SELECT name FROM users",
+		"CREATE TABLE sessions (db INTEGER PRIMARY KEY, row VARCHAR)",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g := &generator{Pkg: "models"}
+	b, err := g.generate(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := string(b)
+	checkBuild(t, b)
+	for _, s := range []string{
+		"package models",
+		"type User struct",
+		"ID        int       `sql:\"id,pk\"`",
+		"Name      *string   `sql:\"name\"`",
+		"CreatedAt time.Time `sql:\"created_at,default\"`",
+		"func SelectUsers(db gosql.Dbi, query string, args ...interface{}) ([]*User, error)",
+		`"SELECT id, email, name, created_at FROM users "+query`,
+		"err := gosql.SelectStructs(db, ",
+		"return list, err",
+		"func SelectUser(db gosql.Dbi, id int) (*User, error)",
+		`"SELECT id, email, name, created_at FROM users WHERE id = ?"`,
+		"func InsertUser(db gosql.Dbi, row *User) error",
+		"type Category struct",
+		"func SelectSession(db gosql.Dbi, pk int) (*Session, error)",
+		"ParentID *int   `sql:\"parent_id\"`",
+		"Data     []byte `sql:\"data\"`",
+	} {
+		if !strings.Contains(src, s) {
+			t.Errorf("expected generated code to contain %q\n%s", s, src)
+		}
+	}
+	if strings.Contains(src, "UserName") {
+		t.Errorf("expected views to be skipped\n%s", src)
+	}
+	again, err := g.generate(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != src {
+		t.Errorf("expected generated code to be stable")
+	}
+
+	g.Tables = []string{"missing"}
+	if _, err := g.generate(db); err == nil {
+		t.Errorf("expected error for missing table")
+	}
+}
+
+// checkBuild compiles the generated code as package of this module so the
+// import of gosql resolves.
+func checkBuild(t *testing.T, src []byte) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	dir, err := os.MkdirTemp(".", "_generated") // ignored by ./...
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "gosql_gen.go"), src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("go", "build", "./"+filepath.Base(dir)).CombinedOutput(); err != nil {
+		t.Fatalf("generated code does not build: %s\n%s\n%s", err, out, src)
+	}
+}
@@ -0,0 +1,71 @@
+// gosql-gen generates Go structs with sql tags and typed Select and Insert
+// helpers for the tables of a database. The output is deterministic so it can
+// be re-run from go generate, e.g.
+//
+//	//go:generate gosql-gen -driver sqlite3 -url schema.db -o tables_gen.go
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var logger = log.New(os.Stderr, "", 0)
+
+func main() {
+	if err := run(); err != nil {
+		logger.Print(err.Error())
+		flag.Usage()
+		os.Exit(1)
+	}
+}
+
+var (
+	driver = flag.String("driver", "postgres", "Database driver (postgres or sqlite3)")
+	url    = flag.String("url", os.Getenv("DATABASE_URL"), "Database url, defaults to $DATABASE_URL")
+	schema = flag.String("schema", "", "Schema of the tables, defaults to public (main with sqlite3)")
+	tables = flag.String("tables", "", "Comma separated list of tables, defaults to all")
+	pkg    = flag.String("pkg", os.Getenv("GOPACKAGE"), "Package name, defaults to $GOPACKAGE set by go generate")
+	out    = flag.String("o", "gosql_gen.go", "Output file, - for stdout")
+)
+
+func run() error {
+	flag.Parse()
+	if *url == "" {
+		return errors.New("url must be set")
+	}
+	g := &generator{Pkg: *pkg, Schema: *schema}
+	if g.Pkg == "" {
+		abs, err := filepath.Abs(*out)
+		if err != nil {
+			return err
+		}
+		g.Pkg = filepath.Base(filepath.Dir(abs))
+	}
+	if *tables != "" {
+		g.Tables = strings.Split(*tables, ",")
+	}
+	db, err := sql.Open(*driver, *url)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	b, err := g.generate(db)
+	if err != nil {
+		return err
+	}
+	if *out == "-" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return ioutil.WriteFile(*out, b, 0644)
+}
@@ -3,33 +3,54 @@ package gosql
 import (
 	"fmt"
 	"reflect"
-	"strconv"
 	"strings"
+
+	"github.com/dynport/dgtk/gosql/dialect"
 )
 
-func Columns(db Dbi) ([]*Column, error) {
-	names, err := columnNames(db, &Column{})
-	if err != nil {
-		return nil, err
-	}
-	q := "SELECT " + strings.Join(names, ", ") + " FROM information_schema.columns"
-	rows, err := db.Query(q)
-	if err != nil {
-		return nil, err
+// Columns returns the columns of all tables and views, ordered by schema,
+// table and position. Use WithSchema, PublicTables and WithName to filter.
+func Columns(db Dbi, funcs ...tableOpt) ([]*Column, error) {
+	opt := &tableOptions{}
+	opt.apply(funcs...)
+	d := dialect.Of(db)
+	// not all versions and dialects have the same columns, missing ones are left empty
+	q := "SELECT * FROM " + d.InformationSchema("columns")
+	v := []interface{}{}
+	w := []string{}
+	if opt.TableSchema != "" {
+		v = append(v, opt.TableSchema)
+		w = append(w, "table_schema = "+d.Placeholder(len(v)))
 	}
-	defer rows.Close()
-	for rows.Next() {
-		t := &Column{}
-		rows.Scan(&t.TableCatalog, &t.TableSchema, &t.TableName, &t.ColumnName, &t.OrdinalPosition, &t.ColumnDefault, &t.IsNullable, &t.DataType, &t.CharacterMaximumLength, &t.CharacterOctetLength, &t.NumericPrecision, &t.NumericPrecisionRadix, &t.NumericScale, &t.DatetimePrecision, &t.IntervalType, &t.IntervalPrecision, &t.CharacterSetCatalog, &t.CharacterSetSchema, &t.CharacterSetName, &t.CollationCatalog, &t.CollationSchema, &t.CollationName, &t.DomainCatalog, &t.DomainSchema, &t.DomainName, &t.UdtCatalog, &t.UdtSchema, &t.UdtName, &t.ScopeCatalog, &t.ScopeSchema, &t.ScopeName, &t.MaximumCardinality, &t.DtdIdentifier, &t.IsSelfReferencing, &t.IsIdentity, &t.IdentityGeneration, &t.IdentityStart, &t.IdentityIncrement, &t.IdentityMaximum, &t.IdentityMinimum, &t.IdentityCycle, &t.IsGenerated, &t.GenerationExpression, &t.IsUpdatable)
-		err = rows.Scan()
-		if err != nil {
-			return nil, err
-		}
+	if opt.TableName != "" {
+		v = append(v, opt.TableName)
+		w = append(w, "table_name = "+d.Placeholder(len(v)))
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+	if len(w) > 0 {
+		q += " WHERE " + strings.Join(w, " AND ")
 	}
-	return nil, nil
+	q += " ORDER BY table_schema, table_name, ordinal_position"
+	out := []*Column{}
+	return out, SelectStructs(db, q, &out, v...)
+}
+
+// YesOrNo scans the information_schema type yes_or_no.
+type YesOrNo bool
+
+func (b *YesOrNo) Scan(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		*b = false
+	case []byte:
+		*b = YesOrNo(strings.EqualFold(string(v), "YES"))
+	case string:
+		*b = YesOrNo(strings.EqualFold(v, "YES"))
+	case bool:
+		*b = YesOrNo(v)
+	default:
+		return fmt.Errorf("unable to scan %T into YesOrNo", v)
+	}
+	return nil
 }
 
 type tableOptions struct {
@@ -52,6 +73,12 @@ func WithName(s string) tableOpt {
 	}
 }
 
+func WithSchema(s string) tableOpt {
+	return func(o *tableOptions) {
+		o.TableSchema = s
+	}
+}
+
 func PublicTables(o *tableOptions) {
 	o.TableSchema = "public"
 }
@@ -86,6 +113,7 @@ func Views(db Dbi, funcs ...viewOpt) ([]*View, error) {
 	for _, f := range funcs {
 		f(o)
 	}
+	d := dialect.Of(db)
 	w := []string{}
 	i := []interface{}{}
 	for _, v := range []struct{ Name, Value string }{
@@ -94,11 +122,11 @@ func Views(db Dbi, funcs ...viewOpt) ([]*View, error) {
 		{"table_name", o.Name},
 	} {
 		if v.Value != "" {
-			w = append(w, v.Name+" = $"+strconv.Itoa(len(w)+1))
+			w = append(w, v.Name+" = "+d.Placeholder(len(w)+1))
 			i = append(i, v.Value)
 		}
 	}
-	q := "SELECT table_catalog, table_schema, table_name FROM information_schema.views"
+	q := "SELECT table_catalog, table_schema, table_name FROM " + d.InformationSchema("views")
 	if len(w) > 0 {
 		q += " WHERE " + strings.Join(w, " AND ")
 	}
@@ -114,15 +142,16 @@ func Tables(db Dbi, funcs ...tableOpt) ([]*Table, error) {
 	if err != nil {
 		return nil, err
 	}
-	q := "SELECT " + strings.Join(names, ", ") + " FROM information_schema.tables"
+	d := dialect.Of(db)
+	q := "SELECT " + strings.Join(names, ", ") + " FROM " + d.InformationSchema("tables")
 	v := []interface{}{}
 	w := []string{}
 	if opt.TableSchema != "" {
-		w = append(w, "table_schema = $1")
+		w = append(w, "table_schema = "+d.Placeholder(1))
 		v = append(v, opt.TableSchema)
 	}
 	if opt.TableName != "" {
-		w = append(w, fmt.Sprintf("table_name = $%d", len(w)+1))
+		w = append(w, "table_name = "+d.Placeholder(len(w)+1))
 		v = append(v, opt.TableName)
 	}
 	if len(w) > 0 {
@@ -169,48 +198,48 @@ type Table struct {
 }
 
 type Column struct {
-	TableCatalog           string `sql:"table_catalog"`            // information_schema.sql_identifier
-	TableSchema            string `sql:"table_schema"`             // information_schema.sql_identifier
-	TableName              string `sql:"table_name"`               // information_schema.sql_identifier
-	ColumnName             string `sql:"column_name"`              // information_schema.sql_identifier
-	OrdinalPosition        int    `sql:"ordinal_position"`         // information_schema.cardinal_number
-	ColumnDefault          string `sql:"column_default"`           // information_schema.character_data
-	IsNullable             bool   `sql:"is_nullable"`              // information_schema.yes_or_no
-	DataType               string `sql:"data_type"`                // information_schema.character_data
-	CharacterMaximumLength int    `sql:"character_maximum_length"` // information_schema.cardinal_number
-	CharacterOctetLength   int    `sql:"character_octet_length"`   // information_schema.cardinal_number
-	NumericPrecision       int    `sql:"numeric_precision"`        // information_schema.cardinal_number
-	NumericPrecisionRadix  int    `sql:"numeric_precision_radix"`  // information_schema.cardinal_number
-	NumericScale           int    `sql:"numeric_scale"`            // information_schema.cardinal_number
-	DatetimePrecision      int    `sql:"datetime_precision"`       // information_schema.cardinal_number
-	IntervalType           string `sql:"interval_type"`            // information_schema.character_data
-	IntervalPrecision      int    `sql:"interval_precision"`       // information_schema.cardinal_number
-	CharacterSetCatalog    string `sql:"character_set_catalog"`    // information_schema.sql_identifier
-	CharacterSetSchema     string `sql:"character_set_schema"`     // information_schema.sql_identifier
-	CharacterSetName       string `sql:"character_set_name"`       // information_schema.sql_identifier
-	CollationCatalog       string `sql:"collation_catalog"`        // information_schema.sql_identifier
-	CollationSchema        string `sql:"collation_schema"`         // information_schema.sql_identifier
-	CollationName          string `sql:"collation_name"`           // information_schema.sql_identifier
-	DomainCatalog          string `sql:"domain_catalog"`           // information_schema.sql_identifier
-	DomainSchema           string `sql:"domain_schema"`            // information_schema.sql_identifier
-	DomainName             string `sql:"domain_name"`              // information_schema.sql_identifier
-	UdtCatalog             string `sql:"udt_catalog"`              // information_schema.sql_identifier
-	UdtSchema              string `sql:"udt_schema"`               // information_schema.sql_identifier
-	UdtName                string `sql:"udt_name"`                 // information_schema.sql_identifier
-	ScopeCatalog           string `sql:"scope_catalog"`            // information_schema.sql_identifier
-	ScopeSchema            string `sql:"scope_schema"`             // information_schema.sql_identifier
-	ScopeName              string `sql:"scope_name"`               // information_schema.sql_identifier
-	MaximumCardinality     int    `sql:"maximum_cardinality"`      // information_schema.cardinal_number
-	DtdIdentifier          string `sql:"dtd_identifier"`           // information_schema.sql_identifier
-	IsSelfReferencing      bool   `sql:"is_self_referencing"`      // information_schema.yes_or_no
-	IsIdentity             bool   `sql:"is_identity"`              // information_schema.yes_or_no
-	IdentityGeneration     string `sql:"identity_generation"`      // information_schema.character_data
-	IdentityStart          string `sql:"identity_start"`           // information_schema.character_data
-	IdentityIncrement      string `sql:"identity_increment"`       // information_schema.character_data
-	IdentityMaximum        string `sql:"identity_maximum"`         // information_schema.character_data
-	IdentityMinimum        string `sql:"identity_minimum"`         // information_schema.character_data
-	IdentityCycle          bool   `sql:"identity_cycle"`           // information_schema.yes_or_no
-	IsGenerated            string `sql:"is_generated"`             // information_schema.character_data
-	GenerationExpression   string `sql:"generation_expression"`    // information_schema.character_data
-	IsUpdatable            bool   `sql:"is_updatable"`             // information_schema.yes_or_no
+	TableCatalog           string  `sql:"table_catalog"`            // information_schema.sql_identifier
+	TableSchema            string  `sql:"table_schema"`             // information_schema.sql_identifier
+	TableName              string  `sql:"table_name"`               // information_schema.sql_identifier
+	ColumnName             string  `sql:"column_name"`              // information_schema.sql_identifier
+	OrdinalPosition        int     `sql:"ordinal_position"`         // information_schema.cardinal_number
+	ColumnDefault          *string `sql:"column_default"`           // information_schema.character_data
+	IsNullable             YesOrNo `sql:"is_nullable"`              // information_schema.yes_or_no
+	DataType               string  `sql:"data_type"`                // information_schema.character_data
+	CharacterMaximumLength *int    `sql:"character_maximum_length"` // information_schema.cardinal_number
+	CharacterOctetLength   *int    `sql:"character_octet_length"`   // information_schema.cardinal_number
+	NumericPrecision       *int    `sql:"numeric_precision"`        // information_schema.cardinal_number
+	NumericPrecisionRadix  *int    `sql:"numeric_precision_radix"`  // information_schema.cardinal_number
+	NumericScale           *int    `sql:"numeric_scale"`            // information_schema.cardinal_number
+	DatetimePrecision      *int    `sql:"datetime_precision"`       // information_schema.cardinal_number
+	IntervalType           *string `sql:"interval_type"`            // information_schema.character_data
+	IntervalPrecision      *int    `sql:"interval_precision"`       // information_schema.cardinal_number
+	CharacterSetCatalog    *string `sql:"character_set_catalog"`    // information_schema.sql_identifier
+	CharacterSetSchema     *string `sql:"character_set_schema"`     // information_schema.sql_identifier
+	CharacterSetName       *string `sql:"character_set_name"`       // information_schema.sql_identifier
+	CollationCatalog       *string `sql:"collation_catalog"`        // information_schema.sql_identifier
+	CollationSchema        *string `sql:"collation_schema"`         // information_schema.sql_identifier
+	CollationName          *string `sql:"collation_name"`           // information_schema.sql_identifier
+	DomainCatalog          *string `sql:"domain_catalog"`           // information_schema.sql_identifier
+	DomainSchema           *string `sql:"domain_schema"`            // information_schema.sql_identifier
+	DomainName             *string `sql:"domain_name"`              // information_schema.sql_identifier
+	UdtCatalog             *string `sql:"udt_catalog"`              // information_schema.sql_identifier
+	UdtSchema              *string `sql:"udt_schema"`               // information_schema.sql_identifier
+	UdtName                *string `sql:"udt_name"`                 // information_schema.sql_identifier
+	ScopeCatalog           *string `sql:"scope_catalog"`            // information_schema.sql_identifier
+	ScopeSchema            *string `sql:"scope_schema"`             // information_schema.sql_identifier
+	ScopeName              *string `sql:"scope_name"`               // information_schema.sql_identifier
+	MaximumCardinality     *int    `sql:"maximum_cardinality"`      // information_schema.cardinal_number
+	DtdIdentifier          *string `sql:"dtd_identifier"`           // information_schema.sql_identifier
+	IsSelfReferencing      YesOrNo `sql:"is_self_referencing"`      // information_schema.yes_or_no
+	IsIdentity             YesOrNo `sql:"is_identity"`              // information_schema.yes_or_no
+	IdentityGeneration     *string `sql:"identity_generation"`      // information_schema.character_data
+	IdentityStart          *string `sql:"identity_start"`           // information_schema.character_data
+	IdentityIncrement      *string `sql:"identity_increment"`       // information_schema.character_data
+	IdentityMaximum        *string `sql:"identity_maximum"`         // information_schema.character_data
+	IdentityMinimum        *string `sql:"identity_minimum"`         // information_schema.character_data
+	IdentityCycle          YesOrNo `sql:"identity_cycle"`           // information_schema.yes_or_no
+	IsGenerated            *string `sql:"is_generated"`             // information_schema.character_data
+	GenerationExpression   *string `sql:"generation_expression"`    // information_schema.character_data
+	IsUpdatable            YesOrNo `sql:"is_updatable"`             // information_schema.yes_or_no
 }
@@ -43,6 +43,41 @@ func setup(t *testing.T) *sql.DB {
 func TestColumns(t *testing.T) {
 	db := setup(t)
 	defer db.Close()
+
+	cols, err := Columns(db, PublicTables, WithName("accounts"))
+	if err != nil {
+		t.Fatal("unexpected error calling Columns", err)
+	}
+	if len(cols) != 3 {
+		t.Fatalf("expected 3 columns, found %d", len(cols))
+	}
+	tests := []struct {
+		Description string
+		Expected    interface{}
+		Value       interface{}
+	}{
+		{"ColumnName", "id", cols[0].ColumnName},
+		{"IsNullable", YesOrNo(false), cols[0].IsNullable},
+		{"HasDefault", true, cols[0].ColumnDefault != nil},
+		{"DataType", "character varying", cols[1].DataType},
+		{"Age", YesOrNo(true), cols[2].IsNullable},
+	}
+	for _, tst := range tests {
+		if tst.Expected != tst.Value {
+			t.Errorf("expected value of %q to be %#v, was %#v", tst.Description, tst.Expected, tst.Value)
+		}
+	}
+
+	schema, err := LoadSchema(db, PublicTables, WithName("accounts"))
+	if err != nil {
+		t.Fatal("unexpected error calling LoadSchema", err)
+	}
+	if len(schema.Tables) != 1 || len(schema.Tables[0].Columns) != 3 {
+		t.Fatalf("expected accounts table with 3 columns, got %#v", schema.Tables)
+	}
+	if c := schema.Tables[0].Column("name"); c == nil || c.Type != "character varying" || c.Nullable {
+		t.Errorf("unexpected name column %#v", c)
+	}
 }
 
 func TestTables(t *testing.T) {
@@ -1,10 +1,12 @@
 package gosql
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
-	"time"
+
+	"github.com/dynport/dgtk/gosql/dialect"
 )
 
 type Valuer interface {
@@ -16,36 +18,43 @@ type Map map[string]interface{}
 
 type QueryOption struct {
 	IfNotExists bool
+	Dialect     dialect.Dialect // defaults to dialect.Default
 }
 
 func IfNotExists(o *QueryOption) {
 	o.IfNotExists = true
 }
 
-func (m Map) CreateTableStatement(name string, opts ...func(*QueryOption)) (string, error) {
-	opt := &QueryOption{}
+// ForDialect creates statements for the given dialect.
+func ForDialect(d dialect.Dialect) func(*QueryOption) {
+	return func(o *QueryOption) {
+		o.Dialect = d
+	}
+}
+
+func newQueryOption(opts []func(*QueryOption)) *QueryOption {
+	opt := &QueryOption{Dialect: dialect.Default}
 	for _, o := range opts {
 		o(opt)
 	}
+	return opt
+}
+
+func (m Map) CreateTableStatement(name string, opts ...func(*QueryOption)) (string, error) {
+	opt := newQueryOption(opts)
 	cols, e := m.Columns()
 	if e != nil {
 		return "", e
 	}
 	names := []string{}
 	for _, c := range cols {
-		dbType := ""
-		switch t := c.Value.(type) {
-		case JsonType:
-			dbType = "JSON"
-		case string:
-			dbType = "VARCHAR"
-		case int:
-			dbType = "INTEGER"
-		case time.Time:
-			dbType = "TIMESTAMP WITH TIME ZONE"
-		default:
-			_ = t
-			return "", fmt.Errorf("unable to handle type %T", c.Value)
+		v := c.Value
+		if _, ok := v.(JsonType); ok {
+			v = json.RawMessage(nil)
+		}
+		dbType, e := opt.Dialect.Type(v)
+		if e != nil {
+			return "", e
 		}
 		names = append(names, c.Name+" "+dbType)
 	}
@@ -57,7 +66,8 @@ func (m Map) CreateTableStatement(name string, opts ...func(*QueryOption)) (stri
 
 }
 
-func (m Map) InsertStatement(tableName string) (string, []interface{}, error) {
+func (m Map) InsertStatement(tableName string, opts ...func(*QueryOption)) (string, []interface{}, error) {
+	opt := newQueryOption(opts)
 	cols, err := m.Columns()
 	if err != nil {
 		return "", nil, err
@@ -67,7 +77,7 @@ func (m Map) InsertStatement(tableName string) (string, []interface{}, error) {
 	args := []interface{}{}
 	for i, c := range cols {
 		names = append(names, c.Name)
-		idxs = append(idxs, fmt.Sprintf("$%d", i+1))
+		idxs = append(idxs, opt.Dialect.Placeholder(i+1))
 		args = append(args, c.Value)
 	}
 	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", tableName, strings.Join(names, ", "), strings.Join(idxs, ", ")), args, nil
@@ -0,0 +1,95 @@
+package gosql
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dynport/dgtk/gosql/dialect"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// testMySQL connects to TEST_MYSQL_URL, e.g.
+// "root@tcp(127.0.0.1:3306)/dgtk_migrations" (see the mysql service
+// in migrations/docker-compose.yml).
+func testMySQL(t *testing.T) *sql.DB {
+	url := os.Getenv("TEST_MYSQL_URL")
+	if os.Getenv("TEST_WITH_DB") != "true" || url == "" {
+		t.SkipNow()
+	}
+	db, err := sql.Open("mysql", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{"DROP VIEW IF EXISTS adults", "DROP TABLE IF EXISTS accounts, migrations"} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestMySQL(t *testing.T) {
+	db := testMySQL(t)
+	defer db.Close()
+
+	m := Map{"name": "Linux", "age": 27, "created_at": time.Now()}
+	create, err := m.CreateTableStatement("accounts", ForDialect(dialect.Of(db)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator := NewMigrator(create, "CREATE VIEW adults AS SELECT * FROM accounts WHERE age >= 18")
+	if err := migrator.Execute(db); err != nil {
+		t.Fatal(err)
+	}
+	// executing twice must skip all migrations
+	if err := migrator.Execute(db); err != nil {
+		t.Fatal(err)
+	}
+	insert, args, err := m.InsertStatement("accounts", ForDialect(dialect.Of(db)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(insert, args...); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := db.QueryRow("SELECT name FROM adults").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	tables, err := Tables(db, WithName("accounts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	views, err := Views(db, func(o *viewOptions) { o.Name = "adults" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns, err := Columns(db, WithName("accounts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || len(views) != 1 || len(columns) != 3 {
+		t.Fatalf("expected 1 table, 1 view and 3 columns, got %d, %d and %d", len(tables), len(views), len(columns))
+	}
+	tests := []struct {
+		Name     string
+		Expected interface{}
+		Value    interface{}
+	}{
+		{"dialect", dialect.MySQL, dialect.Of(db)},
+		{"create", "CREATE TABLE accounts (age INTEGER, created_at DATETIME(6), name VARCHAR(255))", create},
+		{"insert", "INSERT INTO accounts (age, created_at, name) VALUES (?, ?, ?)", insert},
+		{"name", "Linux", name},
+		{"table", "accounts", tables[0].TableName},
+		{"type", "BASE TABLE", tables[0].TableType},
+		{"view", "adults", views[0].TableName},
+		{"column", "age", columns[0].ColumnName},
+	}
+	for _, tst := range tests {
+		if tst.Expected != tst.Value {
+			t.Errorf("expected %s to be %#v, was %#v", tst.Name, tst.Expected, tst.Value)
+		}
+	}
+}
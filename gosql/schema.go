@@ -0,0 +1,449 @@
+package gosql
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dynport/dgtk/gosql/dialect"
+)
+
+// Schema describes the tables and views of a database schema. It is loaded
+// with LoadSchema or defined as desired state and compared with Diff.
+type Schema struct {
+	Tables []*SchemaTable
+	Views  []*SchemaView
+}
+
+type SchemaTable struct {
+	Name        string
+	Columns     []*SchemaColumn
+	PrimaryKey  []string
+	Constraints []*Constraint // UNIQUE and CHECK constraints
+	Indexes     []*Index      // indexes not backing a constraint
+	ForeignKeys []*ForeignKey
+}
+
+type SchemaColumn struct {
+	Name     string
+	Type     string // e.g. "character varying(255)"
+	Nullable bool
+	Default  string // expression, empty without default
+}
+
+type Constraint struct {
+	Name    string
+	Type    string   // UNIQUE or CHECK
+	Columns []string // used with UNIQUE
+	Check   string   // expression used with CHECK, e.g. "(age > 0)"
+}
+
+type Index struct {
+	Name    string
+	Unique  bool
+	Columns []string // column names or expressions
+	Where   string   // predicate of partial indexes
+}
+
+type ForeignKey struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+	OnUpdate   string // e.g. "CASCADE", empty for NO ACTION
+	OnDelete   string
+}
+
+type SchemaView struct {
+	Name       string
+	Definition string // the query of the view
+}
+
+// Table returns the table with the given name or nil.
+func (s *Schema) Table(name string) *SchemaTable {
+	for _, t := range s.Tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// View returns the view with the given name or nil.
+func (s *Schema) View(name string) *SchemaView {
+	for _, v := range s.Views {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Column returns the column with the given name or nil.
+func (t *SchemaTable) Column(name string) *SchemaColumn {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// LoadSchema loads the schema of a Postgres (defaults to the current schema,
+// see WithSchema) or SQLite database. WithName restricts the schema to a
+// single table.
+func LoadSchema(db Dbi, funcs ...tableOpt) (*Schema, error) {
+	opt := &tableOptions{}
+	opt.apply(funcs...)
+	var s *Schema
+	var e error
+	switch d := dialect.Of(db); d {
+	case dialect.Postgres:
+		s, e = loadPostgresSchema(db, opt.TableSchema)
+	case dialect.SQLite:
+		s, e = loadSQLiteSchema(db)
+	default:
+		return nil, fmt.Errorf("loading the schema is not supported for %s", d.Name())
+	}
+	if e != nil {
+		return nil, e
+	}
+	if opt.TableName != "" {
+		t := s.Table(opt.TableName)
+		s = &Schema{}
+		if t != nil {
+			s.Tables = append(s.Tables, t)
+		}
+	}
+	return s, nil
+}
+
+func loadPostgresSchema(db Dbi, schema string) (*Schema, error) {
+	if schema == "" {
+		var e error
+		if schema, e = SelectString(db, "SELECT current_schema()"); e != nil {
+			return nil, e
+		}
+	}
+	s := &Schema{}
+	tables := map[string]*SchemaTable{}
+	names, e := SelectStrings(db, "SELECT table_name FROM information_schema.tables WHERE table_schema = $1 AND table_type = 'BASE TABLE' ORDER BY table_name", schema)
+	if e != nil {
+		return nil, e
+	}
+	for _, n := range names {
+		t := &SchemaTable{Name: n}
+		tables[n] = t
+		s.Tables = append(s.Tables, t)
+	}
+
+	// information_schema.columns does not contain the modifiers of types
+	rows, e := db.Query(`SELECT c.relname, a.attname, format_type(a.atttypid, a.atttypmod), NOT a.attnotnull, COALESCE(pg_get_expr(d.adbin, d.adrelid), '')
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE n.nspname = $1 AND c.relkind = 'r' AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY c.relname, a.attnum`, schema)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table string
+		c := &SchemaColumn{}
+		if e := rows.Scan(&table, &c.Name, &c.Type, &c.Nullable, &c.Default); e != nil {
+			return nil, e
+		}
+		if t, ok := tables[table]; ok {
+			t.Columns = append(t.Columns, c)
+		}
+	}
+	if e := rows.Err(); e != nil {
+		return nil, e
+	}
+
+	rows, e = db.Query(`SELECT tc.table_name, tc.constraint_name, tc.constraint_type, kcu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name AND kcu.table_name = tc.table_name
+		WHERE tc.table_schema = $1 AND tc.constraint_type IN ('PRIMARY KEY', 'UNIQUE')
+		ORDER BY tc.table_name, tc.constraint_name, kcu.ordinal_position`, schema)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, name, typ, column string
+		if e := rows.Scan(&table, &name, &typ, &column); e != nil {
+			return nil, e
+		}
+		t, ok := tables[table]
+		if !ok {
+			continue
+		}
+		if typ == "PRIMARY KEY" {
+			t.PrimaryKey = append(t.PrimaryKey, column)
+			continue
+		}
+		if n := len(t.Constraints); n > 0 && t.Constraints[n-1].Name == name {
+			t.Constraints[n-1].Columns = append(t.Constraints[n-1].Columns, column)
+		} else {
+			t.Constraints = append(t.Constraints, &Constraint{Name: name, Type: "UNIQUE", Columns: []string{column}})
+		}
+	}
+	if e := rows.Err(); e != nil {
+		return nil, e
+	}
+
+	// information_schema.check_constraints also contains the NOT NULL checks
+	rows, e = db.Query(`SELECT c.relname, con.conname, pg_get_constraintdef(con.oid)
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND con.contype = 'c'
+		ORDER BY c.relname, con.conname`, schema)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, name, def string
+		if e := rows.Scan(&table, &name, &def); e != nil {
+			return nil, e
+		}
+		if t, ok := tables[table]; ok {
+			t.Constraints = append(t.Constraints, &Constraint{Name: name, Type: "CHECK", Check: strings.TrimPrefix(def, "CHECK ")})
+		}
+	}
+	if e := rows.Err(); e != nil {
+		return nil, e
+	}
+
+	rows, e = db.Query(`SELECT t.relname, i.relname, ix.indisunique, COALESCE(pg_get_expr(ix.indpred, ix.indrelid), ''),
+			array_to_string(ARRAY(SELECT pg_get_indexdef(ix.indexrelid, k + 1, true) FROM generate_subscripts(ix.indkey, 1) AS k ORDER BY k), E'\n')
+		FROM pg_index ix
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE n.nspname = $1 AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = ix.indexrelid)
+		ORDER BY t.relname, i.relname`, schema)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, columns string
+		idx := &Index{}
+		if e := rows.Scan(&table, &idx.Name, &idx.Unique, &idx.Where, &columns); e != nil {
+			return nil, e
+		}
+		idx.Columns = strings.Split(columns, "\n")
+		if t, ok := tables[table]; ok {
+			t.Indexes = append(t.Indexes, idx)
+		}
+	}
+	if e := rows.Err(); e != nil {
+		return nil, e
+	}
+
+	rows, e = db.Query(`SELECT kcu.table_name, kcu.constraint_name, kcu.column_name, ref.table_name, ref.column_name, rc.update_rule, rc.delete_rule
+		FROM information_schema.referential_constraints rc
+		JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema = rc.constraint_schema AND kcu.constraint_name = rc.constraint_name
+		JOIN information_schema.key_column_usage ref ON ref.constraint_schema = rc.unique_constraint_schema AND ref.constraint_name = rc.unique_constraint_name AND ref.ordinal_position = kcu.position_in_unique_constraint
+		WHERE kcu.table_schema = $1
+		ORDER BY kcu.table_name, kcu.constraint_name, kcu.ordinal_position`, schema)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, name, column, refTable, refColumn, onUpdate, onDelete string
+		if e := rows.Scan(&table, &name, &column, &refTable, &refColumn, &onUpdate, &onDelete); e != nil {
+			return nil, e
+		}
+		t, ok := tables[table]
+		if !ok {
+			continue
+		}
+		if n := len(t.ForeignKeys); n > 0 && t.ForeignKeys[n-1].Name == name {
+			fk := t.ForeignKeys[n-1]
+			fk.Columns, fk.RefColumns = append(fk.Columns, column), append(fk.RefColumns, refColumn)
+		} else {
+			t.ForeignKeys = append(t.ForeignKeys, &ForeignKey{Name: name, Columns: []string{column}, RefTable: refTable, RefColumns: []string{refColumn}, OnUpdate: referentialAction(onUpdate), OnDelete: referentialAction(onDelete)})
+		}
+	}
+	if e := rows.Err(); e != nil {
+		return nil, e
+	}
+
+	rows, e = db.Query("SELECT table_name, view_definition FROM information_schema.views WHERE table_schema = $1 ORDER BY table_name", schema)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	for rows.Next() {
+		v := &SchemaView{}
+		if e := rows.Scan(&v.Name, &v.Definition); e != nil {
+			return nil, e
+		}
+		v.Definition = normalizeViewDefinition(v.Definition)
+		s.Views = append(s.Views, v)
+	}
+	return s, rows.Err()
+}
+
+func referentialAction(rule string) string {
+	if rule == "NO ACTION" {
+		return ""
+	}
+	return rule
+}
+
+func normalizeViewDefinition(def string) string {
+	return strings.TrimSuffix(strings.TrimSpace(def), ";")
+}
+
+var createViewRegexp = regexp.MustCompile(`(?is)^\s*CREATE\s+(?:TEMP\s+|TEMPORARY\s+)?VIEW\s+(?:IF\s+NOT\s+EXISTS\s+)?\S+\s+AS\s+`)
+
+func loadSQLiteSchema(db Dbi) (*Schema, error) {
+	s := &Schema{}
+	names, e := SelectStrings(db, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if e != nil {
+		return nil, e
+	}
+	for _, n := range names {
+		t := &SchemaTable{Name: n}
+		s.Tables = append(s.Tables, t)
+		if e := loadSQLiteTable(db, t); e != nil {
+			return nil, e
+		}
+	}
+	rows, e := db.Query("SELECT name, sql FROM sqlite_master WHERE type = 'view' ORDER BY name")
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	for rows.Next() {
+		v := &SchemaView{}
+		if e := rows.Scan(&v.Name, &v.Definition); e != nil {
+			return nil, e
+		}
+		v.Definition = normalizeViewDefinition(createViewRegexp.ReplaceAllString(v.Definition, ""))
+		s.Views = append(s.Views, v)
+	}
+	return s, rows.Err()
+}
+
+func loadSQLiteTable(db Dbi, t *SchemaTable) error {
+	rows, e := db.Query(`SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?) ORDER BY cid`, t.Name)
+	if e != nil {
+		return e
+	}
+	defer rows.Close()
+	pks := map[int]string{}
+	for rows.Next() {
+		var notNull bool
+		var def *string
+		var pk int
+		c := &SchemaColumn{}
+		if e := rows.Scan(&c.Name, &c.Type, &notNull, &def, &pk); e != nil {
+			return e
+		}
+		c.Nullable = !notNull && pk == 0
+		if def != nil {
+			c.Default = *def
+		}
+		if pk > 0 {
+			pks[pk] = c.Name
+		}
+		t.Columns = append(t.Columns, c)
+	}
+	if e := rows.Err(); e != nil {
+		return e
+	}
+	for i := 1; i <= len(pks); i++ {
+		t.PrimaryKey = append(t.PrimaryKey, pks[i])
+	}
+
+	type sqliteIndex struct {
+		Name    string `sql:"name"`
+		Unique  bool   `sql:"unique"`
+		Origin  string `sql:"origin"` // c for CREATE INDEX, u for UNIQUE and pk for PRIMARY KEY
+		Partial bool   `sql:"partial"`
+	}
+	indexes := []*sqliteIndex{}
+	if e := SelectStructs(db, `SELECT name, "unique", origin, partial FROM pragma_index_list(?) ORDER BY name`, &indexes, t.Name); e != nil {
+		return e
+	}
+	for _, i := range indexes {
+		if i.Origin == "pk" {
+			continue
+		}
+		columns, e := SelectStrings(db, "SELECT COALESCE(name, '') FROM pragma_index_info(?) ORDER BY seqno", i.Name)
+		if e != nil {
+			return e
+		}
+		if i.Origin == "u" {
+			t.Constraints = append(t.Constraints, &Constraint{Name: i.Name, Type: "UNIQUE", Columns: columns})
+			continue
+		}
+		idx := &Index{Name: i.Name, Unique: i.Unique, Columns: columns}
+		if i.Partial {
+			var def string
+			if e := db.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'index' AND name = ?", i.Name).Scan(&def); e != nil {
+				return e
+			}
+			if p := strings.Index(strings.ToUpper(def), " WHERE "); p >= 0 {
+				idx.Where = strings.TrimSpace(def[p+7:])
+			}
+		}
+		t.Indexes = append(t.Indexes, idx)
+	}
+
+	type sqliteForeignKey struct {
+		ID       int     `sql:"id"`
+		Table    string  `sql:"table"`
+		From     string  `sql:"from"`
+		To       *string `sql:"to"` // NULL when referencing the primary key
+		OnUpdate string  `sql:"on_update"`
+		OnDelete string  `sql:"on_delete"`
+	}
+	fks := []*sqliteForeignKey{}
+	if e := SelectStructs(db, `SELECT id, "table", "from", "to", on_update, on_delete FROM pragma_foreign_key_list(?) ORDER BY id, seq`, &fks, t.Name); e != nil {
+		return e
+	}
+	byID := map[int]*ForeignKey{}
+	refPKs := map[string][]string{}
+	for _, f := range fks {
+		fk, ok := byID[f.ID]
+		if !ok {
+			fk = &ForeignKey{RefTable: f.Table, OnUpdate: referentialAction(f.OnUpdate), OnDelete: referentialAction(f.OnDelete)}
+			byID[f.ID] = fk
+			t.ForeignKeys = append(t.ForeignKeys, fk)
+		}
+		to := ""
+		if f.To != nil {
+			to = *f.To
+		} else {
+			pk, ok := refPKs[f.Table]
+			if !ok {
+				if pk, e = SelectStrings(db, "SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk", f.Table); e != nil {
+					return e
+				}
+				refPKs[f.Table] = pk
+			}
+			if len(fk.Columns) >= len(pk) {
+				return fmt.Errorf("foreign key of %s references the primary key of %s which has %d columns", t.Name, f.Table, len(pk))
+			}
+			to = pk[len(fk.Columns)]
+		}
+		fk.Columns, fk.RefColumns = append(fk.Columns, f.From), append(fk.RefColumns, to)
+	}
+	// SQLite does not keep the names of foreign keys
+	for _, fk := range t.ForeignKeys {
+		fk.Name = t.Name + "_" + strings.Join(fk.Columns, "_") + "_fkey"
+	}
+	sort.Slice(t.ForeignKeys, func(a, b int) bool { return t.ForeignKeys[a].Name < t.ForeignKeys[b].Name })
+	return nil
+}
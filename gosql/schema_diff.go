@@ -0,0 +1,321 @@
+package gosql
+
+import (
+	"sort"
+	"strings"
+)
+
+// Diff returns the statements migrating schema from to schema to, e.g. a
+// loaded database to a desired model or one database to another. The
+// statements use the Postgres syntax, altering existing columns is not
+// supported by SQLite.
+func Diff(from, to *Schema) []string {
+	if from == nil {
+		from = &Schema{}
+	}
+	if to == nil {
+		to = &Schema{}
+	}
+	out := []string{}
+	// views may depend on the tables and are recreated when changed
+	for _, v := range from.Views {
+		if n := to.View(v.Name); n == nil || n.Definition != v.Definition {
+			out = append(out, "DROP VIEW "+v.Name)
+		}
+	}
+	for _, t := range from.Tables {
+		n := to.Table(t.Name)
+		if n == nil {
+			continue
+		}
+		for _, fk := range t.ForeignKeys {
+			if !containsForeignKey(n.ForeignKeys, fk) {
+				out = append(out, "ALTER TABLE "+t.Name+" DROP CONSTRAINT "+fk.Name)
+			}
+		}
+		for _, c := range t.Constraints {
+			if !containsConstraint(n.Constraints, c) {
+				out = append(out, "ALTER TABLE "+t.Name+" DROP CONSTRAINT "+c.Name)
+			}
+		}
+		for _, i := range t.Indexes {
+			if !containsIndex(n.Indexes, i) {
+				out = append(out, "DROP INDEX "+i.Name)
+			}
+		}
+	}
+	for _, t := range from.Tables {
+		if n := to.Table(t.Name); n == nil {
+			for _, fk := range t.ForeignKeys {
+				out = append(out, "ALTER TABLE "+t.Name+" DROP CONSTRAINT "+fk.Name)
+			}
+		}
+	}
+	for _, t := range from.Tables {
+		if to.Table(t.Name) == nil {
+			out = append(out, "DROP TABLE "+t.Name)
+		}
+	}
+	// foreign keys of created tables are defined inline when the referenced
+	// table exists, the others are added when all tables exist
+	inline := map[*ForeignKey]bool{}
+	exists := map[string]bool{}
+	for _, t := range from.Tables {
+		if to.Table(t.Name) != nil {
+			exists[t.Name] = true
+		}
+	}
+	for _, t := range createOrder(from, to) {
+		for _, fk := range t.ForeignKeys {
+			if exists[fk.RefTable] || fk.RefTable == t.Name {
+				inline[fk] = true
+			}
+		}
+		out = append(out, createTableStatement(t, inline))
+		for _, i := range sortedIndexes(t.Indexes) {
+			out = append(out, createIndexStatement(t.Name, i))
+		}
+		exists[t.Name] = true
+	}
+	for _, t := range to.Tables {
+		o := from.Table(t.Name)
+		if o == nil {
+			continue
+		}
+		out = append(out, alterTableStatements(o, t)...)
+		for _, c := range sortedConstraints(t.Constraints) {
+			if !containsConstraint(o.Constraints, c) {
+				out = append(out, "ALTER TABLE "+t.Name+" ADD "+constraintDefinition(c))
+			}
+		}
+		for _, i := range sortedIndexes(t.Indexes) {
+			if !containsIndex(o.Indexes, i) {
+				out = append(out, createIndexStatement(t.Name, i))
+			}
+		}
+	}
+	for _, t := range to.Tables {
+		var existing []*ForeignKey
+		if o := from.Table(t.Name); o != nil {
+			existing = o.ForeignKeys
+		}
+		for _, fk := range sortedForeignKeys(t.ForeignKeys) {
+			if !inline[fk] && !containsForeignKey(existing, fk) {
+				out = append(out, "ALTER TABLE "+t.Name+" ADD "+foreignKeyDefinition(fk))
+			}
+		}
+	}
+	for _, v := range to.Views {
+		if o := from.View(v.Name); o == nil || o.Definition != v.Definition {
+			out = append(out, "CREATE VIEW "+v.Name+" AS "+v.Definition)
+		}
+	}
+	return out
+}
+
+// DDL returns the statements creating the schema.
+func (s *Schema) DDL() []string {
+	return Diff(nil, s)
+}
+
+// createOrder returns the tables of to missing in from, tables referenced
+// by foreign keys first where possible.
+func createOrder(from, to *Schema) []*SchemaTable {
+	pending := []*SchemaTable{}
+	for _, t := range to.Tables {
+		if from.Table(t.Name) == nil {
+			pending = append(pending, t)
+		}
+	}
+	sort.Slice(pending, func(a, b int) bool { return pending[a].Name < pending[b].Name })
+	isPending := func(name string) bool {
+		for _, t := range pending {
+			if t.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+	out := []*SchemaTable{}
+	for len(pending) > 0 {
+		next := 0 // the first one when all are part of a cycle
+		for i, t := range pending {
+			ready := true
+			for _, fk := range t.ForeignKeys {
+				if fk.RefTable != t.Name && isPending(fk.RefTable) {
+					ready = false
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+		out = append(out, pending[next])
+		pending = append(pending[:next], pending[next+1:]...)
+	}
+	return out
+}
+
+func createTableStatement(t *SchemaTable, inline map[*ForeignKey]bool) string {
+	defs := []string{}
+	for _, c := range t.Columns {
+		defs = append(defs, columnDefinition(c))
+	}
+	if len(t.PrimaryKey) > 0 {
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(t.PrimaryKey, ", ")+")")
+	}
+	for _, c := range sortedConstraints(t.Constraints) {
+		defs = append(defs, constraintDefinition(c))
+	}
+	for _, fk := range sortedForeignKeys(t.ForeignKeys) {
+		if inline[fk] {
+			defs = append(defs, foreignKeyDefinition(fk))
+		}
+	}
+	return "CREATE TABLE " + t.Name + " (" + strings.Join(defs, ", ") + ")"
+}
+
+func alterTableStatements(from, to *SchemaTable) []string {
+	prefix := "ALTER TABLE " + to.Name + " "
+	out := []string{}
+	for _, c := range from.Columns {
+		if to.Column(c.Name) == nil {
+			out = append(out, prefix+"DROP COLUMN "+c.Name)
+		}
+	}
+	for _, c := range to.Columns {
+		o := from.Column(c.Name)
+		if o == nil {
+			out = append(out, prefix+"ADD COLUMN "+columnDefinition(c))
+			continue
+		}
+		alter := prefix + "ALTER COLUMN " + c.Name + " "
+		if !strings.EqualFold(o.Type, c.Type) {
+			out = append(out, alter+"TYPE "+c.Type)
+		}
+		if o.Default != c.Default {
+			if c.Default == "" {
+				out = append(out, alter+"DROP DEFAULT")
+			} else {
+				out = append(out, alter+"SET DEFAULT "+c.Default)
+			}
+		}
+		if o.Nullable != c.Nullable {
+			if c.Nullable {
+				out = append(out, alter+"DROP NOT NULL")
+			} else {
+				out = append(out, alter+"SET NOT NULL")
+			}
+		}
+	}
+	if !equalStrings(from.PrimaryKey, to.PrimaryKey) {
+		if len(from.PrimaryKey) > 0 {
+			out = append(out, prefix+"DROP CONSTRAINT "+to.Name+"_pkey")
+		}
+		if len(to.PrimaryKey) > 0 {
+			out = append(out, prefix+"ADD PRIMARY KEY ("+strings.Join(to.PrimaryKey, ", ")+")")
+		}
+	}
+	return out
+}
+
+func columnDefinition(c *SchemaColumn) string {
+	def := c.Name + " " + c.Type
+	if !c.Nullable {
+		def += " NOT NULL"
+	}
+	if c.Default != "" {
+		def += " DEFAULT " + c.Default
+	}
+	return def
+}
+
+func constraintDefinition(c *Constraint) string {
+	def := "CONSTRAINT " + c.Name + " "
+	if c.Type == "CHECK" {
+		return def + "CHECK " + c.Check
+	}
+	return def + c.Type + " (" + strings.Join(c.Columns, ", ") + ")"
+}
+
+func foreignKeyDefinition(fk *ForeignKey) string {
+	def := "CONSTRAINT " + fk.Name + " FOREIGN KEY (" + strings.Join(fk.Columns, ", ") + ") REFERENCES " + fk.RefTable + " (" + strings.Join(fk.RefColumns, ", ") + ")"
+	if fk.OnUpdate != "" {
+		def += " ON UPDATE " + fk.OnUpdate
+	}
+	if fk.OnDelete != "" {
+		def += " ON DELETE " + fk.OnDelete
+	}
+	return def
+}
+
+func createIndexStatement(table string, i *Index) string {
+	s := "CREATE "
+	if i.Unique {
+		s += "UNIQUE "
+	}
+	s += "INDEX " + i.Name + " ON " + table + " (" + strings.Join(i.Columns, ", ") + ")"
+	if i.Where != "" {
+		s += " WHERE " + i.Where
+	}
+	return s
+}
+
+func containsConstraint(list []*Constraint, c *Constraint) bool {
+	for _, o := range list {
+		if o.Name == c.Name && o.Type == c.Type && o.Check == c.Check && equalStrings(o.Columns, c.Columns) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsIndex(list []*Index, i *Index) bool {
+	for _, o := range list {
+		if o.Name == i.Name && o.Unique == i.Unique && o.Where == i.Where && equalStrings(o.Columns, i.Columns) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsForeignKey(list []*ForeignKey, fk *ForeignKey) bool {
+	for _, o := range list {
+		if o.Name == fk.Name && o.RefTable == fk.RefTable && o.OnUpdate == fk.OnUpdate && o.OnDelete == fk.OnDelete &&
+			equalStrings(o.Columns, fk.Columns) && equalStrings(o.RefColumns, fk.RefColumns) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedConstraints(list []*Constraint) []*Constraint {
+	out := append([]*Constraint{}, list...)
+	sort.Slice(out, func(a, b int) bool { return out[a].Name < out[b].Name })
+	return out
+}
+
+func sortedIndexes(list []*Index) []*Index {
+	out := append([]*Index{}, list...)
+	sort.Slice(out, func(a, b int) bool { return out[a].Name < out[b].Name })
+	return out
+}
+
+func sortedForeignKeys(list []*ForeignKey) []*ForeignKey {
+	out := append([]*ForeignKey{}, list...)
+	sort.Slice(out, func(a, b int) bool { return out[a].Name < out[b].Name })
+	return out
+}
@@ -0,0 +1,167 @@
+package gosql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testModel() *Schema {
+	return &Schema{
+		Tables: []*SchemaTable{
+			{
+				Name: "orders",
+				Columns: []*SchemaColumn{
+					{Name: "id", Type: "INTEGER"},
+					{Name: "user_id", Type: "INTEGER"},
+					{Name: "total", Type: "INTEGER", Default: "0"},
+				},
+				PrimaryKey:  []string{"id"},
+				Indexes:     []*Index{{Name: "orders_total_idx", Columns: []string{"total"}, Where: "total > 0"}},
+				ForeignKeys: []*ForeignKey{{Name: "orders_user_id_fkey", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}, OnDelete: "CASCADE"}},
+			},
+			{
+				Name: "users",
+				Columns: []*SchemaColumn{
+					{Name: "id", Type: "INTEGER"},
+					{Name: "email", Type: "VARCHAR"},
+					{Name: "name", Type: "VARCHAR", Nullable: true},
+				},
+				PrimaryKey: []string{"id"},
+				Indexes:    []*Index{{Name: "users_email_idx", Unique: true, Columns: []string{"email"}}},
+			},
+		},
+		Views: []*SchemaView{{Name: "big_orders", Definition: "SELECT * FROM orders WHERE total > 100"}},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := testModel()
+	to := testModel()
+	users := to.Table("users")
+	users.Columns = append(users.Columns[:2], &SchemaColumn{Name: "age", Type: "INTEGER", Default: "18"})
+	users.Column("email").Nullable = true
+	users.Constraints = []*Constraint{{Name: "users_age_check", Type: "CHECK", Check: "(age > 0)"}}
+	to.Tables = append(to.Tables, &SchemaTable{
+		Name:        "addresses",
+		Columns:     []*SchemaColumn{{Name: "user_id", Type: "INTEGER"}},
+		ForeignKeys: []*ForeignKey{{Name: "addresses_user_id_fkey", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}}},
+	})
+	to.Views[0].Definition = "SELECT * FROM orders WHERE total > 1000"
+
+	tests := []struct {
+		Name     string
+		Expected interface{}
+		Value    interface{}
+	}{
+		{"equal", []string{}, Diff(testModel(), testModel())},
+		{"changes", []string{
+			"DROP VIEW big_orders",
+			"CREATE TABLE addresses (user_id INTEGER NOT NULL, CONSTRAINT addresses_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id))",
+			"ALTER TABLE users DROP COLUMN name",
+			"ALTER TABLE users ALTER COLUMN email DROP NOT NULL",
+			"ALTER TABLE users ADD COLUMN age INTEGER NOT NULL DEFAULT 18",
+			"ALTER TABLE users ADD CONSTRAINT users_age_check CHECK (age > 0)",
+			"CREATE VIEW big_orders AS SELECT * FROM orders WHERE total > 1000",
+		}, Diff(from, to)},
+		{"drop", []string{
+			"DROP VIEW big_orders",
+			"ALTER TABLE orders DROP CONSTRAINT orders_user_id_fkey",
+			"DROP TABLE orders",
+			"DROP TABLE users",
+		}, Diff(testModel(), nil)},
+		{"ddl", []string{
+			"CREATE TABLE users (id INTEGER NOT NULL, email VARCHAR NOT NULL, name VARCHAR, PRIMARY KEY (id))",
+			"CREATE UNIQUE INDEX users_email_idx ON users (email)",
+			"CREATE TABLE orders (id INTEGER NOT NULL, user_id INTEGER NOT NULL, total INTEGER NOT NULL DEFAULT 0, PRIMARY KEY (id), CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE)",
+			"CREATE INDEX orders_total_idx ON orders (total) WHERE total > 0",
+			"CREATE VIEW big_orders AS SELECT * FROM orders WHERE total > 100",
+		}, testModel().DDL()},
+	}
+	for _, tst := range tests {
+		if !reflect.DeepEqual(tst.Expected, tst.Value) {
+			t.Errorf("expected %s to be %#v, was %#v", tst.Name, tst.Expected, tst.Value)
+		}
+	}
+}
+
+func TestSchemaSQLite(t *testing.T) {
+	db := testSQLite(t)
+	defer db.Close()
+	model := testModel()
+	for _, s := range model.DDL() {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatalf("%s: %s", s, err)
+		}
+	}
+	loaded, err := LoadSchema(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := Diff(loaded, model); len(diff) > 0 {
+		t.Errorf("expected loaded schema to equal model, got diff %#v", diff)
+	}
+	if _, err := db.Exec("ALTER TABLE users ADD COLUMN age INTEGER"); err != nil {
+		t.Fatal(err)
+	}
+	users, err := LoadSchema(db, WithName("users"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols, err := Columns(db, WithName("users"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != 4 {
+		t.Fatalf("expected 4 columns, got %d", len(cols))
+	}
+	tests := []struct {
+		Name     string
+		Expected interface{}
+		Value    interface{}
+	}{
+		{"tables", 1, len(users.Tables)},
+		{"diff", []string{"ALTER TABLE users ADD COLUMN age INTEGER"}, Diff(model, &Schema{Tables: []*SchemaTable{model.Table("orders"), users.Table("users")}, Views: model.Views})},
+		{"column", "email", cols[1].ColumnName},
+		{"position", 2, cols[1].OrdinalPosition},
+		{"type", "varchar", cols[1].DataType},
+		{"not null", YesOrNo(false), cols[1].IsNullable},
+		{"nullable", YesOrNo(true), cols[3].IsNullable},
+	}
+	for _, tst := range tests {
+		if !reflect.DeepEqual(tst.Expected, tst.Value) {
+			t.Errorf("expected %s to be %#v, was %#v", tst.Name, tst.Expected, tst.Value)
+		}
+	}
+}
+
+func TestSchemaSQLiteImplicitReference(t *testing.T) {
+	db := testSQLite(t)
+	defer db.Close()
+	for _, s := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY)",
+		"CREATE TABLE memberships (group_name VARCHAR NOT NULL, user_id INTEGER NOT NULL, PRIMARY KEY (group_name, user_id))",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users)",
+		"CREATE TABLE invites (id INTEGER PRIMARY KEY, group_name VARCHAR, user_id INTEGER, FOREIGN KEY (group_name, user_id) REFERENCES memberships)",
+	} {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatalf("%s: %s", s, err)
+		}
+	}
+	s, err := LoadSchema(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		Name     string
+		Expected interface{}
+		Value    interface{}
+	}{
+		{"orders", []*ForeignKey{{Name: "orders_user_id_fkey", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}}}, s.Table("orders").ForeignKeys},
+		{"invites", []*ForeignKey{{Name: "invites_group_name_user_id_fkey", Columns: []string{"group_name", "user_id"}, RefTable: "memberships", RefColumns: []string{"group_name", "user_id"}}}, s.Table("invites").ForeignKeys},
+	}
+	for _, tst := range tests {
+		if !reflect.DeepEqual(tst.Expected, tst.Value) {
+			t.Errorf("expected %s foreign keys to be %#v, was %#v", tst.Name, tst.Expected, tst.Value)
+		}
+	}
+}
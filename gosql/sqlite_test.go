@@ -0,0 +1,177 @@
+package gosql
+
+import (
+	"bytes"
+	"database/sql"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dynport/dgtk/gosql/dialect"
+	engine "github.com/dynport/dgtk/migrations"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func testSQLite(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSQLite(t *testing.T) {
+	db := testSQLite(t)
+	defer db.Close()
+
+	m := Map{"name": "Linux", "age": 27, "created_at": time.Now()}
+	create, err := m.CreateTableStatement("accounts", ForDialect(dialect.SQLite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewMigrator(create, "CREATE VIEW adults AS SELECT * FROM accounts WHERE age >= 18").Execute(db); err != nil {
+		t.Fatal(err)
+	}
+	insert, args, err := m.InsertStatement("accounts", ForDialect(dialect.SQLite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(insert, args...); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := db.QueryRow("SELECT name FROM adults").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	tables, err := Tables(WithDialect(tx, dialect.SQLite), WithName("accounts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	views, err := Views(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || len(views) != 1 {
+		t.Fatalf("expected 1 table and 1 view, got %d and %d", len(tables), len(views))
+	}
+	tests := []struct {
+		Name     string
+		Expected interface{}
+		Value    interface{}
+	}{
+		{"create", "CREATE TABLE accounts (age INTEGER, created_at TIMESTAMP, name VARCHAR)", create},
+		{"insert", "INSERT INTO accounts (age, created_at, name) VALUES (?, ?, ?)", insert},
+		{"name", "Linux", name},
+		{"table", "accounts", tables[0].TableName},
+		{"type", "BASE TABLE", tables[0].TableType},
+		{"view", "adults", views[0].TableName},
+	}
+	for _, tst := range tests {
+		if tst.Expected != tst.Value {
+			t.Errorf("expected %s to be %#v, was %#v", tst.Name, tst.Expected, tst.Value)
+		}
+	}
+}
+
+func TestMigratorSQLite(t *testing.T) {
+	db := testSQLite(t)
+	defer db.Close()
+
+	m := NewMigrator("CREATE TABLE users (id INTEGER PRIMARY KEY)", "INSERT INTO users (id) VALUES (77)")
+	logs := &bytes.Buffer{}
+	m.Logger = log.New(logs, "", 0)
+	migs, err := m.migrations(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := migs.ExecuteUntil(db, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := migs.ExecuteUntil(db, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rest, err := migs.Execute(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the engine of package migrations must see the same history
+	status, err := engine.New("CREATE TABLE users (id INTEGER PRIMARY KEY)", "INSERT INTO users (id) VALUES (77)").Status(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := SelectInt(db, "SELECT id FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		Name     string
+		Expected interface{}
+		Value    interface{}
+	}{
+		{"first", 1, first},
+		{"again", 0, again},
+		{"rest", 1, rest},
+		{"executed", true, migs[1].Executed},
+		{"status", engine.StateExecuted, status[1].State},
+		{"id", 77, id},
+		{"logs", "EXECUTE 1,SKIP 1,SKIP 1,EXECUTE 2", logTypes(logs.String())},
+	}
+	for _, tst := range tests {
+		if tst.Expected != tst.Value {
+			t.Errorf("expected %s to be %#v, was %#v", tst.Name, tst.Expected, tst.Value)
+		}
+	}
+}
+
+// logTypes returns the type and index of all migration log lines.
+func logTypes(logs string) string {
+	out := []string{}
+	for _, l := range strings.Split(strings.TrimSpace(logs), "\n") {
+		if f := strings.Fields(l); len(f) > 2 {
+			out = append(out, strings.TrimSuffix(f[0], ":")+" "+f[2])
+		}
+	}
+	return strings.Join(out, ",")
+}
+
+func TestMigrationResultSQLite(t *testing.T) {
+	db := testSQLite(t)
+	defer db.Close()
+
+	migs, err := NewMigrator("CREATE TABLE users (id INTEGER PRIMARY KEY)", "INSERT INTO users (id) VALUES (1), (2)").migrations(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migs[0].Execute(db); err != nil {
+		t.Fatal(err)
+	}
+	res, err := migs[1].Execute(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res == nil {
+		t.Fatal("expected result of the statement")
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected != 2 {
+		t.Errorf("expected 2 rows to be affected, got %d", affected)
+	}
+	if res, err = migs[1].Execute(db); err != nil {
+		t.Fatal(err)
+	} else if res != nil {
+		t.Errorf("expected no result for an executed migration, got %#v", res)
+	}
+}
@@ -45,19 +45,8 @@ func UnmarshalRow(rows Rows, i interface{}) error {
 		v = v.Elem()
 	}
 	fields := map[string]reflect.Value{}
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		name := v.Type().Field(i).Tag.Get("sql")
-		if name == "" {
-			name = v.Type().Field(i).Tag.Get("json")
-		}
-		if name != "" {
-			parts := strings.Split(name, ",")
-			if len(parts) > 1 {
-				name = parts[0]
-			}
-			fields[name] = field
-		}
+	for _, f := range structFields(v) {
+		fields[f.Name] = f.Value
 	}
 	columns, e := rows.Columns()
 	if e != nil {
@@ -76,3 +65,51 @@ func UnmarshalRow(rows Rows, i interface{}) error {
 	}
 	return rows.Scan(out...)
 }
+
+type structField struct {
+	Name    string
+	Value   reflect.Value
+	PK      bool // tag option "pk"
+	Default bool // tag option "default"
+}
+
+// structFields returns the exported fields of v with a column name in the sql
+// or json tag, e.g. `sql:"id,pk"`. Fields tagged with "-" or without name
+// (e.g. `json:",omitempty"`) are skipped. The field for column "id" is the
+// primary key when no field has the option "pk".
+func structFields(v reflect.Value) []*structField {
+	out := []*structField{}
+	hasPK := false
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Tag.Get("sql")
+		if name == "" {
+			name = field.Tag.Get("json")
+		}
+		parts := strings.Split(name, ",")
+		if parts[0] == "" || parts[0] == "-" {
+			continue
+		}
+		f := &structField{Name: parts[0], Value: v.Field(i)}
+		for _, o := range parts[1:] {
+			switch o {
+			case "pk":
+				f.PK, hasPK = true, true
+			case "default":
+				f.Default = true
+			}
+		}
+		out = append(out, f)
+	}
+	if !hasPK {
+		for _, f := range out {
+			if f.Name == "id" {
+				f.PK = true
+			}
+		}
+	}
+	return out
+}
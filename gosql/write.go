@@ -0,0 +1,375 @@
+package gosql
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/dynport/dgtk/gosql/dialect"
+	"github.com/lib/pq"
+)
+
+// Insert inserts the struct i (a pointer) into table. The columns are taken
+// from the sql or json tags like in UnmarshalRow. The primary key (tag option
+// "pk" or the column "id") and columns with the tag option "default" are left
+// to the database when zero and read back into i, e.g.
+//
+//	type User struct {
+//		ID        int       `sql:"id,pk"`
+//		Name      string    `sql:"name"`
+//		CreatedAt time.Time `sql:"created_at,default"`
+//	}
+//
+// Dialects without RETURNING (MySQL) use the last insert id and select the
+// default columns afterwards. The dialect of a transaction is unknown, so
+// Insert, Update, Upsert and BulkInsert return an error for transactions not
+// wrapped with WithDialect.
+func Insert(db Dbi, table string, i interface{}) error {
+	fields, e := writableFields(i)
+	if e != nil {
+		return e
+	}
+	d, e := dialect.Lookup(db)
+	if e != nil {
+		return e
+	}
+	q, args := insertStatement(d, table, fields)
+	return write(db, d, table, fields, q, args, nil)
+}
+
+// Update updates the row of struct i (a pointer) identified by its primary
+// key. Columns with the tag option "default" are not updated when zero and
+// read back into i. Returns sql.ErrNoRows when no row was updated (not
+// detected with MySQL).
+func Update(db Dbi, table string, i interface{}) error {
+	fields, e := writableFields(i)
+	if e != nil {
+		return e
+	}
+	pks := primaryKeys(fields)
+	if len(pks) == 0 {
+		return fmt.Errorf("%T has no primary key", i)
+	}
+	d, e := dialect.Lookup(db)
+	if e != nil {
+		return e
+	}
+	set, args := []string{}, []interface{}{}
+	for _, f := range fields {
+		if !f.PK && !(f.Default && f.Value.IsZero()) {
+			args = append(args, f.Value.Interface())
+			set = append(set, f.Name+" = "+d.Placeholder(len(args)))
+		}
+	}
+	if len(set) == 0 {
+		return fmt.Errorf("%T has no columns to update", i)
+	}
+	where := []string{}
+	for _, f := range pks {
+		args = append(args, f.Value.Interface())
+		where = append(where, f.Name+" = "+d.Placeholder(len(args)))
+	}
+	q := "UPDATE " + table + " SET " + strings.Join(set, ", ") + " WHERE " + strings.Join(where, " AND ")
+	returning := []*structField{}
+	for _, f := range fields {
+		if f.Default {
+			returning = append(returning, f)
+		}
+	}
+	if len(returning) > 0 && d.Returning() {
+		return db.QueryRow(q+" RETURNING "+strings.Join(fieldNames(returning), ", "), args...).Scan(fieldPointers(returning)...)
+	}
+	res, e := db.Exec(q, args...)
+	if e != nil {
+		return e
+	}
+	if d != dialect.MySQL {
+		// MySQL does not count rows updated with the same values
+		if n, e := res.RowsAffected(); e == nil && n == 0 {
+			return sql.ErrNoRows
+		}
+	}
+	if len(returning) > 0 {
+		return reload(db, d, table, returning, pks)
+	}
+	return nil
+}
+
+// Upsert inserts i (a pointer) or updates all inserted columns of the
+// existing row with the same conflict columns (defaults to the primary key).
+// The primary key and default columns are read back like with Insert.
+func Upsert(db Dbi, table string, i interface{}, conflict ...string) error {
+	fields, e := writableFields(i)
+	if e != nil {
+		return e
+	}
+	if len(conflict) == 0 {
+		conflict = fieldNames(primaryKeys(fields))
+		if len(conflict) == 0 {
+			return fmt.Errorf("%T has no primary key and no conflict columns are given", i)
+		}
+	}
+	d, e := dialect.Lookup(db)
+	if e != nil {
+		return e
+	}
+	q, args := insertStatement(d, table, fields)
+	isConflict := map[string]bool{}
+	for _, c := range conflict {
+		isConflict[c] = true
+	}
+	update := []string{}
+	for _, f := range insertFields(fields) {
+		if !isConflict[f.Name] {
+			update = append(update, f.Name)
+		}
+	}
+	if len(update) == 0 {
+		// updating a conflict column with the same value returns the row
+		update = conflict
+	}
+	q += " " + d.OnConflict(conflict, update)
+	where := []*structField{}
+	for _, f := range fields {
+		if isConflict[f.Name] {
+			where = append(where, f)
+		}
+	}
+	return write(db, d, table, fields, q, args, where)
+}
+
+func writableFields(i interface{}) ([]*structField, error) {
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected pointer to struct, got %T", i)
+	}
+	fields := structFields(v.Elem())
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%T has no fields with sql or json tags", i)
+	}
+	return fields, nil
+}
+
+// insertFields returns all fields but zero primary keys and default columns.
+func insertFields(fields []*structField) []*structField {
+	out := []*structField{}
+	for _, f := range fields {
+		if !((f.PK || f.Default) && f.Value.IsZero()) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func insertStatement(d dialect.Dialect, table string, fields []*structField) (string, []interface{}) {
+	cols := insertFields(fields)
+	if len(cols) == 0 {
+		if d == dialect.MySQL {
+			return "INSERT INTO " + table + " () VALUES ()", nil
+		}
+		return "INSERT INTO " + table + " DEFAULT VALUES", nil
+	}
+	idxs, args := []string{}, []interface{}{}
+	for i, f := range cols {
+		idxs = append(idxs, d.Placeholder(i+1))
+		args = append(args, f.Value.Interface())
+	}
+	return "INSERT INTO " + table + " (" + strings.Join(fieldNames(cols), ", ") + ") VALUES (" + strings.Join(idxs, ", ") + ")", args
+}
+
+// write executes the insert q and reads back the primary keys and default
+// columns. Without RETURNING the row is selected by where or the last insert
+// id when where is empty or zero.
+func write(db Dbi, d dialect.Dialect, table string, fields []*structField, q string, args []interface{}, where []*structField) error {
+	returning := []*structField{}
+	for _, f := range fields {
+		if f.PK || f.Default {
+			returning = append(returning, f)
+		}
+	}
+	if len(returning) == 0 {
+		_, e := db.Exec(q, args...)
+		return e
+	}
+	if d.Returning() {
+		return db.QueryRow(q+" RETURNING "+strings.Join(fieldNames(returning), ", "), args...).Scan(fieldPointers(returning)...)
+	}
+	res, e := db.Exec(q, args...)
+	if e != nil {
+		return e
+	}
+	pks := primaryKeys(fields)
+	if len(where) == 0 || hasZero(where) {
+		if len(pks) != 1 {
+			return fmt.Errorf("reading back columns requires a single primary key, found %d", len(pks))
+		}
+		if pk := pks[0]; pk.Value.IsZero() {
+			id, e := res.LastInsertId()
+			if e != nil {
+				return e
+			}
+			switch pk.Value.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				pk.Value.SetInt(id)
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				pk.Value.SetUint(uint64(id))
+			default:
+				return fmt.Errorf("unable to set last insert id on primary key of kind %s", pk.Value.Kind())
+			}
+		}
+		where = pks
+	}
+	return reload(db, d, table, returning, where)
+}
+
+// reload selects the fields of the row identified by the values of where.
+func reload(db Dbi, d dialect.Dialect, table string, fields, where []*structField) error {
+	cond, args := []string{}, []interface{}{}
+	for _, f := range where {
+		args = append(args, f.Value.Interface())
+		cond = append(cond, f.Name+" = "+d.Placeholder(len(args)))
+	}
+	q := "SELECT " + strings.Join(fieldNames(fields), ", ") + " FROM " + table + " WHERE " + strings.Join(cond, " AND ")
+	return db.QueryRow(q, args...).Scan(fieldPointers(fields)...)
+}
+
+// BulkInsert inserts all elements of list (a slice of structs or pointers to
+// structs). Postgres uses COPY when db is a transaction wrapped with
+// WithDialect, all others multi-row INSERTs. Primary keys and default columns are only inserted when not zero
+// in at least one element and are not read back.
+func BulkInsert(db Dbi, table string, list interface{}) error {
+	v := reflect.ValueOf(list)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("expected slice, got %T", list)
+	}
+	if v.Len() == 0 {
+		return nil
+	}
+	rows := make([][]*structField, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		el := reflect.Indirect(v.Index(i))
+		if el.Kind() != reflect.Struct {
+			return fmt.Errorf("expected slice of structs, got %T", list)
+		}
+		rows = append(rows, structFields(el))
+	}
+	include := []int{}
+	for i, f := range rows[0] {
+		if !f.PK && !f.Default {
+			include = append(include, i)
+			continue
+		}
+		for _, row := range rows {
+			if !row[i].Value.IsZero() {
+				include = append(include, i)
+				break
+			}
+		}
+	}
+	if len(include) == 0 {
+		return fmt.Errorf("%T has no columns to insert", list)
+	}
+	cols := []string{}
+	for _, i := range include {
+		cols = append(cols, rows[0][i].Name)
+	}
+	values := func(row []*structField) []interface{} {
+		out := make([]interface{}, 0, len(include))
+		for _, i := range include {
+			out = append(out, row[i].Value.Interface())
+		}
+		return out
+	}
+	d, e := dialect.Lookup(db)
+	if e != nil {
+		return e
+	}
+	if tx, ok := unwrap(db).(*sql.Tx); ok && d == dialect.Postgres {
+		return copyIn(tx, table, cols, rows, values)
+	}
+	perInsert := maxBulkParams / len(cols)
+	if perInsert == 0 {
+		perInsert = 1
+	}
+	for start := 0; start < len(rows); start += perInsert {
+		end := start + perInsert
+		if end > len(rows) {
+			end = len(rows)
+		}
+		tuples, args := []string{}, []interface{}{}
+		for _, row := range rows[start:end] {
+			idxs := []string{}
+			for _, a := range values(row) {
+				args = append(args, a)
+				idxs = append(idxs, d.Placeholder(len(args)))
+			}
+			tuples = append(tuples, "("+strings.Join(idxs, ", ")+")")
+		}
+		q := "INSERT INTO " + table + " (" + strings.Join(cols, ", ") + ") VALUES " + strings.Join(tuples, ", ")
+		if _, e := db.Exec(q, args...); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// SQLite before 3.32 allows at most 999 parameters per statement
+const maxBulkParams = 999
+
+func copyIn(tx *sql.Tx, table string, cols []string, rows [][]*structField, values func([]*structField) []interface{}) error {
+	q := pq.CopyIn(table, cols...)
+	if parts := strings.SplitN(table, ".", 2); len(parts) == 2 {
+		q = pq.CopyInSchema(parts[0], parts[1], cols...)
+	}
+	stmt, e := tx.Prepare(q)
+	if e != nil {
+		return e
+	}
+	defer stmt.Close()
+	for _, row := range rows {
+		if _, e := stmt.Exec(values(row)...); e != nil {
+			return e
+		}
+	}
+	_, e = stmt.Exec()
+	return e
+}
+
+func primaryKeys(fields []*structField) []*structField {
+	out := []*structField{}
+	for _, f := range fields {
+		if f.PK {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func hasZero(fields []*structField) bool {
+	for _, f := range fields {
+		if f.Value.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldNames(fields []*structField) []string {
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		out = append(out, f.Name)
+	}
+	return out
+}
+
+func fieldPointers(fields []*structField) []interface{} {
+	out := make([]interface{}, 0, len(fields))
+	for _, f := range fields {
+		out = append(out, f.Value.Addr().Interface())
+	}
+	return out
+}
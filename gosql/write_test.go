@@ -0,0 +1,194 @@
+package gosql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dynport/dgtk/gosql/dialect"
+)
+
+type writeUser struct {
+	ID        int       `sql:"id"`
+	Name      string    `sql:"name"`
+	Email     string    `json:"email,omitempty"`
+	Version   int       `sql:"version,default"`
+	CreatedAt time.Time `sql:"created_at,default"`
+	Ignored   string
+}
+
+func TestInsertStatement(t *testing.T) {
+	u := &writeUser{Name: "Linux", Email: "linux@example.com"}
+	fields := structFields(reflect.ValueOf(u).Elem())
+	pg, pgArgs := insertStatement(dialect.Postgres, "users", fields)
+	my, _ := insertStatement(dialect.MySQL, "users", fields)
+	empty, _ := insertStatement(dialect.SQLite, "users", nil)
+	tests := []struct {
+		Name     string
+		Expected interface{}
+		Value    interface{}
+	}{
+		{"postgres", "INSERT INTO users (name, email) VALUES ($1, $2)", pg},
+		{"args", 2, len(pgArgs)},
+		{"mysql", "INSERT INTO users (name, email) VALUES (?, ?)", my},
+		{"empty", "INSERT INTO users DEFAULT VALUES", empty},
+		{"pk", true, fields[0].PK},
+		{"on conflict", "ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name", dialect.Postgres.OnConflict([]string{"id"}, []string{"name"})},
+		{"on duplicate", "ON DUPLICATE KEY UPDATE name = VALUES(name)", dialect.MySQL.OnConflict([]string{"id"}, []string{"name"})},
+	}
+	for _, tst := range tests {
+		if tst.Expected != tst.Value {
+			t.Errorf("expected %s to be %#v, was %#v", tst.Name, tst.Expected, tst.Value)
+		}
+	}
+}
+
+func TestWriteSQLite(t *testing.T) {
+	db := testSQLite(t)
+	defer db.Close()
+	_, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR NOT NULL UNIQUE, email VARCHAR, version INTEGER NOT NULL DEFAULT 1, created_at TIMESTAMP NOT NULL DEFAULT '2015-03-04 05:06:07')")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &writeUser{Name: "Linux", Email: "linux@example.com"}
+	if err := Insert(db, "users", u); err != nil {
+		t.Fatal(err)
+	}
+	u.Email = "torvalds@example.com"
+	if err := Update(db, "users", u); err != nil {
+		t.Fatal(err)
+	}
+	missing := Update(db, "users", &writeUser{ID: 99, Name: "Missing"})
+
+	upserted := &writeUser{Name: "Linux", Email: "upserted@example.com", Version: 2}
+	if err := Upsert(db, "users", upserted, "name"); err != nil {
+		t.Fatal(err)
+	}
+	bulk := []*writeUser{{Name: "BSD"}, {Name: "Plan 9", Email: "plan9@example.com"}}
+	if err := BulkInsert(db, "users", bulk); err != nil {
+		t.Fatal(err)
+	}
+	// without RETURNING the last insert id is used and the row is selected
+	fallback := &writeUser{Name: "Hurd"}
+	if err := Insert(WithDialect(db, dialect.MySQL), "users", fallback); err != nil {
+		t.Fatal(err)
+	}
+	var all []*writeUser
+	if err := SelectStructs(db, "SELECT id, name, email, version FROM users ORDER BY id", &all); err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 {
+		t.Fatalf("expected 4 users, got %d", len(all))
+	}
+	tests := []struct {
+		Name     string
+		Expected interface{}
+		Value    interface{}
+	}{
+		{"id", 1, u.ID},
+		{"version", 1, u.Version},
+		{"created_at", 2015, u.CreatedAt.Year()},
+		{"missing", "sql: no rows in result set", missing.Error()},
+		{"upserted id", 1, upserted.ID},
+		{"upserted email", "upserted@example.com", all[0].Email},
+		{"upserted version", 2, all[0].Version},
+		{"bulk", "Plan 9", all[2].Name},
+		{"bulk email", "plan9@example.com", all[2].Email},
+		{"bulk version", 1, all[2].Version},
+		{"fallback id", 4, fallback.ID},
+		{"fallback created_at", 2015, fallback.CreatedAt.Year()},
+	}
+	for _, tst := range tests {
+		if tst.Expected != tst.Value {
+			t.Errorf("expected %s to be %#v, was %#v", tst.Name, tst.Expected, tst.Value)
+		}
+	}
+}
+
+type taggedUser struct {
+	ID      int    `sql:"id"`
+	Name    string `sql:"name"`
+	Secret  string `json:"-"`
+	Comment string `json:",omitempty"`
+	email   string `sql:"email"`
+}
+
+func TestWriteSkippedFields(t *testing.T) {
+	db := testSQLite(t)
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR NOT NULL, email VARCHAR)"); err != nil {
+		t.Fatal(err)
+	}
+	u := &taggedUser{Name: "Linux", Secret: "secret", Comment: "comment", email: "linux@example.com"}
+	names := fieldNames(structFields(reflect.ValueOf(u).Elem()))
+	insertErr := Insert(db, "users", u)
+	bulkErr := BulkInsert(db, "users", []*taggedUser{{Name: "BSD", Secret: "secret", email: "bsd@example.com"}})
+	var emails int
+	if err := db.QueryRow("SELECT COUNT(email) FROM users").Scan(&emails); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		Name     string
+		Expected interface{}
+		Value    interface{}
+	}{
+		{"names", "id,name", strings.Join(names, ",")},
+		{"insert", nil, insertErr},
+		{"bulk insert", nil, bulkErr},
+		{"id", 1, u.ID},
+		{"unexported", 0, emails},
+	}
+	for _, tst := range tests {
+		if tst.Expected != tst.Value {
+			t.Errorf("expected %s to be %#v, was %#v", tst.Name, tst.Expected, tst.Value)
+		}
+	}
+}
+
+func TestWriteSQLiteTx(t *testing.T) {
+	db := testSQLite(t)
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR NOT NULL, email VARCHAR, version INTEGER NOT NULL DEFAULT 1, created_at TIMESTAMP NOT NULL DEFAULT '2015-03-04 05:06:07')"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	// the dialect of a transaction is unknown
+	plainInsert := Insert(tx, "users", &writeUser{Name: "Linux"})
+	plainBulk := BulkInsert(tx, "users", []*writeUser{{Name: "BSD"}})
+
+	con := WithDialect(tx, dialect.SQLite)
+	u := &writeUser{Name: "Linux"}
+	if err := Insert(con, "users", u); err != nil {
+		t.Fatal(err)
+	}
+	if err := BulkInsert(con, "users", []*writeUser{{Name: "BSD"}, {Name: "Plan 9"}}); err != nil {
+		t.Fatal(err)
+	}
+	var cnt int
+	if err := tx.QueryRow("SELECT COUNT(1) FROM users").Scan(&cnt); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		Name     string
+		Expected interface{}
+		Value    interface{}
+	}{
+		{"plain insert", true, plainInsert != nil},
+		{"plain bulk insert", true, plainBulk != nil},
+		{"id", 1, u.ID},
+		{"version", 1, u.Version},
+		{"count", 3, cnt},
+	}
+	for _, tst := range tests {
+		if tst.Expected != tst.Value {
+			t.Errorf("expected %s to be %#v, was %#v", tst.Name, tst.Expected, tst.Value)
+		}
+	}
+}
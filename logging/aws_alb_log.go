@@ -0,0 +1,151 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	albLogType = iota
+	albLogTimestamp
+	albLogElb
+	albLogClientAndPort
+	albLogTargetAndPort
+	albLogRequestProcessingTime
+	albLogTargetProcessingTime
+	albLogResponseProcessingTime
+	albLogElbStatusCode
+	albLogTargetStatusCode
+	albLogReceivedBytes
+	albLogSentBytes
+	albLogRequest
+	albLogUserAgent
+	albLogSSLCipher
+	albLogSSLProtocol
+	albLogTargetGroupArn
+	albLogTraceId
+	albLogDomainName
+	albLogChosenCertArn
+	albLogMatchedRulePriority
+	albLogRequestCreationTime
+	albLogActionsExecuted
+	albLogRedirectUrl
+	albLogErrorReason
+)
+
+// ApplicationLoadBalancerLog is a line of an AWS application load balancer
+// access log.
+type ApplicationLoadBalancerLog struct {
+	Type                   string    `json:"type,omitempty"`
+	Timestamp              time.Time `json:"timestamp,omitempty"`
+	Elb                    string    `json:"elb,omitempty"`
+	ClientAndPort          string    `json:"client_and_port,omitempty"`
+	TargetAndPort          string    `json:"target_and_port,omitempty"`
+	RequestProcessingTime  float64   `json:"request_processing_time,omitempty"`
+	TargetProcessingTime   float64   `json:"target_processing_time,omitempty"`
+	ResponseProcessingTime float64   `json:"response_processing_time,omitempty"`
+	ElbStatusCode          int       `json:"elb_status_code,omitempty"`
+	TargetStatusCode       int       `json:"target_status_code,omitempty"`
+	ReceivedBytes          int       `json:"received_bytes,omitempty"`
+	SentBytes              int       `json:"sent_bytes,omitempty"`
+	Method                 string    `json:"method,omitempty"`
+	Url                    string    `json:"url,omitempty"`
+	UserAgent              string    `json:"user_agent,omitempty"`
+	SSLCipher              string    `json:"ssl_cipher,omitempty"`
+	SSLProtocol            string    `json:"ssl_protocol,omitempty"`
+	TargetGroupArn         string    `json:"target_group_arn,omitempty"`
+	TraceId                string    `json:"trace_id,omitempty"`
+	DomainName             string    `json:"domain_name,omitempty"`
+	ChosenCertArn          string    `json:"chosen_cert_arn,omitempty"`
+	MatchedRulePriority    int       `json:"matched_rule_priority,omitempty"`
+	RequestCreationTime    time.Time `json:"request_creation_time,omitempty"`
+	ActionsExecuted        []string  `json:"actions_executed,omitempty"`
+	RedirectUrl            string    `json:"redirect_url,omitempty"`
+	ErrorReason            string    `json:"error_reason,omitempty"`
+	RAW                    string    `json:"raw,omitempty"`
+}
+
+const albTimeLayout = "2006-01-02T15:04:05.999999Z"
+
+func (l *ApplicationLoadBalancerLog) Load(raw string) error {
+	l.RAW = raw
+	fields, e := awsFields(raw)
+	if e != nil {
+		return e
+	}
+	if len(fields) <= albLogSSLProtocol {
+		return fmt.Errorf("expected at least %d fields, got %d", albLogSSLProtocol+1, len(fields))
+	}
+	for i, f := range fields {
+		switch i {
+		case albLogType:
+			switch f {
+			case "http", "https", "h2", "grpcs", "ws", "wss":
+				l.Type = f
+			default:
+				return fmt.Errorf("type %q not supported", f)
+			}
+		case albLogTimestamp:
+			l.Timestamp, e = time.Parse(albTimeLayout, f)
+		case albLogElb:
+			l.Elb = f
+		case albLogClientAndPort:
+			l.ClientAndPort = f
+		case albLogTargetAndPort:
+			l.TargetAndPort = filterDash(f)
+		case albLogRequestProcessingTime:
+			l.RequestProcessingTime, e = awsFloat(f)
+		case albLogTargetProcessingTime:
+			l.TargetProcessingTime, e = awsFloat(f)
+		case albLogResponseProcessingTime:
+			l.ResponseProcessingTime, e = awsFloat(f)
+		case albLogElbStatusCode:
+			l.ElbStatusCode, e = awsInt(f)
+		case albLogTargetStatusCode:
+			l.TargetStatusCode, e = awsInt(f)
+		case albLogReceivedBytes:
+			l.ReceivedBytes, e = awsInt(f)
+		case albLogSentBytes:
+			l.SentBytes, e = awsInt(f)
+		case albLogRequest:
+			parts := strings.Split(f, " ")
+			if len(parts) == 3 {
+				l.Method = parts[0]
+				l.Url = parts[1]
+			}
+		case albLogUserAgent:
+			l.UserAgent = filterDash(f)
+		case albLogSSLCipher:
+			l.SSLCipher = filterDash(f)
+		case albLogSSLProtocol:
+			l.SSLProtocol = filterDash(f)
+		case albLogTargetGroupArn:
+			l.TargetGroupArn = filterDash(f)
+		case albLogTraceId:
+			l.TraceId = filterDash(f)
+		case albLogDomainName:
+			l.DomainName = filterDash(f)
+		case albLogChosenCertArn:
+			l.ChosenCertArn = filterDash(f)
+		case albLogMatchedRulePriority:
+			l.MatchedRulePriority, e = awsInt(f)
+		case albLogRequestCreationTime:
+			if f != "-" {
+				l.RequestCreationTime, e = time.Parse(albTimeLayout, f)
+			}
+		case albLogActionsExecuted:
+			if f != "-" {
+				l.ActionsExecuted = strings.Split(f, ",")
+			}
+		case albLogRedirectUrl:
+			l.RedirectUrl = filterDash(f)
+		case albLogErrorReason:
+			l.ErrorReason = filterDash(f)
+		}
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
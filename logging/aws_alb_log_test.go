@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAWSALBLogHTTPS(t *testing.T) {
+	line := `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 "GET https://www.example.com:443/ HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012" 1 2018-07-02T22:22:48.364000Z "authenticate,forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`
+
+	l := &ApplicationLoadBalancerLog{}
+	if err := l.Load(line); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name     string
+		Expected interface{}
+		Value    interface{}
+	}{
+		{"Type", "https", l.Type},
+		{"Timestamp", "2018-07-02T22:23:00.186641Z", l.Timestamp.Format(albTimeLayout)},
+		{"ELB", "app/my-loadbalancer/50dc6c495c0c9188", l.Elb},
+		{"TargetAndPort", "10.0.0.1:80", l.TargetAndPort},
+		{"TargetProcessingTime", 0.048, l.TargetProcessingTime},
+		{"ElbStatusCode", 200, l.ElbStatusCode},
+		{"TargetStatusCode", 200, l.TargetStatusCode},
+		{"SentBytes", 57, l.SentBytes},
+		{"Method", "GET", l.Method},
+		{"URL", "https://www.example.com:443/", l.Url},
+		{"UserAgent", "curl/7.46.0", l.UserAgent},
+		{"SSLCipher", "ECDHE-RSA-AES128-GCM-SHA256", l.SSLCipher},
+		{"SSLProtocol", "TLSv1.2", l.SSLProtocol},
+		{"TargetGroupArn", "arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067", l.TargetGroupArn},
+		{"TraceId", "Root=1-58337281-1d84f3d73c47ec4e58577259", l.TraceId},
+		{"DomainName", "www.example.com", l.DomainName},
+		{"MatchedRulePriority", 1, l.MatchedRulePriority},
+		{"RequestCreationTime", "2018-07-02T22:22:48.364Z", l.RequestCreationTime.Format(albTimeLayout)},
+		{"ActionsExecuted", "authenticate,forward", strings.Join(l.ActionsExecuted, ",")},
+		{"RedirectUrl", "", l.RedirectUrl},
+		{"ErrorReason", "", l.ErrorReason},
+	}
+
+	for _, tst := range tests {
+		if tst.Expected != tst.Value {
+			t.Errorf("expected %s to be %#v, was %#v", tst.Name, tst.Expected, tst.Value)
+		}
+	}
+}
+
+func TestAWSALBLogRedirect(t *testing.T) {
+	line := `http 2018-11-30T22:22:48.364000Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 - -1 -1 -1 301 - 0 172 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0" - - - "Root=1-58337364-23a8c76965a2ef7629b185e3" "-" "-" 0 2018-11-30T22:22:48.364000Z "redirect" "https://www.example.com:443/" "-" "-" "-" "-" "-"`
+	l := &ApplicationLoadBalancerLog{}
+	if err := l.Load(line); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name     string
+		Expected interface{}
+		Value    interface{}
+	}{
+		{"Type", "http", l.Type},
+		{"TargetAndPort", "", l.TargetAndPort},
+		{"RequestProcessingTime", -1.0, l.RequestProcessingTime},
+		{"ElbStatusCode", 301, l.ElbStatusCode},
+		{"TargetStatusCode", 0, l.TargetStatusCode},
+		{"TargetGroupArn", "", l.TargetGroupArn},
+		{"ActionsExecuted", "redirect", strings.Join(l.ActionsExecuted, ",")},
+		{"RedirectUrl", "https://www.example.com:443/", l.RedirectUrl},
+	}
+
+	for _, tst := range tests {
+		if tst.Expected != tst.Value {
+			t.Errorf("expected %s to be %#v, was %#v", tst.Name, tst.Expected, tst.Value)
+		}
+	}
+}
+
+func TestAWSALBLogError(t *testing.T) {
+	line := `https 2018-11-30T22:22:48.364000Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 - 0.001 -1 -1 502 - 311 0 "GET https://www.example.com:443/ HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337364-23a8c76965a2ef7629b185e3" "www.example.com" "-" 0 2018-11-30T22:22:48.364000Z "forward" "-" "LambdaUnhandled" "-" "-" "-" "-"`
+	l := &ApplicationLoadBalancerLog{}
+	if err := l.Load(line); err != nil {
+		t.Fatal(err)
+	}
+	if l.ErrorReason != "LambdaUnhandled" {
+		t.Errorf("expected ErrorReason to be %#v, was %#v", "LambdaUnhandled", l.ErrorReason)
+	}
+
+	if err := (&ApplicationLoadBalancerLog{}).Load(`2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -`); err == nil {
+		t.Error("expected error loading classic elb line")
+	}
+}
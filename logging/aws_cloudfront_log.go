@@ -0,0 +1,143 @@
+package logging
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	cloudFrontLogDate = iota
+	cloudFrontLogTime
+	cloudFrontLogEdgeLocation
+	cloudFrontLogBytesSent
+	cloudFrontLogClientIP
+	cloudFrontLogMethod
+	cloudFrontLogHost
+	cloudFrontLogUriStem
+	cloudFrontLogStatus
+	cloudFrontLogReferer
+	cloudFrontLogUserAgent
+	cloudFrontLogUriQuery
+	cloudFrontLogCookie
+	cloudFrontLogEdgeResultType
+	cloudFrontLogEdgeRequestId
+	cloudFrontLogHostHeader
+	cloudFrontLogProtocol
+	cloudFrontLogBytesReceived
+	cloudFrontLogTimeTaken
+	cloudFrontLogForwardedFor
+	cloudFrontLogSSLProtocol
+	cloudFrontLogSSLCipher
+	cloudFrontLogEdgeResponseResultType
+	cloudFrontLogProtocolVersion
+)
+
+// CloudFrontLog is a line of a CloudFront standard (web distribution) access
+// log. Fields are tab separated, lines starting with # are headers.
+type CloudFrontLog struct {
+	Timestamp              time.Time `json:"timestamp,omitempty"`
+	EdgeLocation           string    `json:"edge_location,omitempty"`
+	BytesSent              int       `json:"bytes_sent,omitempty"`
+	ClientIP               string    `json:"client_ip,omitempty"`
+	Method                 string    `json:"method,omitempty"`
+	Host                   string    `json:"host,omitempty"`
+	UriStem                string    `json:"uri_stem,omitempty"`
+	Status                 int       `json:"status,omitempty"`
+	Referer                string    `json:"referer,omitempty"`
+	UserAgent              string    `json:"user_agent,omitempty"`
+	UriQuery               string    `json:"uri_query,omitempty"`
+	Cookie                 string    `json:"cookie,omitempty"`
+	EdgeResultType         string    `json:"edge_result_type,omitempty"`
+	EdgeRequestId          string    `json:"edge_request_id,omitempty"`
+	HostHeader             string    `json:"host_header,omitempty"`
+	Protocol               string    `json:"protocol,omitempty"`
+	BytesReceived          int       `json:"bytes_received,omitempty"`
+	TimeTaken              float64   `json:"time_taken,omitempty"`
+	ForwardedFor           string    `json:"forwarded_for,omitempty"`
+	SSLProtocol            string    `json:"ssl_protocol,omitempty"`
+	SSLCipher              string    `json:"ssl_cipher,omitempty"`
+	EdgeResponseResultType string    `json:"edge_response_result_type,omitempty"`
+	ProtocolVersion        string    `json:"protocol_version,omitempty"`
+	RAW                    string    `json:"raw,omitempty"`
+}
+
+func (l *CloudFrontLog) Load(raw string) error {
+	l.RAW = raw
+	if strings.HasPrefix(raw, "#") {
+		return fmt.Errorf("header line %q", raw)
+	}
+	fields := strings.Split(raw, "\t")
+	if len(fields) <= cloudFrontLogTimeTaken {
+		return fmt.Errorf("expected at least %d fields, got %d", cloudFrontLogTimeTaken+1, len(fields))
+	}
+	var e error
+	for i, f := range fields {
+		switch i {
+		case cloudFrontLogDate:
+			l.Timestamp, e = time.Parse("2006-01-02 15:04:05", f+" "+fields[cloudFrontLogTime])
+		case cloudFrontLogEdgeLocation:
+			l.EdgeLocation = f
+		case cloudFrontLogBytesSent:
+			l.BytesSent, e = awsInt(f)
+		case cloudFrontLogClientIP:
+			l.ClientIP = f
+		case cloudFrontLogMethod:
+			l.Method = f
+		case cloudFrontLogHost:
+			l.Host = f
+		case cloudFrontLogUriStem:
+			l.UriStem = f
+		case cloudFrontLogStatus:
+			l.Status, e = awsInt(f)
+		case cloudFrontLogReferer:
+			l.Referer = filterDash(f)
+		case cloudFrontLogUserAgent:
+			l.UserAgent, e = cloudFrontUnescape(f)
+		case cloudFrontLogUriQuery:
+			l.UriQuery = filterDash(f)
+		case cloudFrontLogCookie:
+			l.Cookie = filterDash(f)
+		case cloudFrontLogEdgeResultType:
+			l.EdgeResultType = f
+		case cloudFrontLogEdgeRequestId:
+			l.EdgeRequestId = f
+		case cloudFrontLogHostHeader:
+			l.HostHeader = f
+		case cloudFrontLogProtocol:
+			l.Protocol = f
+		case cloudFrontLogBytesReceived:
+			l.BytesReceived, e = awsInt(f)
+		case cloudFrontLogTimeTaken:
+			l.TimeTaken, e = awsFloat(f)
+		case cloudFrontLogForwardedFor:
+			l.ForwardedFor = filterDash(f)
+		case cloudFrontLogSSLProtocol:
+			l.SSLProtocol = filterDash(f)
+		case cloudFrontLogSSLCipher:
+			l.SSLCipher = filterDash(f)
+		case cloudFrontLogEdgeResponseResultType:
+			l.EdgeResponseResultType = f
+		case cloudFrontLogProtocolVersion:
+			l.ProtocolVersion = f
+		}
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// cloudFrontUnescape decodes the URL encoding CloudFront applies to user
+// agents (spaces are logged as %20, sometimes double encoded as %2520).
+func cloudFrontUnescape(f string) (string, error) {
+	s, e := url.PathUnescape(filterDash(f))
+	if e != nil {
+		return "", e
+	}
+	if strings.Contains(s, "%20") {
+		return url.PathUnescape(s)
+	}
+	return s, nil
+}
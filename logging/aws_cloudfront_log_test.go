@@ -0,0 +1,44 @@
+package logging
+
+import "testing"
+
+func TestAWSCloudFrontLog(t *testing.T) {
+	line := "2019-12-04\t21:02:31\tLAX1\t392\t192.0.2.100\tGET\td111111abcdef8.cloudfront.net\t/index.html\t200\t-\tMozilla/5.0%20(Windows%20NT%2010.0;%20Win64;%20x64)\t-\t-\tHit\tSOX4xwn4XV6Q4rgb7XiVGOHms_BGlTAC4KyHmureZmBNrjGdRLiNIQ==\td111111abcdef8.cloudfront.net\thttps\t23\t0.001\t-\tTLSv1.2\tECDHE-RSA-AES128-GCM-SHA256\tHit\tHTTP/2.0\t-\t-\t11040\t0.001\tHit\ttext/html\t78\t-\t-"
+
+	l := &CloudFrontLog{}
+	if err := l.Load(line); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name     string
+		Expected interface{}
+		Value    interface{}
+	}{
+		{"Timestamp", "2019-12-04T21:02:31Z", l.Timestamp.Format("2006-01-02T15:04:05Z07:00")},
+		{"EdgeLocation", "LAX1", l.EdgeLocation},
+		{"BytesSent", 392, l.BytesSent},
+		{"ClientIP", "192.0.2.100", l.ClientIP},
+		{"Method", "GET", l.Method},
+		{"Host", "d111111abcdef8.cloudfront.net", l.Host},
+		{"UriStem", "/index.html", l.UriStem},
+		{"Status", 200, l.Status},
+		{"Referer", "", l.Referer},
+		{"UserAgent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)", l.UserAgent},
+		{"EdgeResultType", "Hit", l.EdgeResultType},
+		{"Protocol", "https", l.Protocol},
+		{"TimeTaken", 0.001, l.TimeTaken},
+		{"SSLProtocol", "TLSv1.2", l.SSLProtocol},
+		{"ProtocolVersion", "HTTP/2.0", l.ProtocolVersion},
+	}
+
+	for _, tst := range tests {
+		if tst.Expected != tst.Value {
+			t.Errorf("expected %s to be %#v, was %#v", tst.Name, tst.Expected, tst.Value)
+		}
+	}
+
+	if err := (&CloudFrontLog{}).Load("#Version: 1.0"); err == nil {
+		t.Error("expected error loading header line")
+	}
+}
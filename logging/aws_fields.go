@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// awsFields splits space separated AWS access log lines. Fields can be
+// quoted with "" (with \" escaping quotes) or enclosed in [] like the
+// timestamps of S3 access logs.
+func awsFields(raw string) ([]string, error) {
+	out := []string{}
+	for i := 0; i < len(raw); {
+		switch raw[i] {
+		case ' ':
+			i++
+		case '"':
+			b := &strings.Builder{}
+			j := i + 1
+			for ; j < len(raw) && raw[j] != '"'; j++ {
+				if raw[j] == '\\' && j+1 < len(raw) {
+					j++
+				}
+				b.WriteByte(raw[j])
+			}
+			if j >= len(raw) {
+				return nil, fmt.Errorf("unterminated quote in %q", raw)
+			}
+			out = append(out, b.String())
+			i = j + 1
+		case '[':
+			j := strings.IndexByte(raw[i:], ']')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated bracket in %q", raw)
+			}
+			out = append(out, raw[i+1:i+j])
+			i += j + 1
+		default:
+			j := strings.IndexByte(raw[i:], ' ')
+			if j < 0 {
+				j = len(raw) - i
+			}
+			out = append(out, raw[i:i+j])
+			i += j
+		}
+	}
+	return out, nil
+}
+
+// awsInt parses integers with "-" meaning 0.
+func awsInt(f string) (int, error) {
+	if f == "-" || f == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(f)
+}
+
+// awsFloat parses floats with "-" meaning 0.
+func awsFloat(f string) (float64, error) {
+	if f == "-" || f == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(f, 64)
+}
@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	s3LogBucketOwner = iota
+	s3LogBucket
+	s3LogTimestamp
+	s3LogRemoteIP
+	s3LogRequester
+	s3LogRequestId
+	s3LogOperation
+	s3LogKey
+	s3LogRequest
+	s3LogStatus
+	s3LogErrorCode
+	s3LogBytesSent
+	s3LogObjectSize
+	s3LogTotalTime
+	s3LogTurnAroundTime
+	s3LogReferer
+	s3LogUserAgent
+	s3LogVersionId
+	s3LogHostId
+	s3LogSignatureVersion
+	s3LogCipherSuite
+	s3LogAuthenticationType
+	s3LogHostHeader
+	s3LogTLSVersion
+)
+
+// S3AccessLog is a line of an S3 server access log.
+type S3AccessLog struct {
+	BucketOwner        string    `json:"bucket_owner,omitempty"`
+	Bucket             string    `json:"bucket,omitempty"`
+	Timestamp          time.Time `json:"timestamp,omitempty"`
+	RemoteIP           string    `json:"remote_ip,omitempty"`
+	Requester          string    `json:"requester,omitempty"`
+	RequestId          string    `json:"request_id,omitempty"`
+	Operation          string    `json:"operation,omitempty"`
+	Key                string    `json:"key,omitempty"`
+	Method             string    `json:"method,omitempty"`
+	Url                string    `json:"url,omitempty"`
+	Status             int       `json:"status,omitempty"`
+	ErrorCode          string    `json:"error_code,omitempty"`
+	BytesSent          int       `json:"bytes_sent,omitempty"`
+	ObjectSize         int       `json:"object_size,omitempty"`
+	TotalTime          int       `json:"total_time,omitempty"` // milliseconds
+	TurnAroundTime     int       `json:"turn_around_time,omitempty"`
+	Referer            string    `json:"referer,omitempty"`
+	UserAgent          string    `json:"user_agent,omitempty"`
+	VersionId          string    `json:"version_id,omitempty"`
+	HostId             string    `json:"host_id,omitempty"`
+	SignatureVersion   string    `json:"signature_version,omitempty"`
+	CipherSuite        string    `json:"cipher_suite,omitempty"`
+	AuthenticationType string    `json:"authentication_type,omitempty"`
+	HostHeader         string    `json:"host_header,omitempty"`
+	TLSVersion         string    `json:"tls_version,omitempty"`
+	RAW                string    `json:"raw,omitempty"`
+}
+
+func (l *S3AccessLog) Load(raw string) error {
+	l.RAW = raw
+	fields, e := awsFields(raw)
+	if e != nil {
+		return e
+	}
+	if len(fields) <= s3LogUserAgent {
+		return fmt.Errorf("expected at least %d fields, got %d", s3LogUserAgent+1, len(fields))
+	}
+	for i, f := range fields {
+		switch i {
+		case s3LogBucketOwner:
+			l.BucketOwner = f
+		case s3LogBucket:
+			l.Bucket = f
+		case s3LogTimestamp:
+			l.Timestamp, e = time.Parse("02/Jan/2006:15:04:05 -0700", f)
+		case s3LogRemoteIP:
+			l.RemoteIP = f
+		case s3LogRequester:
+			l.Requester = filterDash(f)
+		case s3LogRequestId:
+			l.RequestId = f
+		case s3LogOperation:
+			l.Operation = f
+		case s3LogKey:
+			l.Key = filterDash(f)
+		case s3LogRequest:
+			parts := strings.Split(f, " ")
+			if len(parts) == 3 {
+				l.Method = parts[0]
+				l.Url = parts[1]
+			}
+		case s3LogStatus:
+			l.Status, e = awsInt(f)
+		case s3LogErrorCode:
+			l.ErrorCode = filterDash(f)
+		case s3LogBytesSent:
+			l.BytesSent, e = awsInt(f)
+		case s3LogObjectSize:
+			l.ObjectSize, e = awsInt(f)
+		case s3LogTotalTime:
+			l.TotalTime, e = awsInt(f)
+		case s3LogTurnAroundTime:
+			l.TurnAroundTime, e = awsInt(f)
+		case s3LogReferer:
+			l.Referer = filterDash(f)
+		case s3LogUserAgent:
+			l.UserAgent = filterDash(f)
+		case s3LogVersionId:
+			l.VersionId = filterDash(f)
+		case s3LogHostId:
+			l.HostId = filterDash(f)
+		case s3LogSignatureVersion:
+			l.SignatureVersion = filterDash(f)
+		case s3LogCipherSuite:
+			l.CipherSuite = filterDash(f)
+		case s3LogAuthenticationType:
+			l.AuthenticationType = filterDash(f)
+		case s3LogHostHeader:
+			l.HostHeader = filterDash(f)
+		case s3LogTLSVersion:
+			l.TLSVersion = filterDash(f)
+		}
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package logging
+
+import "testing"
+
+func TestAWSS3AccessLog(t *testing.T) {
+	line := `79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be awsexamplebucket1 [06/Feb/2019:00:00:38 +0000] 192.0.2.3 79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be 3E57427F3EXAMPLE REST.GET.VERSIONING photos/2019/08/puppy.jpg "GET /awsexamplebucket1?versioning HTTP/1.1" 200 - 113 - 7 - "-" "S3Console/0.4" - s9lzHYrFp76ZVxRcpX9+5cjAnEH2ROuNkd2BHfIa6UkFVdtjf5mKR3/eTPFvsiP/XV/VLi31234= SigV4 ECDHE-RSA-AES128-GCM-SHA256 AuthHeader awsexamplebucket1.s3.us-west-1.amazonaws.com TLSV1.1`
+
+	l := &S3AccessLog{}
+	if err := l.Load(line); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name     string
+		Expected interface{}
+		Value    interface{}
+	}{
+		{"Bucket", "awsexamplebucket1", l.Bucket},
+		{"Timestamp", "2019-02-06T00:00:38Z", l.Timestamp.UTC().Format("2006-01-02T15:04:05Z07:00")},
+		{"RemoteIP", "192.0.2.3", l.RemoteIP},
+		{"RequestId", "3E57427F3EXAMPLE", l.RequestId},
+		{"Operation", "REST.GET.VERSIONING", l.Operation},
+		{"Key", "photos/2019/08/puppy.jpg", l.Key},
+		{"Method", "GET", l.Method},
+		{"URL", "/awsexamplebucket1?versioning", l.Url},
+		{"Status", 200, l.Status},
+		{"ErrorCode", "", l.ErrorCode},
+		{"BytesSent", 113, l.BytesSent},
+		{"ObjectSize", 0, l.ObjectSize},
+		{"TotalTime", 7, l.TotalTime},
+		{"Referer", "", l.Referer},
+		{"UserAgent", "S3Console/0.4", l.UserAgent},
+		{"SignatureVersion", "SigV4", l.SignatureVersion},
+		{"HostHeader", "awsexamplebucket1.s3.us-west-1.amazonaws.com", l.HostHeader},
+		{"TLSVersion", "TLSV1.1", l.TLSVersion},
+	}
+
+	for _, tst := range tests {
+		if tst.Expected != tst.Value {
+			t.Errorf("expected %s to be %#v, was %#v", tst.Name, tst.Expected, tst.Value)
+		}
+	}
+}
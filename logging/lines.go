@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -28,7 +29,7 @@ func (line *SyslogLine) TagString(key string) string {
 	return fmt.Sprint(line.Tags()[key])
 }
 
-var validKeyRegexp = regexp.MustCompile("(?i)^[a-z]+$")
+var validKeyRegexp = regexp.MustCompile("(?i)^[a-z][a-z0-9_.\\-]*$")
 var callsRegexp = regexp.MustCompile("^([0-9.]+)\\/([0-9]+)$")
 
 func removeQuotes(raw string) string {
@@ -79,44 +80,188 @@ func FieldsWithErr(line string) ([]string, error) {
 	return out, nil
 }
 
+// parseTags extracts key value pairs from a line. When the line ends with a
+// JSON object its attributes are used, otherwise all logfmt style pairs are
+// collected. Values are typed with parseTagValue, values in the form
+// <time>/<calls> are split into <key>_time and <key>_calls.
 func parseTags(raw string) map[string]interface{} {
+	if t, ok := parseJSONTags(raw); ok {
+		return t
+	}
 	t := map[string]interface{}{}
-	for _, field := range Fields(raw) {
-		kv := strings.SplitN(field, "=", 2)
-		if len(kv) == 2 && validKeyRegexp.MatchString(kv[0]) {
-			currentKey := kv[0]
-			value := kv[1]
-			m := callsRegexp.FindStringSubmatch(value)
-			if len(m) == 3 {
-				totalTime, e := strconv.ParseFloat(m[1], 64)
+	for _, pair := range logfmtPairs(raw) {
+		if !validKeyRegexp.MatchString(pair.Key) {
+			continue
+		}
+		if pair.Quoted {
+			t[pair.Key] = pair.Value
+			continue
+		}
+		m := callsRegexp.FindStringSubmatch(pair.Value)
+		if len(m) == 3 {
+			totalTime, e := strconv.ParseFloat(m[1], 64)
+			if e == nil {
+				calls, e := strconv.ParseInt(m[2], 10, 64)
 				if e == nil {
-					calls, e := strconv.ParseInt(m[2], 10, 64)
-					if e == nil {
-						t[currentKey+"_time"] = totalTime
-						t[currentKey+"_calls"] = calls
-					}
+					t[pair.Key+"_time"] = totalTime
+					t[pair.Key+"_calls"] = calls
 				}
-			} else {
-				t[currentKey] = parseTagValue(value)
-				currentKey = ""
 			}
+		} else {
+			t[pair.Key] = parseTagValue(pair.Value)
 		}
 	}
 	return t
 }
 
+// parseJSONTags parses the JSON object at the end of raw, e.g. the message
+// of a syslog line. Nested objects are flattened with keys joined by "_".
+func parseJSONTags(raw string) (map[string]interface{}, bool) {
+	raw = strings.TrimSpace(raw)
+	if !strings.HasSuffix(raw, "}") {
+		return nil, false
+	}
+	for i := strings.Index(raw, "{"); i >= 0; {
+		dec := json.NewDecoder(strings.NewReader(raw[i:]))
+		dec.UseNumber()
+		var m map[string]interface{}
+		if e := dec.Decode(&m); e == nil && !dec.More() {
+			t := map[string]interface{}{}
+			flattenJSONTags(t, "", m)
+			return t, true
+		}
+		next := strings.Index(raw[i+1:], "{")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return nil, false
+}
+
+func flattenJSONTags(t map[string]interface{}, prefix string, m map[string]interface{}) {
+	for k, v := range m {
+		key := prefix + k
+		switch v := v.(type) {
+		case map[string]interface{}:
+			flattenJSONTags(t, key+"_", v)
+		case json.Number:
+			t[key] = parseTagValue(v.String())
+		case string:
+			if d, ok := parseJSONDuration(v); ok {
+				t[key] = d
+			} else {
+				t[key] = v
+			}
+		case nil:
+		default:
+			t[key] = v
+		}
+	}
+}
+
+var durationRegexp = regexp.MustCompile(`^[-+]?([0-9]*(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+$`)
+
+// parseJSONDuration parses JSON strings with a unit suffix like "12ms" as
+// durations. Strings without unit (e.g. "0") stay strings.
+func parseJSONDuration(s string) (time.Duration, bool) {
+	if !durationRegexp.MatchString(s) {
+		return 0, false
+	}
+	d, e := time.ParseDuration(s)
+	return d, e == nil
+}
+
+type logfmtPair struct {
+	Key    string
+	Value  string
+	Quoted bool
+}
+
+// logfmtPairs returns all key=value pairs of raw. Values can be quoted with
+// double quotes and contain spaces and escaped quotes. Words without "=" and
+// quoted strings not belonging to a key are skipped.
+func logfmtPairs(raw string) []logfmtPair {
+	pairs := []logfmtPair{}
+	for i := 0; i < len(raw); {
+		switch {
+		case raw[i] == ' ' || raw[i] == '\t':
+			i++
+			continue
+		case raw[i] == '"':
+			_, i = logfmtQuoted(raw, i)
+			continue
+		}
+		start := i
+		for i < len(raw) && raw[i] != '=' && raw[i] != ' ' && raw[i] != '\t' && raw[i] != '"' {
+			i++
+		}
+		if i >= len(raw) || raw[i] != '=' || i == start {
+			continue
+		}
+		pair := logfmtPair{Key: raw[start:i]}
+		i++
+		if i < len(raw) && raw[i] == '"' {
+			pair.Value, i = logfmtQuoted(raw, i)
+			pair.Quoted = true
+		} else {
+			valueStart := i
+			for i < len(raw) && raw[i] != ' ' && raw[i] != '\t' {
+				i++
+			}
+			pair.Value = raw[valueStart:i]
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
+
+// logfmtQuoted reads the quoted string starting at raw[start] and returns the
+// unescaped value and the index after the closing quote. An unterminated
+// string runs to the end of raw.
+func logfmtQuoted(raw string, start int) (string, int) {
+	buf := make([]byte, 0, 32)
+	i := start + 1
+	for ; i < len(raw); i++ {
+		switch c := raw[i]; c {
+		case '\\':
+			if i+1 < len(raw) {
+				i++
+				switch raw[i] {
+				case 'n':
+					buf = append(buf, '\n')
+				case 't':
+					buf = append(buf, '\t')
+				default:
+					buf = append(buf, raw[i])
+				}
+			}
+		case '"':
+			return string(buf), i + 1
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return string(buf), i
+}
+
 func (line *SyslogLine) Tags() (t map[string]interface{}) {
 	if !line.tagsParsed {
 		line.tags = parseTags(line.Raw)
+		line.tagsParsed = true
 	}
 	return line.tags
 }
 
+// parseTagValue converts raw into an int64, float64 or time.Duration when
+// possible and returns raw without surrounding quotes otherwise.
 func parseTagValue(raw string) interface{} {
 	if i, e := strconv.ParseInt(raw, 10, 64); e == nil {
 		return i
 	} else if f, e := strconv.ParseFloat(raw, 64); e == nil {
 		return f
+	} else if d, e := time.ParseDuration(raw); e == nil {
+		return d
 	}
 	return removeQuotes(raw)
 }
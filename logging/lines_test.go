@@ -3,6 +3,7 @@ package logging
 import (
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -77,12 +78,46 @@ func TestLineTags(t *testing.T) {
 
 	})
 
+	Convey("JSON tags", t, func() {
+		line := &SyslogLine{}
+		So(line.Parse(`2013-12-09T14:19:14.575268+01:00 some.host api[123]: {"status":200,"total":2.235,"duration":"12ms","user":{"id":"u1","admin":true},"msg":"done {ok}","ref":null,"zero":"0","code":"1h2","version":"1.5","name":"ms"}`), ShouldBeNil)
+		tags := line.Tags()
+		So(tags["status"], ShouldEqual, 200)
+		So(tags["total"], ShouldEqual, 2.235)
+		So(tags["duration"], ShouldEqual, 12*time.Millisecond)
+		So(tags["user_id"], ShouldEqual, "u1")
+		So(tags["user_admin"], ShouldEqual, true)
+		So(tags["msg"], ShouldEqual, "done {ok}")
+		So(tags["zero"], ShouldEqual, "0")
+		So(tags["code"], ShouldEqual, "1h2")
+		So(tags["version"], ShouldEqual, "1.5")
+		So(tags["name"], ShouldEqual, "ms")
+		_, ok := tags["ref"]
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("logfmt tags", t, func() {
+		tags := parseTags(`2013-12-09T14:19:14.575268+01:00 some.host api[123]: level=info msg="request \"done\" in time" request_id=abc-1 took=1.5s status=200 size="1024" db=0.5/3 "quoted=ignored" empty=`)
+		So(tags["level"], ShouldEqual, "info")
+		So(tags["msg"], ShouldEqual, `request "done" in time`)
+		So(tags["request_id"], ShouldEqual, "abc-1")
+		So(tags["took"], ShouldEqual, 1500*time.Millisecond)
+		So(tags["status"], ShouldEqual, 200)
+		So(tags["size"], ShouldEqual, "1024")
+		So(tags["db_time"], ShouldEqual, 0.5)
+		So(tags["db_calls"], ShouldEqual, 3)
+		So(tags["empty"], ShouldEqual, "")
+		_, ok := tags["quoted"]
+		So(ok, ShouldBeFalse)
+	})
+
 	Convey("parseTagValue", t, func() {
 		m := map[string]interface{}{
 			"200":   200,
 			"2.235": 2.235,
 			"test":  "test",
 		}
+		So(parseTagValue("250ms"), ShouldEqual, 250*time.Millisecond)
 		for from, to := range m {
 			So(parseTagValue(from), ShouldEqual, to)
 		}
@@ -0,0 +1,287 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// logSource opens log files on a host. Missing files are returned as empty
+// readers.
+type logSource interface {
+	Open(path string, compress bool) (io.ReadCloser, error)
+	Follow(path string, fromBegin bool) (io.ReadCloser, error)
+	Close() error
+}
+
+type localSource struct {
+	done chan struct{}
+	once sync.Once
+}
+
+func newLocalSource() *localSource {
+	return &localSource{done: make(chan struct{})}
+}
+
+func (s *localSource) Open(path string, compress bool) (io.ReadCloser, error) {
+	f, e := os.Open(path)
+	if os.IsNotExist(e) {
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	}
+	return f, e
+}
+
+func (s *localSource) Follow(path string, fromBegin bool) (io.ReadCloser, error) {
+	r := &followReader{path: path, done: s.done, interval: 100 * time.Millisecond}
+	if e := r.reopen(); e != nil {
+		return nil, e
+	}
+	if r.file != nil && !fromBegin {
+		if _, e := r.file.Seek(0, io.SeekEnd); e != nil {
+			r.Close()
+			return nil, e
+		}
+	}
+	return r, nil
+}
+
+func (s *localSource) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return nil
+}
+
+// followReader works like tail -F: at the end of the file it waits for new
+// data and reopens the file when it was rotated or truncated.
+type followReader struct {
+	path     string
+	file     *os.File
+	done     chan struct{}
+	interval time.Duration
+}
+
+func (r *followReader) reopen() error {
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	f, e := os.Open(r.path)
+	if os.IsNotExist(e) {
+		return nil
+	}
+	r.file = f
+	return e
+}
+
+func (r *followReader) rotated() bool {
+	if r.file == nil {
+		return true
+	}
+	current, e := os.Stat(r.path)
+	if e != nil {
+		return false
+	}
+	opened, e := r.file.Stat()
+	if e != nil || !os.SameFile(current, opened) {
+		return true
+	}
+	pos, e := r.file.Seek(0, io.SeekCurrent)
+	return e == nil && current.Size() < pos
+}
+
+func (r *followReader) Read(b []byte) (int, error) {
+	for {
+		select {
+		case <-r.done:
+			r.Close()
+			return 0, io.EOF
+		default:
+		}
+		if r.file != nil {
+			n, e := r.file.Read(b)
+			if n > 0 || (e != nil && e != io.EOF) {
+				return n, e
+			}
+		}
+		if r.rotated() {
+			if e := r.reopen(); e != nil {
+				return 0, e
+			} else if r.file != nil {
+				continue
+			}
+		}
+		select {
+		case <-r.done:
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+func (r *followReader) Close() error {
+	if r.file != nil {
+		f := r.file
+		r.file = nil
+		return f.Close()
+	}
+	return nil
+}
+
+type sshSource struct {
+	client    *ssh.Client
+	agentConn net.Conn
+	once      sync.Once
+}
+
+// dialSSH connects to addr. Without auth methods it authenticates with the
+// keys of the ssh agent listening on SSH_AUTH_SOCK.
+func dialSSH(addr, user string, hostKey ssh.HostKeyCallback, auth []ssh.AuthMethod) (*sshSource, error) {
+	if hostKey == nil {
+		var e error
+		if hostKey, e = defaultHostKeyCallback(); e != nil {
+			return nil, e
+		}
+	}
+	src := &sshSource{}
+	if len(auth) == 0 {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if sock == "" {
+			return nil, fmt.Errorf("SSH_AUTH_SOCK not set, a running ssh agent is required")
+		}
+		var e error
+		if src.agentConn, e = net.Dial("unix", sock); e != nil {
+			return nil, fmt.Errorf("connecting to ssh agent: %s", e)
+		}
+		auth = []ssh.AuthMethod{ssh.PublicKeysCallback(agent.NewClient(src.agentConn).Signers)}
+	}
+	config := &ssh.ClientConfig{
+		User:            user,
+		Auth:            auth,
+		HostKeyCallback: hostKey,
+		Timeout:         10 * time.Second,
+	}
+	dbg.Printf("dialing %s@%s", user, addr)
+	client, e := ssh.Dial("tcp", addr, config)
+	if e != nil {
+		if src.agentConn != nil {
+			src.agentConn.Close()
+		}
+		return nil, e
+	}
+	src.client = client
+	return src, nil
+}
+
+func defaultHostKeyCallback() (ssh.HostKeyCallback, error) {
+	home, e := os.UserHomeDir()
+	if e != nil {
+		return nil, e
+	}
+	return knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func (s *sshSource) Open(path string, compress bool) (io.ReadCloser, error) {
+	cmd := "cat "
+	if compress && !strings.HasSuffix(path, ".gz") {
+		// decoded again by decodeLog
+		cmd = "gzip -c "
+	}
+	return s.run("if [ -e " + shellQuote(path) + " ]; then " + cmd + shellQuote(path) + "; fi")
+}
+
+func (s *sshSource) Follow(path string, fromBegin bool) (io.ReadCloser, error) {
+	n := "0"
+	if fromBegin {
+		n = "+0"
+	}
+	return s.run("tail -n " + n + " -F " + shellQuote(path))
+}
+
+func (s *sshSource) run(cmd string) (io.ReadCloser, error) {
+	session, e := s.client.NewSession()
+	if e != nil {
+		return nil, e
+	}
+	stdout, e := session.StdoutPipe()
+	if e != nil {
+		session.Close()
+		return nil, e
+	}
+	dbg.Printf("starting command %q", cmd)
+	if e := session.Start(cmd); e != nil {
+		session.Close()
+		return nil, e
+	}
+	return &sessionReader{Reader: stdout, session: session, cmd: cmd}, nil
+}
+
+func (s *sshSource) Close() error {
+	var e error
+	s.once.Do(func() {
+		e = s.client.Close()
+		if s.agentConn != nil {
+			s.agentConn.Close()
+		}
+	})
+	return e
+}
+
+// sessionReader reads the output of a remote command and reports a failing
+// command once its output was read.
+type sessionReader struct {
+	io.Reader
+	session *ssh.Session
+	cmd     string
+	waited  bool
+}
+
+func (r *sessionReader) Read(b []byte) (int, error) {
+	n, e := r.Reader.Read(b)
+	if e == io.EOF && !r.waited {
+		r.waited = true
+		if err := r.session.Wait(); err != nil {
+			return n, fmt.Errorf("running %q: %s", r.cmd, err)
+		}
+	}
+	return n, e
+}
+
+func (r *sessionReader) Close() error {
+	r.session.Signal(ssh.SIGTERM)
+	return r.session.Close()
+}
+
+// FollowFile returns a reader following the file at path like tail -F until
+// it is closed. Without fromBegin reading starts at the end of the file. Close
+// can be called while another goroutine is reading.
+func FollowFile(path string, fromBegin bool) (io.ReadCloser, error) {
+	s := newLocalSource()
+	r, e := s.Follow(path, fromBegin)
+	if e != nil {
+		return nil, e
+	}
+	return &followFile{ReadCloser: r, src: s}, nil
+}
+
+type followFile struct {
+	io.ReadCloser
+	src *localSource
+}
+
+// Close makes a pending Read return io.EOF, the file is closed by the
+// reading goroutine.
+func (f *followFile) Close() error {
+	return f.src.Close()
+}
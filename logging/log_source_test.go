@@ -0,0 +1,367 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newTestKey(t *testing.T) (*ecdsa.PrivateKey, ssh.Signer) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, signer
+}
+
+// testSSHServer accepts the authorized key and runs the commands of exec
+// requests locally with sh.
+type testSSHServer struct {
+	Addr    string
+	HostKey ssh.PublicKey
+
+	mutex    sync.Mutex
+	commands []string
+}
+
+func startSSHServer(t *testing.T, authorized ssh.PublicKey) *testSSHServer {
+	_, hostKey := newTestKey(t)
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(c ssh.ConnMetadata, k ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(k.Marshal(), authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key for %s", c.User())
+		},
+	}
+	config.AddHostKey(hostKey)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	s := &testSSHServer{Addr: l.Addr().String(), HostKey: hostKey.PublicKey()}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, config)
+		}
+	}()
+	return s
+}
+
+func (s *testSSHServer) Commands() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.commands...)
+}
+
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		ch, requests, err := nc.Accept()
+		if err != nil {
+			continue
+		}
+		go s.session(ch, requests)
+	}
+}
+
+func (s *testSSHServer) session(ch ssh.Channel, requests <-chan *ssh.Request) {
+	var cmd *exec.Cmd
+	kill := func() {
+		if cmd != nil && cmd.Process != nil {
+			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+	}
+	defer kill()
+	for req := range requests {
+		switch req.Type {
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil || cmd != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			s.mutex.Lock()
+			s.commands = append(s.commands, payload.Command)
+			s.mutex.Unlock()
+			cmd = exec.Command("sh", "-c", payload.Command)
+			cmd.Stdout, cmd.Stderr = ch, ch.Stderr()
+			cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+			if err := cmd.Start(); err != nil {
+				req.Reply(false, nil)
+				ch.Close()
+				continue
+			}
+			req.Reply(true, nil)
+			go func(cmd *exec.Cmd) {
+				status := 0
+				if err := cmd.Wait(); err != nil {
+					status = 1
+				}
+				ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+				ch.Close()
+			}(cmd)
+		case "signal":
+			kill()
+		default:
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+// startAgent serves an ssh agent holding key on a unix socket and points
+// SSH_AUTH_SOCK to it.
+func startAgent(t *testing.T, key *ecdsa.PrivateKey) {
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		l.Close()
+		os.RemoveAll(dir)
+	})
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", sock)
+}
+
+// writeKnownHosts writes a known_hosts file for addr and returns a callback
+// verifying host keys with it.
+func writeKnownHosts(t *testing.T, path, addr string, key ssh.PublicKey) ssh.HostKeyCallback {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(knownhosts.Line([]string{addr}, key)+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cb, err := knownhosts.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cb
+}
+
+func readAll(t *testing.T, rl *RemoteLog) (string, error) {
+	r, err := rl.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	return string(b), err
+}
+
+func TestSSHSourceOpen(t *testing.T) {
+	clientKey, signer := newTestKey(t)
+	srv := startSSHServer(t, signer.PublicKey())
+	root := t.TempDir()
+	hour := time.Date(2014, 1, 2, 3, 0, 0, 0, time.UTC)
+	writeHourlyLog(t, root, hour, false, "GET /a", "POST /b")
+	writeHourlyLog(t, root, hour, true, "GET /c")
+	hostKey := writeKnownHosts(t, filepath.Join(t.TempDir(), "known_hosts"), srv.Addr, srv.HostKey)
+
+	startAgent(t, clientKey)
+	tests := []struct {
+		Description string
+		Log         *RemoteLog
+		Expected    string
+	}{
+		{"agent", &RemoteLog{}, "GET /a\nPOST /b\nGET /c\n"},
+		{"key", &RemoteLog{Auth: []ssh.AuthMethod{ssh.PublicKeys(signer)}}, "GET /a\nPOST /b\nGET /c\n"},
+		{"pattern", &RemoteLog{Pattern: "^GET"}, "GET /a\nGET /c\n"},
+		{"compress", &RemoteLog{Compress: true}, "GET /a\nPOST /b\nGET /c\n"},
+		{"missing", &RemoteLog{Time: hour.Add(time.Hour)}, ""},
+	}
+	for _, tst := range tests {
+		rl := tst.Log
+		rl.Host, rl.User, rl.CustomLogRoot, rl.HostKeyCallback = srv.Addr, "deploy", root, hostKey
+		if rl.Time.IsZero() {
+			rl.Time = hour
+		}
+		v, err := readAll(t, rl)
+		if err != nil {
+			t.Errorf("%s: %s", tst.Description, err)
+		}
+		if v != tst.Expected {
+			t.Errorf("%s: expected lines to be %q, was %q", tst.Description, tst.Expected, v)
+		}
+	}
+	cmds := srv.Commands()
+	if len(cmds) == 0 || !strings.Contains(cmds[len(cmds)-1], "cat ") {
+		t.Errorf("expected files to be read with cat, got %q", cmds)
+	}
+}
+
+func TestSSHSourceAuth(t *testing.T) {
+	_, signer := newTestKey(t)
+	srv := startSSHServer(t, signer.PublicKey())
+	hostKey := writeKnownHosts(t, filepath.Join(t.TempDir(), "known_hosts"), srv.Addr, srv.HostKey)
+	otherKey, otherSigner := newTestKey(t)
+
+	startAgent(t, otherKey)
+	tests := []struct {
+		Description string
+		Log         *RemoteLog
+		Expected    string
+	}{
+		{"agent without key", &RemoteLog{}, "unable to authenticate"},
+		{"unknown key", &RemoteLog{Auth: []ssh.AuthMethod{ssh.PublicKeys(otherSigner)}}, "unable to authenticate"},
+	}
+	for _, tst := range tests {
+		rl := tst.Log
+		rl.Host, rl.CustomLogRoot, rl.HostKeyCallback = srv.Addr, t.TempDir(), hostKey
+		if _, err := readAll(t, rl); err == nil || !strings.Contains(err.Error(), tst.Expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tst.Description, tst.Expected, err)
+		}
+	}
+
+	t.Setenv("SSH_AUTH_SOCK", "")
+	if _, err := readAll(t, &RemoteLog{Host: srv.Addr, HostKeyCallback: hostKey}); err == nil || !strings.Contains(err.Error(), "SSH_AUTH_SOCK not set") {
+		t.Errorf("expected error without agent, got %v", err)
+	}
+}
+
+func TestSSHSourceKnownHosts(t *testing.T) {
+	_, signer := newTestKey(t)
+	srv := startSSHServer(t, signer.PublicKey())
+	_, otherHostKey := newTestKey(t)
+	auth := []ssh.AuthMethod{ssh.PublicKeys(signer)}
+
+	// the default callback checks ~/.ssh/known_hosts
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeKnownHosts(t, filepath.Join(home, ".ssh", "known_hosts"), srv.Addr, otherHostKey.PublicKey())
+	_, err := readAll(t, &RemoteLog{Host: srv.Addr, Auth: auth, CustomLogRoot: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "key mismatch") {
+		t.Errorf("expected key mismatch error, got %v", err)
+	}
+
+	writeKnownHosts(t, filepath.Join(home, ".ssh", "known_hosts"), "other.host:22", srv.HostKey)
+	_, err = readAll(t, &RemoteLog{Host: srv.Addr, Auth: auth, CustomLogRoot: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "key is unknown") {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+
+	writeKnownHosts(t, filepath.Join(home, ".ssh", "known_hosts"), srv.Addr, srv.HostKey)
+	if _, err = readAll(t, &RemoteLog{Host: srv.Addr, Auth: auth, CustomLogRoot: t.TempDir()}); err != nil {
+		t.Errorf("expected known host to be accepted, got %v", err)
+	}
+}
+
+func TestSSHSourceFollow(t *testing.T) {
+	_, signer := newTestKey(t)
+	srv := startSSHServer(t, signer.PublicKey())
+	hostKey := writeKnownHosts(t, filepath.Join(t.TempDir(), "known_hosts"), srv.Addr, srv.HostKey)
+	root := t.TempDir()
+	rl := &RemoteLog{
+		Host:            srv.Addr,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKey,
+		CustomLogRoot:   root,
+		Tail:            true,
+		FromBegin:       true,
+	}
+	if err := ioutil.WriteFile(rl.Current(), []byte("old line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := rl.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	expectLine := func(expected string) {
+		select {
+		case l := <-lines:
+			if l != expected {
+				t.Errorf("expected line to be %q, was %q", expected, l)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for %q", expected)
+		}
+	}
+
+	// tail is running once the existing line was read
+	expectLine("old line")
+	f, err := os.OpenFile(rl.Current(), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("new line\n")
+	f.Close()
+	expectLine("new line")
+	if cmds := srv.Commands(); len(cmds) != 1 || !strings.HasPrefix(cmds[0], "tail -n +0 -F ") {
+		t.Errorf("expected tail command, got %q", cmds)
+	}
+
+	r.Close()
+	select {
+	case _, ok := <-lines:
+		if ok {
+			t.Error("expected no more lines after close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for reader to finish")
+	}
+}
@@ -0,0 +1,323 @@
+package logging
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dynport/dgtk/txt"
+)
+
+// Record is the common structured form of a parsed log line.
+type Record struct {
+	Format string                 `json:"format,omitempty"`
+	Time   time.Time              `json:"time,omitempty"`
+	Host   string                 `json:"host,omitempty"`
+	Fields map[string]interface{} `json:"fields,omitempty"`
+	Raw    string                 `json:"raw,omitempty"`
+}
+
+// Parser turns a raw log line into a Record. Parse returns an error when the
+// line is not in the format of the parser.
+type Parser interface {
+	Name() string
+	Parse(raw string) (*Record, error)
+}
+
+// ParserFunc adapts a function to the Parser interface.
+func ParserFunc(name string, f func(raw string) (*Record, error)) Parser {
+	return &parserFunc{name: name, f: f}
+}
+
+type parserFunc struct {
+	name string
+	f    func(raw string) (*Record, error)
+}
+
+func (p *parserFunc) Name() string {
+	return p.name
+}
+
+func (p *parserFunc) Parse(raw string) (r *Record, e error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			r, e = nil, fmt.Errorf("%s: unable to parse %q: %v", p.name, raw, rec)
+		}
+	}()
+	r, e = p.f(raw)
+	if e != nil {
+		return nil, e
+	}
+	if r.Format == "" {
+		r.Format = p.name
+	}
+	if r.Raw == "" {
+		r.Raw = raw
+	}
+	return r, nil
+}
+
+// NewRegistry creates a registry trying the parsers in the given order.
+func NewRegistry(parsers ...Parser) *Registry {
+	r := &Registry{}
+	for _, p := range parsers {
+		r.Register(p)
+	}
+	return r
+}
+
+// Registry holds parsers by name. More specific parsers must be registered
+// before generic ones (like syslog) as the first matching parser wins.
+type Registry struct {
+	mutex   sync.RWMutex
+	parsers []Parser
+}
+
+// DefaultRegistry contains parsers for all line types of this package.
+var DefaultRegistry = NewRegistry(
+	NginxParser,
+	HAProxyParser,
+	UnicornParser,
+	MetrixParser,
+	ALBParser,
+	ELBParser,
+	CloudFrontParser,
+	S3Parser,
+	RailsParser,
+	SyslogParser,
+)
+
+// Register adds p to the registry, replacing a parser with the same name.
+func (r *Registry) Register(p Parser) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i, existing := range r.parsers {
+		if existing.Name() == p.Name() {
+			r.parsers[i] = p
+			return
+		}
+	}
+	r.parsers = append(r.parsers, p)
+}
+
+// Parsers returns all registered parsers in order.
+func (r *Registry) Parsers() []Parser {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return append([]Parser{}, r.parsers...)
+}
+
+// Lookup returns the parser with the given name or nil.
+func (r *Registry) Lookup(name string) Parser {
+	for _, p := range r.Parsers() {
+		if p.Name() == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Detect returns the parser able to parse most of the samples. On a tie the
+// parser registered first wins.
+func (r *Registry) Detect(samples ...string) (Parser, error) {
+	var best Parser
+	bestCnt := 0
+	for _, p := range r.Parsers() {
+		cnt := 0
+		for _, s := range samples {
+			if _, e := p.Parse(s); e == nil {
+				cnt++
+			}
+		}
+		if cnt > bestCnt {
+			best, bestCnt = p, cnt
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no parser found for %d samples", len(samples))
+	}
+	return best, nil
+}
+
+// Parse parses raw with the first parser accepting it. Use it for streams
+// with mixed formats.
+func (r *Registry) Parse(raw string) (*Record, error) {
+	for _, p := range r.Parsers() {
+		if rec, e := p.Parse(raw); e == nil {
+			return rec, nil
+		}
+	}
+	return nil, fmt.Errorf("no parser found for line %q", raw)
+}
+
+var (
+	SyslogParser = ParserFunc("syslog", func(raw string) (*Record, error) {
+		l := &SyslogLine{}
+		if e := parseSyslog(l, raw); e != nil {
+			return nil, e
+		}
+		f := structFields(l)
+		for k, v := range l.Tags() {
+			if _, ok := f[k]; !ok {
+				f[k] = v
+			}
+		}
+		return &Record{Time: l.Time, Host: l.Host, Fields: f}, nil
+	})
+
+	NginxParser = ParserFunc("nginx", func(raw string) (*Record, error) {
+		l := &NginxLine{}
+		if e := l.Parse(raw); e != nil {
+			return nil, e
+		}
+		return &Record{Time: l.Time, Host: l.Host, Fields: structFields(l)}, nil
+	})
+
+	HAProxyParser = ParserFunc("haproxy", func(raw string) (*Record, error) {
+		l := &HAProxyLine{}
+		if e := l.Parse(raw); e != nil {
+			return nil, e
+		}
+		return &Record{Time: l.Time, Host: l.Host, Fields: structFields(l)}, nil
+	})
+
+	UnicornParser = ParserFunc("unicorn", func(raw string) (*Record, error) {
+		l := &UnicornLine{}
+		if e := l.Parse(raw); e != nil {
+			return nil, e
+		}
+		return &Record{Time: l.Time, Host: l.Host, Fields: structFields(l)}, nil
+	})
+
+	MetrixParser = ParserFunc("metrix", func(raw string) (*Record, error) {
+		s := &SyslogLine{}
+		if e := parseSyslog(s, raw); e != nil {
+			return nil, e
+		}
+		if s.Tag != "metrix" {
+			return nil, fmt.Errorf("tag %q not supported", s.Tag)
+		}
+		l := &MetrixLine{}
+		if e := l.Parse(raw); e != nil {
+			return nil, e
+		}
+		f := structFields(l)
+		delete(f, "tags")
+		for k, v := range l.Tags {
+			f[k] = v
+		}
+		return &Record{Time: l.Timestamp, Host: l.Host, Fields: f}, nil
+	})
+
+	RailsParser = ParserFunc("rails", func(raw string) (*Record, error) {
+		l := &RailsLine{}
+		if e := l.Parse(raw); e != nil {
+			return nil, e
+		}
+		rec := &Record{Fields: structFields(l)}
+		s := &SyslogLine{}
+		if parseSyslog(s, raw) == nil {
+			rec.Time, rec.Host = s.Time, s.Host
+		}
+		return rec, nil
+	})
+
+	ELBParser = ParserFunc("elb", func(raw string) (*Record, error) {
+		l := &ElasticLoadBalancerLog{}
+		if e := l.Load(raw); e != nil {
+			return nil, e
+		}
+		if l.Elb == "" || l.ElbStatusCode == 0 {
+			return nil, fmt.Errorf("not an elb log line")
+		}
+		return &Record{Time: l.Timestamp, Host: l.Elb, Fields: structFields(l)}, nil
+	})
+
+	ALBParser = ParserFunc("alb", func(raw string) (*Record, error) {
+		l := &ApplicationLoadBalancerLog{}
+		if e := l.Load(raw); e != nil {
+			return nil, e
+		}
+		return &Record{Time: l.Timestamp, Host: l.Elb, Fields: structFields(l)}, nil
+	})
+
+	CloudFrontParser = ParserFunc("cloudfront", func(raw string) (*Record, error) {
+		l := &CloudFrontLog{}
+		if e := l.Load(raw); e != nil {
+			return nil, e
+		}
+		return &Record{Time: l.Timestamp, Host: l.Host, Fields: structFields(l)}, nil
+	})
+
+	S3Parser = ParserFunc("s3", func(raw string) (*Record, error) {
+		l := &S3AccessLog{}
+		if e := l.Load(raw); e != nil {
+			return nil, e
+		}
+		return &Record{Time: l.Timestamp, Host: l.Bucket, Fields: structFields(l)}, nil
+	})
+)
+
+// parseSyslog is SyslogLine.Parse but fails for lines without a header.
+func parseSyslog(l *SyslogLine, raw string) error {
+	if e := l.Parse(raw); e != nil {
+		return e
+	}
+	if l.Time.IsZero() {
+		return fmt.Errorf("no syslog header found in %q", raw)
+	}
+	return nil
+}
+
+// recordKeys are stored in the Record itself and not in its fields.
+var recordKeys = map[string]bool{"raw": true, "time": true, "timestamp": true, "host": true}
+
+// structFields returns all non zero exported fields of v (including fields of
+// embedded structs) keyed by their json name or the underscored field name.
+func structFields(v interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	collectFields(reflect.ValueOf(v), out)
+	return out
+}
+
+func collectFields(v reflect.Value, out map[string]interface{}) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		fv := v.Field(i)
+		if f.Anonymous {
+			collectFields(fv, out)
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = txt.Underscore(f.Name)
+		}
+		if recordKeys[name] || isZero(fv) {
+			continue
+		}
+		out[name] = fv.Interface()
+	}
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
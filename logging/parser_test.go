@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"testing"
+)
+
+const (
+	RAILS_LINE  = `2014-11-07T14:16:59.014363+00:00 i-fa2e28b9 rails.info[11]: [3742facc-a8cd-4cd0-aa63-b4e69b58e20e] Completed 200 OK in 19ms (Views: 1.0ms | ActiveRecord: 5.1ms)`
+	METRIX_LINE = `2014-06-03T08:03:01.357642+00:00 krusty-he-284430 metrix.notice[14506]: processes.Vsize 1401782581 10 pid=320 name=md1_raid1`
+	ELB_LINE    = `2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -`
+	SYSLOG_LINE = `2014-06-03T08:03:01.357642+00:00 some-host cron[12]: running job=backup took=1.5`
+)
+
+func TestRegistryParse(t *testing.T) {
+	tests := []struct {
+		Line   string
+		Format string
+		Host   string
+		Key    string
+		Value  interface{}
+	}{
+		{SSL_LINE, "nginx", "cnc-618c0f60", "status", "301"},
+		{HAPROXY_LINE, "haproxy", "192.168.0.6", "backend", "ff"},
+		{UNICORN_LINE, "unicorn", "eae43d53eaf7", "uuid", "96baf015-c074-4d92-9b5a-d7b9a20e55ca"},
+		{METRIX_LINE, "metrix", "krusty-he-284430", "name", "md1_raid1"},
+		{ELB_LINE, "elb", "my-loadbalancer", "elb_status_code", 200},
+		{RAILS_LINE, "rails", "i-fa2e28b9", "total_time", 0.019},
+		{SYSLOG_LINE, "syslog", "some-host", "took", 1.5},
+	}
+	for _, tst := range tests {
+		r, err := DefaultRegistry.Parse(tst.Line)
+		if err != nil {
+			t.Errorf("parsing %q: %s", tst.Line, err)
+			continue
+		}
+		if r.Format != tst.Format || r.Host != tst.Host || r.Time.IsZero() || r.Raw != tst.Line {
+			t.Errorf("expected format=%q host=%q with time and raw line, got %+v", tst.Format, tst.Host, r)
+		}
+		if v := r.Fields[tst.Key]; v != tst.Value {
+			t.Errorf("%s: expected field %q to be %#v, was %#v", tst.Format, tst.Key, tst.Value, v)
+		}
+	}
+
+	if _, err := DefaultRegistry.Parse("not a log line"); err == nil {
+		t.Error("expected error parsing invalid line")
+	}
+}
+
+func TestRegistryDetect(t *testing.T) {
+	// syslog parses all lines as well but nginx is more specific
+	p, err := DefaultRegistry.Detect(SSL_LINE, LINE_WITH_KEY_VALUE_PAIRS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "nginx" {
+		t.Errorf("expected nginx to be detected, got %q", p.Name())
+	}
+
+	// only syslog can handle mixed lines
+	p, err = DefaultRegistry.Detect(SSL_LINE, LINE_WITH_KEY_VALUE_PAIRS, SYSLOG_LINE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "syslog" {
+		t.Errorf("expected syslog to be detected, got %q", p.Name())
+	}
+
+	p, err = DefaultRegistry.Detect(ELB_LINE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "elb" {
+		t.Errorf("expected elb to be detected, got %q", p.Name())
+	}
+
+	if _, err := DefaultRegistry.Detect("garbage"); err == nil {
+		t.Error("expected error detecting garbage")
+	}
+
+	r := NewRegistry(SyslogParser)
+	r.Register(ParserFunc("syslog", func(raw string) (*Record, error) {
+		return &Record{Fields: map[string]interface{}{"custom": true}}, nil
+	}))
+	if len(r.Parsers()) != 1 || r.Lookup("syslog") == nil {
+		t.Errorf("expected parser to be replaced, got %d parsers", len(r.Parsers()))
+	}
+}
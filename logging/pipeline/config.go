@@ -0,0 +1,233 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/dynport/dgtk/logging"
+)
+
+// Config describes a pipeline in JSON, e.g.
+//
+//	{
+//	  "source": {"type": "file", "path": "/var/log/nginx.log", "follow": true},
+//	  "parser": "nginx",
+//	  "filters": [{"field": "status", "reject": "^2"}],
+//	  "enrich": {"env": "production"},
+//	  "sinks": [{"type": "elasticsearch", "address": "http://127.0.0.1:9200", "index": "logs-", "index_time_layout": "2006.01.02"}]
+//	}
+type Config struct {
+	Source  *SourceConfig          `json:"source"`
+	Parser  string                 `json:"parser,omitempty"` // name in logging.DefaultRegistry, all are tried when empty
+	Filters []*FilterConfig        `json:"filters,omitempty"`
+	Enrich  map[string]interface{} `json:"enrich,omitempty"`
+	Sinks   []*SinkConfig          `json:"sinks"`
+}
+
+type SourceConfig struct {
+	Type      string `json:"type"` // file, stdin or remote
+	Path      string `json:"path,omitempty"`
+	Follow    bool   `json:"follow,omitempty"`
+	FromBegin bool   `json:"from_begin,omitempty"`
+
+	// used with type remote
+	Hosts   []string  `json:"hosts,omitempty"`
+	User    string    `json:"user,omitempty"`
+	LogRoot string    `json:"log_root,omitempty"`
+	Pattern string    `json:"pattern,omitempty"`
+	From    time.Time `json:"from,omitempty"`
+	Until   time.Time `json:"until,omitempty"`
+}
+
+// FilterConfig is either a Match or Reject for Field or an Include or
+// Exclude of fields.
+type FilterConfig struct {
+	Field   string   `json:"field,omitempty"`
+	Match   string   `json:"match,omitempty"`
+	Reject  string   `json:"reject,omitempty"`
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+type SinkConfig struct {
+	Type    string `json:"type"` // elasticsearch, opentsdb or json
+	Address string `json:"address,omitempty"`
+
+	// used with type elasticsearch
+	Index           string `json:"index,omitempty"`
+	IndexTimeLayout string `json:"index_time_layout,omitempty"`
+	DocType         string `json:"doc_type,omitempty"`
+
+	// used with type opentsdb
+	Metric string   `json:"metric,omitempty"`
+	Values []string `json:"values,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+func LoadConfig(path string) (*Config, error) {
+	f, e := os.Open(path)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+	c := &Config{}
+	if e := json.NewDecoder(f).Decode(c); e != nil {
+		return nil, fmt.Errorf("decoding %s: %s", path, e)
+	}
+	return c, nil
+}
+
+// Validate checks the config without connecting to any sink.
+func (c *Config) Validate() error {
+	_, e := c.build()
+	return e
+}
+
+// Pipeline builds the pipeline described by the config. Sinks are only
+// connected once all other parts are valid.
+func (c *Config) Pipeline() (*Pipeline, error) {
+	p, e := c.build()
+	if e != nil {
+		return nil, e
+	}
+	for _, s := range c.Sinks {
+		sink, e := s.sink()
+		if e != nil {
+			p.closeSinks()
+			return nil, e
+		}
+		p.Sinks = append(p.Sinks, sink)
+	}
+	return p, nil
+}
+
+// build returns the pipeline without sinks.
+func (c *Config) build() (*Pipeline, error) {
+	if c.Source == nil {
+		return nil, fmt.Errorf("source must be set")
+	}
+	src, e := c.Source.source()
+	if e != nil {
+		return nil, e
+	}
+	p := &Pipeline{Source: src}
+	if c.Parser != "" {
+		if p.Parser = logging.DefaultRegistry.Lookup(c.Parser); p.Parser == nil {
+			return nil, fmt.Errorf("parser %q not found", c.Parser)
+		}
+	}
+	for _, f := range c.Filters {
+		proc, e := f.processor()
+		if e != nil {
+			return nil, e
+		}
+		p.Processors = append(p.Processors, proc)
+	}
+	if len(c.Enrich) > 0 {
+		p.Processors = append(p.Processors, Set(c.Enrich))
+	}
+	if len(c.Sinks) == 0 {
+		return nil, fmt.Errorf("at least one sink must be configured")
+	}
+	for _, s := range c.Sinks {
+		if e := s.validate(); e != nil {
+			return nil, e
+		}
+	}
+	return p, nil
+}
+
+func (c *SourceConfig) source() (Source, error) {
+	switch c.Type {
+	case "file":
+		if c.Path == "" {
+			return nil, fmt.Errorf("path must be set for file source")
+		}
+		return &FileSource{Path: c.Path, Follow: c.Follow, FromBegin: c.FromBegin}, nil
+	case "stdin":
+		return &StdinSource{}, nil
+	case "remote":
+		if len(c.Hosts) == 0 {
+			return nil, fmt.Errorf("hosts must be set for remote source")
+		}
+		src := &RemoteSource{}
+		for _, h := range c.Hosts {
+			src.Logs = append(src.Logs, &logging.RemoteLog{
+				Host:          h,
+				User:          c.User,
+				CustomLogRoot: c.LogRoot,
+				Pattern:       c.Pattern,
+				Time:          c.From,
+				Until:         c.Until,
+				Tail:          c.Follow,
+				FromBegin:     c.FromBegin,
+			})
+		}
+		return src, nil
+	}
+	return nil, fmt.Errorf("source type %q not supported", c.Type)
+}
+
+func (c *FilterConfig) processor() (Processor, error) {
+	switch {
+	case c.Match != "" || c.Reject != "":
+		if c.Field == "" {
+			return nil, fmt.Errorf("field must be set for match and reject filters")
+		}
+		if c.Match != "" {
+			re, e := regexp.Compile(c.Match)
+			if e != nil {
+				return nil, e
+			}
+			return Match(c.Field, re), nil
+		}
+		re, e := regexp.Compile(c.Reject)
+		if e != nil {
+			return nil, e
+		}
+		return Reject(c.Field, re), nil
+	case len(c.Include) > 0:
+		return Include(c.Include...), nil
+	case len(c.Exclude) > 0:
+		return Exclude(c.Exclude...), nil
+	}
+	return nil, fmt.Errorf("filter %+v has no match, reject, include or exclude", c)
+}
+
+func (c *SinkConfig) validate() error {
+	switch c.Type {
+	case "elasticsearch":
+		if c.Address == "" || c.Index == "" {
+			return fmt.Errorf("address and index must be set for elasticsearch sink")
+		}
+	case "opentsdb":
+		if c.Address == "" || len(c.Values) == 0 {
+			return fmt.Errorf("address and values must be set for opentsdb sink")
+		}
+	case "json":
+	default:
+		return fmt.Errorf("sink type %q not supported", c.Type)
+	}
+	return nil
+}
+
+func (c *SinkConfig) sink() (Sink, error) {
+	switch c.Type {
+	case "elasticsearch":
+		s := NewElasticsearchSink(c.Address, c.Index)
+		s.IndexTimeLayout = c.IndexTimeLayout
+		s.Type = c.DocType
+		return s, nil
+	case "opentsdb":
+		s, e := NewOpenTSDBSink(c.Address, c.Metric)
+		if e != nil {
+			return nil, e
+		}
+		s.Values, s.Tags = c.Values, c.Tags
+		return s, nil
+	}
+	return &JSONSink{Writer: os.Stdout}, nil
+}
@@ -0,0 +1,81 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+	content := `{
+		"source": {"type": "remote", "hosts": ["a", "b"], "from": "2014-01-01T10:00:00Z", "until": "2014-01-01T12:00:00Z"},
+		"parser": "nginx",
+		"filters": [{"field": "status", "match": "^5"}, {"exclude": ["ua"]}],
+		"enrich": {"env": "production"},
+		"sinks": [{"type": "json"}]
+	}`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := cfg.Pipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, ok := p.Source.(*RemoteSource)
+	if !ok || len(src.Logs) != 2 {
+		t.Fatalf("expected remote source with 2 logs, got %#v", p.Source)
+	}
+	if v := len(src.Logs[0].Paths()); v != 3 {
+		t.Errorf("expected 3 paths, got %d", v)
+	}
+	if p.Parser == nil || p.Parser.Name() != "nginx" {
+		t.Errorf("expected nginx parser, got %#v", p.Parser)
+	}
+	if len(p.Processors) != 3 {
+		t.Errorf("expected 3 processors, got %d", len(p.Processors))
+	}
+	if _, ok := p.Sinks[0].(*JSONSink); !ok {
+		t.Errorf("expected json sink, got %#v", p.Sinks[0])
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		Config   *Config
+		Expected string
+	}{
+		{&Config{}, "source must be set"},
+		{&Config{Source: &SourceConfig{Type: "ftp"}}, `source type "ftp" not supported`},
+		{&Config{Source: &SourceConfig{Type: "file"}}, "path must be set"},
+		{&Config{Source: &SourceConfig{Type: "stdin"}, Parser: "apache"}, `parser "apache" not found`},
+		{&Config{Source: &SourceConfig{Type: "stdin"}, Filters: []*FilterConfig{{Match: "x"}}}, "field must be set"},
+		{&Config{Source: &SourceConfig{Type: "stdin"}, Filters: []*FilterConfig{{Field: "a", Match: "("}}}, "missing closing )"},
+		{&Config{Source: &SourceConfig{Type: "stdin"}}, "at least one sink"},
+		{&Config{Source: &SourceConfig{Type: "stdin"}, Sinks: []*SinkConfig{{Type: "opentsdb", Address: "localhost:4242"}}}, "address and values must be set"},
+		{&Config{Source: &SourceConfig{Type: "stdin"}, Sinks: []*SinkConfig{{Type: "elasticsearch", Address: "http://localhost:9200", Index: "logs"}}}, ""},
+	}
+	for _, tst := range tests {
+		err := tst.Config.Validate()
+		if tst.Expected == "" {
+			if err != nil {
+				t.Errorf("expected no error, got %q", err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tst.Expected) {
+			t.Errorf("expected error containing %q, got %v", tst.Expected, err)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/dynport/dgtk/cli"
+)
+
+var logger = log.New(os.Stderr, "", 0)
+
+func main() {
+	switch e := router().RunWithArgs(); e {
+	case nil, cli.ErrorNoRoute, cli.ErrorHelpRequested:
+		// ignore
+
+	default:
+		logger.Fatal(e)
+	}
+}
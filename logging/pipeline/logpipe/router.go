@@ -0,0 +1,13 @@
+package main
+
+import "github.com/dynport/dgtk/cli"
+
+func router() *cli.Router {
+	r := cli.NewRouter()
+
+	r.Register("run", &run{}, "run the pipeline described by a config file")
+	r.Register("check", &check{}, "validate a pipeline config without running it")
+	r.Register("parsers", &parsers{}, "list all available parsers")
+
+	return r
+}
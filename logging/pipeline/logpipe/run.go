@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/dynport/dgtk/logging"
+	"github.com/dynport/dgtk/logging/pipeline"
+)
+
+type run struct {
+	Config        string `cli:"type=arg required=true desc='path to the pipeline config (JSON)'"`
+	StatsInterval string `cli:"type=opt long=stats-interval desc='print stats in this interval (e.g. 10s)'"`
+}
+
+func (r *run) Run() error {
+	var interval time.Duration
+	if r.StatsInterval != "" {
+		var e error
+		if interval, e = time.ParseDuration(r.StatsInterval); e != nil {
+			return e
+		}
+	}
+	cfg, e := pipeline.LoadConfig(r.Config)
+	if e != nil {
+		return e
+	}
+	p, e := cfg.Pipeline()
+	if e != nil {
+		return e
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(c)
+	go func() {
+		select {
+		case s := <-c:
+			logger.Printf("received %s, stopping", s)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if interval > 0 {
+		go func() {
+			t := time.NewTicker(interval)
+			defer t.Stop()
+			for {
+				select {
+				case <-t.C:
+					logger.Print(p.Stats())
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	e = p.Run(ctx)
+	logger.Print(p.Stats())
+	if e == context.Canceled {
+		return nil
+	}
+	return e
+}
+
+type check struct {
+	Config string `cli:"type=arg required=true desc='path to the pipeline config (JSON)'"`
+}
+
+func (r *check) Run() error {
+	cfg, e := pipeline.LoadConfig(r.Config)
+	if e != nil {
+		return e
+	}
+	if e := cfg.Validate(); e != nil {
+		return e
+	}
+	b, e := json.MarshalIndent(cfg, "", "  ")
+	if e != nil {
+		return e
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+type parsers struct {
+}
+
+func (r *parsers) Run() error {
+	for _, p := range logging.DefaultRegistry.Parsers() {
+		fmt.Println(p.Name())
+	}
+	return nil
+}
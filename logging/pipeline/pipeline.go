@@ -0,0 +1,195 @@
+// Package pipeline reads log lines from a source, parses them into records,
+// filters and enriches them and writes them to sinks like Elasticsearch or
+// OpenTSDB.
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/dynport/dgtk/logging"
+)
+
+// Source provides the raw log lines.
+type Source interface {
+	Open() (io.ReadCloser, error)
+}
+
+// Processor filters or modifies a record. Returning false drops the record.
+type Processor interface {
+	Process(r *logging.Record) bool
+}
+
+// ProcessorFunc adapts a function to the Processor interface.
+type ProcessorFunc func(r *logging.Record) bool
+
+func (f ProcessorFunc) Process(r *logging.Record) bool {
+	return f(r)
+}
+
+// Sink receives all records passing the processors.
+type Sink interface {
+	Write(r *logging.Record) error
+	Close() error
+}
+
+// Pipeline connects a source with sinks. Lines which can not be parsed are
+// counted and skipped.
+type Pipeline struct {
+	Source Source
+	// Parser defaults to trying all parsers of logging.DefaultRegistry.
+	Parser     logging.Parser
+	Processors []Processor
+	Sinks      []Sink
+
+	stats counters
+}
+
+type counters struct {
+	lines, parsed, failed, dropped, written atomic.Int64
+}
+
+// Stats counts the lines and records passing through a pipeline.
+type Stats struct {
+	Lines   int64 `json:"lines"`
+	Parsed  int64 `json:"parsed"`
+	Failed  int64 `json:"failed"`
+	Dropped int64 `json:"dropped"`
+	Written int64 `json:"written"`
+}
+
+func (s *Stats) String() string {
+	return fmt.Sprintf("lines=%d parsed=%d failed=%d dropped=%d written=%d", s.Lines, s.Parsed, s.Failed, s.Dropped, s.Written)
+}
+
+// Stats returns a snapshot of the counters. It is safe to call while the
+// pipeline is running.
+func (p *Pipeline) Stats() *Stats {
+	return &Stats{
+		Lines:   p.stats.lines.Load(),
+		Parsed:  p.stats.parsed.Load(),
+		Failed:  p.stats.failed.Load(),
+		Dropped: p.stats.dropped.Load(),
+		Written: p.stats.written.Load(),
+	}
+}
+
+// Run reads the source until it ends or ctx is done and closes all sinks.
+func (p *Pipeline) Run(ctx context.Context) error {
+	if p.Source == nil {
+		return fmt.Errorf("source must be set")
+	}
+	r, e := p.Source.Open()
+	if e != nil {
+		return e
+	}
+	defer r.Close()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			r.Close()
+		case <-stop:
+		}
+	}()
+
+	e = p.read(ctx, r)
+	if err := p.closeSinks(); e == nil {
+		e = err
+	}
+	return e
+}
+
+// read handles the lines of r until it ends or ctx is done. Lines are read in
+// a goroutine so sources which can not be interrupted by Close (e.g. stdin)
+// do not block Run after ctx is done.
+func (p *Pipeline) read(ctx context.Context, r io.Reader) error {
+	lines := make(chan string)
+	errs := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		br := bufio.NewReader(r)
+		for {
+			line, e := br.ReadString('\n')
+			if line = strings.TrimRight(line, "\r\n"); line != "" {
+				select {
+				case lines <- line:
+				case <-done:
+					return
+				}
+			}
+			if e != nil {
+				errs <- e
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line := <-lines:
+			if err := p.Handle(line); err != nil {
+				return err
+			}
+		case e := <-errs:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			} else if e == io.EOF {
+				return nil
+			}
+			return e
+		}
+	}
+}
+
+// Handle passes a single line through the pipeline. It only returns an
+// error when a sink fails.
+func (p *Pipeline) Handle(line string) error {
+	p.stats.lines.Add(1)
+	rec, e := p.parse(line)
+	if e != nil {
+		p.stats.failed.Add(1)
+		dbg.Printf("unable to parse %q: %s", line, e)
+		return nil
+	}
+	p.stats.parsed.Add(1)
+	if rec.Fields == nil {
+		rec.Fields = map[string]interface{}{}
+	}
+	for _, proc := range p.Processors {
+		if !proc.Process(rec) {
+			p.stats.dropped.Add(1)
+			return nil
+		}
+	}
+	for _, s := range p.Sinks {
+		if e := s.Write(rec); e != nil {
+			return e
+		}
+	}
+	p.stats.written.Add(1)
+	return nil
+}
+
+func (p *Pipeline) parse(line string) (*logging.Record, error) {
+	if p.Parser != nil {
+		return p.Parser.Parse(line)
+	}
+	return logging.DefaultRegistry.Parse(line)
+}
+
+func (p *Pipeline) closeSinks() (e error) {
+	for _, s := range p.Sinks {
+		if err := s.Close(); err != nil && e == nil {
+			e = err
+		}
+	}
+	return e
+}
@@ -0,0 +1,233 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dynport/dgtk/logging"
+)
+
+const (
+	nginxOK    = `2013-12-09T14:19:14.575268+01:00 some.host nginx.notice[]: some.ip - host=phraseapp.com method=GET status=200 length=11928 total=2.235 ua="curl/7.38.0" uri="/" ref="-"`
+	nginxError = `2013-12-09T14:19:15.575268+01:00 other.host nginx.notice[]: some.ip - host=phraseapp.com method=POST status=500 length=12 total=0.5 ua="curl/7.38.0" uri="/login" ref="-"`
+)
+
+type memorySink struct {
+	records []*logging.Record
+	closed  bool
+}
+
+func (s *memorySink) Write(r *logging.Record) error {
+	s.records = append(s.records, r)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestPipelineRun(t *testing.T) {
+	sink := &memorySink{}
+	p := &Pipeline{
+		Source: &ReaderSource{Reader: strings.NewReader(nginxOK + "\nnot a log line\n" + nginxError + "\n" + nginxOK)},
+		Parser: logging.NginxParser,
+		Processors: []Processor{
+			Reject("uri", regexp.MustCompile("^/login")),
+			Include("status", "total_time"),
+			Set(map[string]interface{}{"env": "test"}),
+		},
+		Sinks: []Sink{sink},
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !sink.closed {
+		t.Error("expected sink to be closed")
+	}
+	stats := p.Stats()
+	tests := []struct {
+		Name     string
+		Expected interface{}
+		Value    interface{}
+	}{
+		{"Lines", int64(4), stats.Lines},
+		{"Parsed", int64(3), stats.Parsed},
+		{"Failed", int64(1), stats.Failed},
+		{"Dropped", int64(1), stats.Dropped},
+		{"Written", int64(2), stats.Written},
+		{"Records", 2, len(sink.records)},
+	}
+	for _, tst := range tests {
+		if tst.Expected != tst.Value {
+			t.Errorf("expected %s to be %#v, was %#v", tst.Name, tst.Expected, tst.Value)
+		}
+	}
+	r := sink.records[0]
+	if len(r.Fields) != 3 || r.Fields["status"] != "200" || r.Fields["total_time"] != 2.235 || r.Fields["env"] != "test" {
+		t.Errorf("unexpected fields %#v", r.Fields)
+	}
+	if r.Host != "some.host" || r.Format != "nginx" {
+		t.Errorf("unexpected record %#v", r)
+	}
+}
+
+func TestPipelineRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	src := &blockingSource{}
+	p := &Pipeline{Source: src, Sinks: []Sink{&memorySink{}}}
+	errs := make(chan error)
+	go func() {
+		errs <- p.Run(ctx)
+	}()
+	cancel()
+	select {
+	case err := <-errs:
+		if err != context.Canceled {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for pipeline to stop")
+	}
+}
+
+func TestPipelineRunCanceledStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sink := &signalSink{written: make(chan struct{}, 1)}
+	p := &Pipeline{Source: &StdinSource{}, Sinks: []Sink{sink}}
+	errs := make(chan error)
+	go func() {
+		errs <- p.Run(ctx)
+	}()
+	io.WriteString(w, nginxOK+"\n")
+	select {
+	case <-sink.written:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the first record")
+	}
+	// the pipeline is blocked reading the next line now
+	cancel()
+	select {
+	case err := <-errs:
+		if err != context.Canceled {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for pipeline to stop")
+	}
+}
+
+type signalSink struct {
+	written chan struct{}
+}
+
+func (s *signalSink) Write(r *logging.Record) error {
+	s.written <- struct{}{}
+	return nil
+}
+
+func (s *signalSink) Close() error {
+	return nil
+}
+
+type blockingSource struct{}
+
+func (s *blockingSource) Open() (io.ReadCloser, error) {
+	r, _ := io.Pipe()
+	return r, nil
+}
+
+func TestJSONSink(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := &Pipeline{
+		Source: &ReaderSource{Reader: strings.NewReader(nginxOK)},
+		Sinks:  []Sink{&JSONSink{Writer: buf}},
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	r := &logging.Record{}
+	if err := json.Unmarshal(buf.Bytes(), r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Format != "nginx" || r.Fields["method"] != "GET" || r.Raw != nginxOK {
+		t.Errorf("unexpected record %#v", r)
+	}
+}
+
+func TestOpenTSDBSink(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sink := &OpenTSDBSink{Writer: buf, Metric: "nginx", Values: []string{"total_time", "length", "missing"}, Tags: []string{"method", "status", "uri"}}
+	r, err := logging.NginxParser.Parse(nginxError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(r); err != nil {
+		t.Fatal(err)
+	}
+	expected := "put nginx.total_time 1386595155 0.5 host=other.host method=POST status=500 uri=/login\n" +
+		"put nginx.length 1386595155 12 host=other.host method=POST status=500 uri=/login\n"
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestElasticsearchSink(t *testing.T) {
+	bodies := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies <- r.URL.Path + "\n" + string(b)
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	sink := NewElasticsearchSink(srv.URL, "logs-")
+	sink.IndexTimeLayout = "2006.01.02"
+	r, err := logging.NginxParser.Parse(nginxOK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(<-bodies), "\n")
+	if len(lines) != 3 || lines[0] != "/_bulk" {
+		t.Fatalf("unexpected request %q", lines)
+	}
+	meta := map[string]map[string]string{}
+	if err := json.Unmarshal([]byte(lines[1]), &meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta["index"]["_index"] != "logs-2013.12.09" || meta["index"]["_type"] != "log" || meta["index"]["_id"] != recordID(r) {
+		t.Errorf("unexpected meta %#v", meta)
+	}
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(lines[2]), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["@timestamp"] != "2013-12-09T13:19:14.575268Z" || doc["host"] != "some.host" || doc["method"] != "GET" {
+		t.Errorf("unexpected doc %#v", doc)
+	}
+}
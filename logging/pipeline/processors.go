@@ -0,0 +1,80 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/dynport/dgtk/logging"
+)
+
+// Value returns the value of field in r. The names "host", "format" and
+// "raw" refer to the attributes of the record when not set as field.
+func Value(r *logging.Record, field string) (interface{}, bool) {
+	if v, ok := r.Fields[field]; ok {
+		return v, true
+	}
+	switch field {
+	case "host":
+		return r.Host, r.Host != ""
+	case "format":
+		return r.Format, r.Format != ""
+	case "raw":
+		return r.Raw, r.Raw != ""
+	}
+	return nil, false
+}
+
+func valueMatches(r *logging.Record, field string, re *regexp.Regexp) bool {
+	v, ok := Value(r, field)
+	return ok && re.MatchString(fmt.Sprint(v))
+}
+
+// Match keeps only records with field matching re.
+func Match(field string, re *regexp.Regexp) Processor {
+	return ProcessorFunc(func(r *logging.Record) bool {
+		return valueMatches(r, field, re)
+	})
+}
+
+// Reject drops all records with field matching re.
+func Reject(field string, re *regexp.Regexp) Processor {
+	return ProcessorFunc(func(r *logging.Record) bool {
+		return !valueMatches(r, field, re)
+	})
+}
+
+// Include removes all fields but the given ones.
+func Include(fields ...string) Processor {
+	keep := map[string]bool{}
+	for _, f := range fields {
+		keep[f] = true
+	}
+	return ProcessorFunc(func(r *logging.Record) bool {
+		for k := range r.Fields {
+			if !keep[k] {
+				delete(r.Fields, k)
+			}
+		}
+		return true
+	})
+}
+
+// Exclude removes the given fields.
+func Exclude(fields ...string) Processor {
+	return ProcessorFunc(func(r *logging.Record) bool {
+		for _, f := range fields {
+			delete(r.Fields, f)
+		}
+		return true
+	})
+}
+
+// Set adds the given fields to every record, replacing existing values.
+func Set(fields map[string]interface{}) Processor {
+	return ProcessorFunc(func(r *logging.Record) bool {
+		for k, v := range fields {
+			r.Fields[k] = v
+		}
+		return true
+	})
+}
@@ -0,0 +1,163 @@
+package pipeline
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dynport/dgtk/es"
+	"github.com/dynport/dgtk/logging"
+)
+
+// ElasticsearchSink indexes records with an es.BatchIndexer. The id of a
+// document is derived from the raw line so reading a log twice does not
+// create duplicates.
+type ElasticsearchSink struct {
+	Indexer *es.BatchIndexer
+	Index   string
+	// IndexTimeLayout is appended to Index using the time of the record,
+	// e.g. "2006.01.02" for daily indexes.
+	IndexTimeLayout string
+	Type            string // defaults to "log"
+}
+
+func NewElasticsearchSink(addr, index string) *ElasticsearchSink {
+	return &ElasticsearchSink{Indexer: es.NewBatchIndexer(addr), Index: index}
+}
+
+func (s *ElasticsearchSink) Write(r *logging.Record) error {
+	index := s.Index
+	if s.IndexTimeLayout != "" {
+		index += r.Time.UTC().Format(s.IndexTimeLayout)
+	}
+	docType := s.Type
+	if docType == "" {
+		docType = "log"
+	}
+	return s.Indexer.Add(&es.Doc{Index: index, Type: docType, Id: recordID(r), Source: document(r)})
+}
+
+func (s *ElasticsearchSink) Close() error {
+	return s.Indexer.Close()
+}
+
+func recordID(r *logging.Record) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(r.Host+"\n"+r.Raw)))
+}
+
+// document flattens a record into a single map with the attributes of the
+// record next to its fields.
+func document(r *logging.Record) map[string]interface{} {
+	doc := make(map[string]interface{}, len(r.Fields)+4)
+	for k, v := range r.Fields {
+		doc[k] = v
+	}
+	if !r.Time.IsZero() {
+		doc["@timestamp"] = r.Time.UTC()
+	}
+	for k, v := range map[string]string{"host": r.Host, "format": r.Format, "raw": r.Raw} {
+		if _, ok := doc[k]; !ok && v != "" {
+			doc[k] = v
+		}
+	}
+	return doc
+}
+
+// OpenTSDBSink writes numeric fields of records as OpenTSDB put commands.
+type OpenTSDBSink struct {
+	Writer io.Writer // e.g. a connection to a tsd, see NewOpenTSDBSink
+	Metric string    // prefix of the metric names
+	Values []string  // numeric fields written as <Metric>.<field>
+	Tags   []string  // fields written as tags (next to host), a tsd requires at least one
+}
+
+// NewOpenTSDBSink connects to the tsd at addr (e.g. "localhost:4242").
+func NewOpenTSDBSink(addr, metric string) (*OpenTSDBSink, error) {
+	conn, e := net.Dial("tcp", addr)
+	if e != nil {
+		return nil, e
+	}
+	return &OpenTSDBSink{Writer: conn, Metric: metric}, nil
+}
+
+var invalidTagChars = regexp.MustCompile(`[^a-zA-Z0-9\-_./]+`)
+
+func tagValue(v interface{}) string {
+	return invalidTagChars.ReplaceAllString(fmt.Sprint(v), "_")
+}
+
+func (s *OpenTSDBSink) Write(r *logging.Record) error {
+	tags := []string{}
+	if r.Host != "" {
+		tags = append(tags, "host="+tagValue(r.Host))
+	}
+	for _, t := range s.Tags {
+		if v, ok := Value(r, t); ok && fmt.Sprint(v) != "" {
+			tags = append(tags, tagValue(t)+"="+tagValue(v))
+		}
+	}
+	sort.Strings(tags)
+	ts := r.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	for _, field := range s.Values {
+		v, ok := numericValue(r.Fields[field])
+		if !ok {
+			continue
+		}
+		metric := field
+		if s.Metric != "" {
+			metric = s.Metric + "." + field
+		}
+		line := fmt.Sprintf("put %s %d %v %s\n", metric, ts.Unix(), v, strings.Join(tags, " "))
+		if _, e := io.WriteString(s.Writer, line); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+func numericValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case time.Duration:
+		return v.Seconds(), true
+	}
+	return 0, false
+}
+
+func (s *OpenTSDBSink) Close() error {
+	if c, ok := s.Writer.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// JSONSink writes every record as JSON object on a single line.
+type JSONSink struct {
+	Writer io.Writer
+}
+
+func (s *JSONSink) Write(r *logging.Record) error {
+	return json.NewEncoder(s.Writer).Encode(r)
+}
+
+func (s *JSONSink) Close() error {
+	return nil
+}
@@ -0,0 +1,57 @@
+package pipeline
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/dynport/dgtk/logging"
+)
+
+// FileSource reads a local file. With Follow set it waits for new lines like
+// tail -F.
+type FileSource struct {
+	Path      string
+	Follow    bool
+	FromBegin bool // to be used with Follow
+}
+
+func (s *FileSource) Open() (io.ReadCloser, error) {
+	if s.Follow {
+		return logging.FollowFile(s.Path, s.FromBegin)
+	}
+	return os.Open(s.Path)
+}
+
+// StdinSource reads lines from stdin. Closing it does not interrupt a
+// blocked read, Pipeline.Run returns when its context is done anyway.
+type StdinSource struct{}
+
+func (s *StdinSource) Open() (io.ReadCloser, error) {
+	return ioutil.NopCloser(os.Stdin), nil
+}
+
+// RemoteSource reads the logs of one or more hosts. Lines of multiple hosts
+// are merged by timestamp.
+type RemoteSource struct {
+	Logs []*logging.RemoteLog
+}
+
+func (s *RemoteSource) Open() (io.ReadCloser, error) {
+	if len(s.Logs) == 1 {
+		return s.Logs[0].Open()
+	}
+	return logging.OpenMerged(s.Logs...)
+}
+
+// ReaderSource reads lines from an already opened reader.
+type ReaderSource struct {
+	Reader io.Reader
+}
+
+func (s *ReaderSource) Open() (io.ReadCloser, error) {
+	if rc, ok := s.Reader.(io.ReadCloser); ok {
+		return rc, nil
+	}
+	return ioutil.NopCloser(s.Reader), nil
+}
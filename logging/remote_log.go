@@ -1,21 +1,38 @@
 package logging
 
 import (
+	"bufio"
+	"bytes"
 	"compress/gzip"
 	"io"
-	"os/exec"
+	"net"
+	"regexp"
+	"sync"
 	"time"
+
+	"golang.org/x/crypto/ssh"
 )
 
 type RemoteLog struct {
-	Host          string
-	User          string
+	Host string
+	User string
+	// Pattern is a Go regular expression (see package regexp) matched against
+	// every line. It used to be passed to grep on the remote host, patterns
+	// relying on grep syntax (e.g. `a\|b` for alternation) must be
+	// rewritten (`a|b`).
 	Pattern       string
 	Tail          bool
 	Time          time.Time
+	Until         time.Time // read all hourly files from Time up to Until
 	Compress      bool
 	CustomLogRoot string
 	FromBegin     bool // to be used with tail
+
+	// HostKeyCallback verifies the key of Host. It defaults to checking
+	// ~/.ssh/known_hosts.
+	HostKeyCallback ssh.HostKeyCallback
+	// Auth defaults to the keys of the ssh agent listening on SSH_AUTH_SOCK.
+	Auth []ssh.AuthMethod
 }
 
 const (
@@ -31,6 +48,16 @@ func NewRemoteLogFromTime(host string, t time.Time, pattern string) *RemoteLog {
 	}
 }
 
+// NewRemoteLogsFromRange returns one log per host reading all hourly files
+// between from and until. Use OpenMerged to read them in parallel.
+func NewRemoteLogsFromRange(hosts []string, from, until time.Time, pattern string) []*RemoteLog {
+	logs := make([]*RemoteLog, 0, len(hosts))
+	for _, host := range hosts {
+		logs = append(logs, &RemoteLog{Host: host, Time: from, Until: until, Pattern: pattern})
+	}
+	return logs
+}
+
 func (rl *RemoteLog) LogRoot() string {
 	if rl.CustomLogRoot != "" {
 		return rl.CustomLogRoot
@@ -44,15 +71,35 @@ func (rl *RemoteLog) Current() string {
 
 func (rl *RemoteLog) Path() string {
 	if !rl.Time.IsZero() {
-		return rl.Time.UTC().Format(rl.LogRoot() + "/" + HOURLY_PATTERN)
+		return rl.pathAt(rl.Time)
 	}
 	return rl.Current()
 }
 
+func (rl *RemoteLog) pathAt(t time.Time) string {
+	return t.UTC().Format(rl.LogRoot() + "/" + HOURLY_PATTERN)
+}
+
 func (rl *RemoteLog) GzipPath() string {
 	return rl.Path() + ".gz"
 }
 
+// Paths returns the hourly files between Time and Until (both inclusive). It
+// returns only Path when Until is not set.
+func (rl *RemoteLog) Paths() []string {
+	if rl.Time.IsZero() || rl.Until.IsZero() {
+		return []string{rl.Path()}
+	}
+	paths := []string{}
+	for t := rl.Time.UTC().Truncate(time.Hour); !t.After(rl.Until); t = t.Add(time.Hour) {
+		paths = append(paths, rl.pathAt(t))
+	}
+	return paths
+}
+
+// Command returns the shell pipeline equivalent to Open.
+//
+// Deprecated: Open no longer runs this command.
 func (rl *RemoteLog) Command() string {
 	cmd := rl.CatCmd()
 	if rl.Pattern != "" {
@@ -64,10 +111,12 @@ func (rl *RemoteLog) Command() string {
 	return cmd
 }
 
+// Deprecated: Open filters lines with Pattern as regular expression.
 func (rl *RemoteLog) GrepCmd() string {
 	return "grep " + rl.Pattern
 }
 
+// Deprecated: Open no longer runs this command.
 func (rl *RemoteLog) CatCmd() string {
 	if rl.Tail {
 		n := "0"
@@ -79,35 +128,131 @@ func (rl *RemoteLog) CatCmd() string {
 	return "{ test -e " + rl.Path() + " && cat " + rl.Path() + "; test -e " + rl.GzipPath() + " && cat " + rl.GzipPath() + " | gunzip; }"
 }
 
-func (rl *RemoteLog) Open() (reader io.ReadCloser, e error) {
-	c := rl.Command()
-	var cmd *exec.Cmd
+func (rl *RemoteLog) address() string {
+	if _, _, e := net.SplitHostPort(rl.Host); e == nil {
+		return rl.Host
+	}
+	return net.JoinHostPort(rl.Host, "22")
+}
+
+func (rl *RemoteLog) source() (logSource, error) {
+	if rl.Host == "" {
+		return newLocalSource(), nil
+	}
 	user := rl.User
 	if user == "" {
 		user = "root"
 	}
-	if rl.Host != "" {
-		cmd = exec.Command("ssh", "-t", "-l", user, rl.Host, c)
-	} else {
-		cmd = exec.Command("bash", "-c", c)
+	return dialSSH(rl.address(), user, rl.HostKeyCallback, rl.Auth)
+}
+
+// Open connects to Host via ssh (authenticating with Auth or the running ssh
+// agent) and returns a reader for all lines matching Pattern. Without Host the files
+// are read from the local disk. Gzipped files are decoded while streaming.
+// With Tail set, the reader follows the current file until it is closed.
+func (rl *RemoteLog) Open() (io.ReadCloser, error) {
+	var filter *regexp.Regexp
+	if rl.Pattern != "" {
+		var e error
+		if filter, e = regexp.Compile(rl.Pattern); e != nil {
+			return nil, e
+		}
 	}
-	dbg.Printf("using cmd %q", cmd.Path)
-	reader, e = cmd.StdoutPipe()
+	dbg.Printf("opening log on host %q", rl.Host)
+	src, e := rl.source()
 	if e != nil {
 		return nil, e
 	}
-	dbg.Printf("starting command %q", c)
-	e = cmd.Start()
+	pr, pw := io.Pipe()
+	go func() {
+		defer src.Close()
+		pw.CloseWithError(rl.copyLines(pw, src, filter))
+	}()
+	return &remoteLogReader{PipeReader: pr, src: src}, nil
+}
+
+func (rl *RemoteLog) copyLines(w io.Writer, src logSource, filter *regexp.Regexp) error {
+	if rl.Tail {
+		dbg.Printf("following %q", rl.Current())
+		r, e := src.Follow(rl.Current(), rl.FromBegin)
+		if e != nil {
+			return e
+		}
+		defer r.Close()
+		testHookFollowing()
+		return copyMatching(w, r, filter)
+	}
+	for _, p := range rl.Paths() {
+		for _, path := range []string{p, p + ".gz"} {
+			dbg.Printf("reading %q", path)
+			if e := copyFile(w, src, path, rl.Compress, filter); e != nil {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// testHookFollowing is called once the reader following the current file was
+// opened.
+var testHookFollowing = func() {}
+
+func copyFile(w io.Writer, src logSource, path string, compress bool, filter *regexp.Regexp) error {
+	r, e := src.Open(path, compress)
 	if e != nil {
-		return nil, e
+		return e
 	}
-	dbg.Print("command started")
-	if rl.Compress {
-		reader, e = gzip.NewReader(reader)
-		if e != nil {
-			return nil, e
+	defer r.Close()
+	return copyMatching(w, r, filter)
+}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decodeLog returns a reader decoding r in case it is gzipped.
+func decodeLog(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(2)
+	if bytes.Equal(magic, gzipMagic) {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+func copyMatching(w io.Writer, r io.Reader, filter *regexp.Regexp) error {
+	decoded, e := decodeLog(r)
+	if e != nil {
+		return e
+	}
+	br := bufio.NewReader(decoded)
+	for {
+		line, e := br.ReadBytes('\n')
+		if len(line) > 0 && (filter == nil || filter.Match(line)) {
+			if line[len(line)-1] != '\n' {
+				line = append(line, '\n')
+			}
+			if _, err := w.Write(line); err != nil {
+				return err
+			}
+		}
+		if e == io.EOF {
+			return nil
+		} else if e != nil {
+			return e
 		}
 	}
-	dbg.Print("returning reader")
-	return reader, nil
+}
+
+type remoteLogReader struct {
+	*io.PipeReader
+	src  logSource
+	once sync.Once
+}
+
+// Close stops reading and closes the connection to the host.
+func (r *remoteLogReader) Close() error {
+	r.once.Do(func() {
+		r.PipeReader.Close()
+		r.src.Close()
+	})
+	return nil
 }
@@ -0,0 +1,211 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"io"
+	"sync"
+	"time"
+)
+
+// OpenMerged opens all logs in parallel and writes their lines ordered by the
+// timestamp at the beginning of each line. Lines without a timestamp keep the
+// time of the previous line of the same log. Logs in Tail mode never end, so
+// their lines are written in the order they arrive.
+func OpenMerged(logs ...*RemoteLog) (io.ReadCloser, error) {
+	readers := make([]io.ReadCloser, 0, len(logs))
+	closeAll := func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}
+	tail := false
+	for _, l := range logs {
+		r, e := l.Open()
+		if e != nil {
+			closeAll()
+			return nil, e
+		}
+		readers = append(readers, r)
+		tail = tail || l.Tail
+	}
+
+	m := &mergedLog{done: make(chan struct{}), closeAll: closeAll}
+	pr, pw := io.Pipe()
+	m.PipeReader = pr
+	streams := make([]chan *timedLine, len(readers))
+	for i, r := range readers {
+		streams[i] = make(chan *timedLine, 1024)
+		go m.readLines(r, streams[i])
+	}
+	go func() {
+		var e error
+		if tail {
+			e = m.writeArriving(pw, streams)
+		} else {
+			e = m.writeOrdered(pw, streams)
+		}
+		if e == nil {
+			e = m.err()
+		}
+		pw.CloseWithError(e)
+	}()
+	return m, nil
+}
+
+type timedLine struct {
+	time time.Time
+	line []byte
+}
+
+type mergedLog struct {
+	*io.PipeReader
+	done     chan struct{}
+	closeAll func()
+	once     sync.Once
+
+	mutex    sync.Mutex
+	firstErr error
+}
+
+// Close stops reading all logs.
+func (m *mergedLog) Close() error {
+	m.once.Do(func() {
+		close(m.done)
+		m.PipeReader.Close()
+		m.closeAll()
+	})
+	return nil
+}
+
+func (m *mergedLog) err() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.firstErr
+}
+
+func (m *mergedLog) setErr(e error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.firstErr == nil {
+		m.firstErr = e
+	}
+}
+
+func (m *mergedLog) readLines(r io.Reader, lines chan<- *timedLine) {
+	defer close(lines)
+	br := bufio.NewReader(r)
+	var last time.Time
+	for {
+		line, e := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if t, ok := lineTime(line); ok {
+				last = t
+			}
+			select {
+			case lines <- &timedLine{time: last, line: line}:
+			case <-m.done:
+				return
+			}
+		}
+		if e == io.EOF {
+			return
+		} else if e != nil {
+			m.setErr(e)
+			return
+		}
+	}
+}
+
+func (m *mergedLog) writeArriving(w io.Writer, streams []chan *timedLine) error {
+	all := make(chan *timedLine)
+	wg := &sync.WaitGroup{}
+	for _, s := range streams {
+		wg.Add(1)
+		go func(s chan *timedLine) {
+			defer wg.Done()
+			for l := range s {
+				select {
+				case all <- l:
+				case <-m.done:
+					return
+				}
+			}
+		}(s)
+	}
+	go func() {
+		wg.Wait()
+		close(all)
+	}()
+	for l := range all {
+		if _, e := w.Write(l.line); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+func (m *mergedLog) writeOrdered(w io.Writer, streams []chan *timedLine) error {
+	h := &lineHeap{}
+	for i, s := range streams {
+		if l, ok := <-s; ok {
+			heap.Push(h, &lineHead{timedLine: l, stream: i})
+		}
+	}
+	for h.Len() > 0 {
+		head := heap.Pop(h).(*lineHead)
+		if _, e := w.Write(head.line); e != nil {
+			return e
+		}
+		if l, ok := <-streams[head.stream]; ok {
+			heap.Push(h, &lineHead{timedLine: l, stream: head.stream})
+		}
+	}
+	return nil
+}
+
+type lineHead struct {
+	*timedLine
+	stream int
+}
+
+// lineHeap orders lines by time and keeps the order of the streams for equal
+// times.
+type lineHeap []*lineHead
+
+func (h lineHeap) Len() int { return len(h) }
+
+func (h lineHeap) Less(i, j int) bool {
+	if h[i].time.Equal(h[j].time) {
+		return h[i].stream < h[j].stream
+	}
+	return h[i].time.Before(h[j].time)
+}
+
+func (h lineHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *lineHeap) Push(x interface{}) { *h = append(*h, x.(*lineHead)) }
+
+func (h *lineHeap) Pop() interface{} {
+	old := *h
+	l := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return l
+}
+
+var lineTimeLayouts = []string{timeLayout, timeLayoutWithoutMicro, time.RFC3339Nano}
+
+// lineTime parses the timestamp in the first field of a syslog line.
+func lineTime(line []byte) (time.Time, bool) {
+	field := line
+	if i := bytes.IndexAny(line, " \t\n"); i >= 0 {
+		field = line[:i]
+	}
+	for _, layout := range lineTimeLayouts {
+		if t, e := time.Parse(layout, string(field)); e == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,216 @@
+package logging
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeHourlyLog(t *testing.T, root string, hour time.Time, gzipped bool, lines ...string) {
+	path := hour.UTC().Format(root + "/" + HOURLY_PATTERN)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Join(lines, "\n") + "\n"
+	if !gzipped {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	f, err := os.Create(path + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	if _, err := gz.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoteLogPaths(t *testing.T) {
+	from := time.Date(2014, 1, 1, 22, 30, 0, 0, time.UTC)
+	rl := &RemoteLog{Time: from, Until: from.Add(2 * time.Hour)}
+	expected := []string{
+		"/var/log/hourly/2014/01/01/2014-01-01T22.log",
+		"/var/log/hourly/2014/01/01/2014-01-01T23.log",
+		"/var/log/hourly/2014/01/02/2014-01-02T00.log",
+	}
+	if v := strings.Join(rl.Paths(), ","); v != strings.Join(expected, ",") {
+		t.Errorf("expected paths to be %v, was %v", expected, v)
+	}
+
+	rl = &RemoteLog{}
+	if v := strings.Join(rl.Paths(), ","); v != "/var/log/hourly/current" {
+		t.Errorf("expected paths to be %q, was %q", "/var/log/hourly/current", v)
+	}
+
+	for host, expected := range map[string]string{"example.com": "example.com:22", "example.com:2222": "example.com:2222"} {
+		rl = &RemoteLog{Host: host}
+		if v := rl.address(); v != expected {
+			t.Errorf("expected address of %q to be %q, was %q", host, expected, v)
+		}
+	}
+}
+
+func TestRemoteLogOpenRange(t *testing.T) {
+	root, err := ioutil.TempDir("", "remote_log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	from := time.Date(2014, 1, 1, 10, 0, 0, 0, time.UTC)
+	writeHourlyLog(t, root, from, true,
+		"2014-01-01T10:00:01+00:00 host1 nginx: status=200",
+		"2014-01-01T10:00:02+00:00 host1 nginx: status=500",
+	)
+	writeHourlyLog(t, root, from.Add(time.Hour), false,
+		"2014-01-01T11:00:01+00:00 host1 nginx: status=200",
+		"2014-01-01T11:00:02+00:00 host1 nginx: status=500",
+	)
+
+	rl := &RemoteLog{CustomLogRoot: root, Time: from, Until: from.Add(2 * time.Hour), Pattern: "status=5[0-9]{2}"}
+	r, err := rl.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "2014-01-01T10:00:02+00:00 host1 nginx: status=500\n2014-01-01T11:00:02+00:00 host1 nginx: status=500\n"
+	if string(b) != expected {
+		t.Errorf("expected %q, was %q", expected, string(b))
+	}
+
+	if _, err := (&RemoteLog{Pattern: "("}).Open(); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestOpenMerged(t *testing.T) {
+	root, err := ioutil.TempDir("", "remote_log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	hour := time.Date(2014, 1, 1, 10, 0, 0, 0, time.UTC)
+	writeHourlyLog(t, root+"/a", hour, false,
+		"2014-01-01T10:00:01.000000+00:00 a app: 1",
+		"2014-01-01T10:00:03.000000+00:00 a app: 3",
+		"  continued",
+		"2014-01-01T10:00:05.000000+00:00 a app: 6",
+	)
+	writeHourlyLog(t, root+"/b", hour, true,
+		"2014-01-01T10:00:02.000000+00:00 b app: 2",
+		"2014-01-01T10:00:04.000000+00:00 b app: 5",
+		"2014-01-01T10:00:05.000000+00:00 b app: 7",
+	)
+
+	r, err := OpenMerged(
+		&RemoteLog{CustomLogRoot: root + "/a", Time: hour},
+		&RemoteLog{CustomLogRoot: root + "/b", Time: hour},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		lines = append(lines, fields[len(fields)-1])
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if v := strings.Join(lines, ","); v != "1,2,3,continued,5,6,7" {
+		t.Errorf("expected lines to be %q, was %q", "1,2,3,continued,5,6,7", v)
+	}
+}
+
+func TestRemoteLogTail(t *testing.T) {
+	root, err := ioutil.TempDir("", "remote_log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	rl := &RemoteLog{CustomLogRoot: root, Tail: true}
+	if err := ioutil.WriteFile(rl.Current(), []byte("old line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	following := make(chan struct{})
+	testHookFollowing = func() { close(following) }
+	defer func() { testHookFollowing = func() {} }()
+	r, err := rl.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	expectLine := func(expected string) {
+		select {
+		case l := <-lines:
+			if l != expected {
+				t.Errorf("expected line to be %q, was %q", expected, l)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for %q", expected)
+		}
+	}
+
+	// the reader seeked to the end of the file
+	select {
+	case <-following:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the reader to follow the file")
+	}
+	f, err := os.OpenFile(rl.Current(), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("new line\n")
+	f.Close()
+	expectLine("new line")
+
+	// rotate the file
+	if err := os.Rename(rl.Current(), rl.Current()+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(rl.Current(), []byte("rotated line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectLine("rotated line")
+
+	r.Close()
+	select {
+	case _, ok := <-lines:
+		if ok {
+			t.Error("expected no more lines after close")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for reader to finish")
+	}
+}
@@ -0,0 +1,79 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Command is a cli action migrating the database or printing the status of
+// all migrations. Apps register it with their cli.Router, e.g.
+//
+//	router.Register("db/migrate", &migrations.Command{Migrations: migs, Open: openDB}, "migrate the database")
+//
+// Open is called for every run and the returned database is closed
+// afterwards.
+type Command struct {
+	Migrations *Migrations
+	Open       func() (*sql.DB, error)
+	Writer     io.Writer // defaults to os.Stdout
+
+	To     *int `cli:"type=opt long=to desc='migrate up or down to this index (defaults to the latest)'"`
+	Status bool `cli:"type=opt long=status desc='only print the status of all migrations'"`
+}
+
+func (c *Command) Run() error {
+	if c.Migrations == nil || c.Open == nil {
+		return fmt.Errorf("Migrations and Open must be set")
+	}
+	db, err := c.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if !c.Status {
+		to := len(c.Migrations.steps)
+		if c.To != nil {
+			to = *c.To
+		}
+		if err := c.Migrations.MigrateTo(db, to); err != nil {
+			return err
+		}
+	}
+	status, err := c.Migrations.Status(db)
+	if err != nil {
+		return err
+	}
+	return c.printStatus(status)
+}
+
+const maxStatementLength = 60
+
+func (c *Command) printStatus(status []*Status) error {
+	w := c.Writer
+	if w == nil {
+		w = os.Stdout
+	}
+	t := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(t, "IDX\tSTATE\tEXECUTED AT\tDOWN\tSTATEMENT")
+	for _, s := range status {
+		createdAt := "-"
+		if !s.CreatedAt.IsZero() {
+			createdAt = s.CreatedAt.UTC().Format(time.RFC3339)
+		}
+		down := "-"
+		if s.Reversible {
+			down = "yes"
+		}
+		statement := strings.Join(strings.Fields(s.Statement), " ")
+		if len(statement) > maxStatementLength {
+			statement = statement[:maxStatementLength-3] + "..."
+		}
+		fmt.Fprintf(t, "%d\t%s\t%s\t%s\t%s\n", s.Idx, s.State, createdAt, down, statement)
+	}
+	return t.Flush()
+}
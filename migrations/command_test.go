@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandPrintStatus(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := &Command{Writer: buf}
+	err := c.printStatus([]*Status{
+		{Idx: 1, State: StateExecuted, Statement: "CREATE TABLE users (\n  id SERIAL NOT NULL PRIMARY KEY\n)", CreatedAt: time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC), Reversible: true},
+		{Idx: 2, State: StatePending, Statement: "INSERT INTO users (name) VALUES ('a very long name which is cut off at some point')"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"IDX  STATE     EXECUTED AT           DOWN  STATEMENT",
+		"1    executed  2017-01-02T03:04:05Z  yes   CREATE TABLE users ( id SERIAL NOT NULL PRIMARY KEY )",
+		"2    pending   -                     -     INSERT INTO users (name) VALUES ('a very long name which ...",
+	}
+	if v := strings.TrimSpace(buf.String()); v != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), v)
+	}
+}
@@ -0,0 +1,143 @@
+package migrations
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NewFromFS creates migrations from the SQL files in dir of fsys, see Load.
+func NewFromFS(fsys fs.FS, dir string) (*Migrations, error) {
+	list := New()
+	if err := list.Load(fsys, dir); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Set defines the step with the given (1 based) index, e.g. to add Go funcs
+// between migrations loaded from files.
+func (list *Migrations) Set(idx int, step interface{}) error {
+	if idx < 1 {
+		return fmt.Errorf("idx must be greater than 0, was %d", idx)
+	}
+	for len(list.steps) < idx {
+		list.steps = append(list.steps, nil)
+	}
+	if list.steps[idx-1] != nil {
+		return fmt.Errorf("migration %d already defined", idx)
+	}
+	list.steps[idx-1] = step
+	return nil
+}
+
+// Load adds all files named NNNN_name.up.sql in dir of fsys with the index
+// NNNN. A NNNN_name.down.sql file with the same name is used to roll the
+// migration back. Surrounding whitespace of the files is removed before the
+// checksum is calculated. This works with go:embed and os.DirFS.
+func (list *Migrations) Load(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return list.loadFiles(names, func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, path.Join(dir, name))
+	})
+}
+
+// LoadDir loads the SQL files in dir, see Load.
+func (list *Migrations) LoadDir(dir string) error {
+	return list.Load(os.DirFS(dir), ".")
+}
+
+// Assets is implemented by the file systems generated by goassets.
+type Assets interface {
+	Open(name string) (http.File, error)
+	AssetNames() []string
+}
+
+// LoadAssets loads the SQL files in dir of assets generated by goassets, see
+// Load.
+func (list *Migrations) LoadAssets(assets Assets, dir string) error {
+	dir = strings.Trim(dir, "/")
+	if dir == "" {
+		dir = "."
+	}
+	names := []string{}
+	paths := map[string]string{}
+	for _, p := range assets.AssetNames() {
+		if path.Dir(strings.TrimPrefix(p, "/")) == dir {
+			names = append(names, path.Base(p))
+			paths[path.Base(p)] = p
+		}
+	}
+	return list.loadFiles(names, func(name string) ([]byte, error) {
+		f, err := assets.Open(paths[name])
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return ioutil.ReadAll(f)
+	})
+}
+
+func (list *Migrations) loadFiles(names []string, read func(name string) ([]byte, error)) error {
+	steps := map[int]*Step{}
+	stepNames := map[int]string{}
+	sort.Strings(names)
+	for _, name := range names {
+		m := migrationFileRegexp.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		idx, err := strconv.Atoi(m[1])
+		if err != nil {
+			return err
+		}
+		if existing, ok := stepNames[idx]; ok && existing != m[2] {
+			return fmt.Errorf("migration %d defined as %q and %q", idx, existing, m[2])
+		}
+		stepNames[idx] = m[2]
+		b, err := read(name)
+		if err != nil {
+			return err
+		}
+		if steps[idx] == nil {
+			steps[idx] = &Step{}
+		}
+		if m[3] == "up" {
+			steps[idx].Up = strings.TrimSpace(string(b))
+		} else {
+			steps[idx].Down = strings.TrimSpace(string(b))
+		}
+	}
+	idxs := make([]int, 0, len(steps))
+	for idx := range steps {
+		idxs = append(idxs, idx)
+	}
+	sort.Ints(idxs)
+	for _, idx := range idxs {
+		if steps[idx].Up == nil {
+			return fmt.Errorf("migration %d %q has no up file", idx, stepNames[idx])
+		}
+		if err := list.Set(idx, steps[idx]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
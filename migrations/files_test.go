@@ -0,0 +1,132 @@
+package migrations
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"db/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id SERIAL NOT NULL PRIMARY KEY)\n")},
+		"db/0001_create_users.down.sql": {Data: []byte("DROP TABLE users\n")},
+		"db/0003_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email VARCHAR\n")},
+		"db/README.md":                  {Data: []byte("ignored")},
+	}
+	list, err := NewFromFS(fsys, "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Set(2, insertUsers); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Set(3, insertUsers); err == nil {
+		t.Errorf("expected error setting an existing migration")
+	}
+
+	// same checksum as the statement defined in Go
+	inline, err := newMigration(1, "CREATE TABLE users (id SERIAL NOT NULL PRIMARY KEY)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migs := []*Migration{}
+	for i, s := range list.steps {
+		m, err := newMigration(i+1, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		migs = append(migs, m)
+	}
+	tests := map[int]struct{ Has, Want interface{} }{
+		1: {len(migs), 3},
+		2: {migs[0].Statement, "CREATE TABLE users (id SERIAL NOT NULL PRIMARY KEY)"},
+		3: {migs[0].Down, "DROP TABLE users"},
+		4: {migs[1].Statement, "github.com/dynport/dgtk/migrations.insertUsers"},
+		5: {migs[2].Statement, "ALTER TABLE users ADD COLUMN email VARCHAR"},
+		6: {migs[2].Reversible(), false},
+		7: {migs[0].checksum(), inline.checksum()},
+	}
+	for i, tc := range tests {
+		if tc.Has != tc.Want {
+			t.Errorf("%d: want=%#v has=%#v", i, tc.Want, tc.Has)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		Files    fstest.MapFS
+		Expected string
+	}{
+		{fstest.MapFS{"0001_a.down.sql": {Data: []byte("DROP TABLE a")}}, `migration 1 "a" has no up file`},
+		{fstest.MapFS{"0001_a.up.sql": {}, "0001_b.up.sql": {}}, `migration 1 defined as "a" and "b"`},
+	}
+	for _, tst := range tests {
+		err := New().Load(tst.Files, ".")
+		if err == nil || !strings.Contains(err.Error(), tst.Expected) {
+			t.Errorf("expected error containing %q, got %v", tst.Expected, err)
+		}
+	}
+
+	list, err := NewFromFS(fstest.MapFS{"0002_b.up.sql": {Data: []byte("SELECT 1")}}, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newMigration(1, list.steps[0]); err == nil || err.Error() != "migration 1 is not defined" {
+		t.Errorf("expected error for missing migration, got %v", err)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "1_a.up.sql"), []byte("CREATE TABLE a (id INTEGER)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list := New()
+	if err := list.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.steps) != 1 {
+		t.Fatalf("expected 1 step, got %d", len(list.steps))
+	}
+	if s := list.steps[0].(*Step); s.Up != "CREATE TABLE a (id INTEGER)" || s.Down != nil {
+		t.Errorf("unexpected step %#v", s)
+	}
+}
+
+type testAssets struct {
+	http.FileSystem
+	names []string
+}
+
+func (a *testAssets) AssetNames() []string {
+	return a.names
+}
+
+func TestLoadAssets(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0001_a.up.sql":   {Data: []byte("CREATE TABLE a (id INTEGER)")},
+		"migrations/0001_a.down.sql": {Data: []byte("DROP TABLE a")},
+		"other/0002_b.up.sql":        {Data: []byte("CREATE TABLE b (id INTEGER)")},
+	}
+	assets := &testAssets{FileSystem: http.FS(fsys), names: []string{"/migrations/0001_a.down.sql", "/migrations/0001_a.up.sql", "/other/0002_b.up.sql"}}
+	list := New()
+	if err := list.LoadAssets(assets, "/migrations"); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.steps) != 1 {
+		t.Fatalf("expected 1 step, got %d", len(list.steps))
+	}
+	if s := list.steps[0].(*Step); s.Up != "CREATE TABLE a (id INTEGER)" || s.Down != "DROP TABLE a" {
+		t.Errorf("unexpected step %#v", s)
+	}
+}
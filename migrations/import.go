@@ -0,0 +1,102 @@
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Import copies the history of another migrations table into the migrations
+// table, e.g. one written by gosql.Migrator into the public schema or one
+// renamed to run both engines side by side. The table must have the columns
+// idx and statement. md5 (as UUID or hex) and created_at (as timestamp or
+// text) are optional, checksums are recalculated from the statements.
+// Migrations already found with the same statement are skipped, a different
+// statement for the same idx is an error. Returns the number of imported
+// migrations.
+func (list Migrations) Import(tx Con, from string) (int, error) {
+	if from == "migrations" {
+		return 0, fmt.Errorf("can not import the migrations table into itself")
+	}
+	if err := list.Setup(tx); err != nil {
+		return 0, err
+	}
+	history, err := readHistory(tx, from)
+	if err != nil {
+		return 0, err
+	}
+	existing, err := list.loadMigrationStatus(tx)
+	if err != nil {
+		return 0, err
+	}
+	d := list.dialect(tx)
+	cnt := 0
+	for _, m := range history {
+		if e, ok := existing[m.Idx]; ok {
+			if e.Statement != m.Statement {
+				return cnt, fmt.Errorf("MIGRATION MISMATCH:\n<<<<<<< %s migration %d\n%q\n=======\n%q\n>>>>>>> db migration\n", from, m.Idx, m.Statement, e.Statement)
+			}
+			continue
+		}
+		m.Logger, m.dialect = list.Logger, d
+		createdAt := m.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+		if err := m.record(tx, createdAt); err != nil {
+			return cnt, err
+		}
+		m.log("IMPORT", 0)
+		cnt++
+	}
+	return cnt, nil
+}
+
+func readHistory(con Con, table string) ([]*Migration, error) {
+	rows, err := con.Query("SELECT * FROM " + table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]bool{}
+	for _, c := range cols {
+		found[c] = true
+	}
+	for _, c := range []string{"idx", "statement"} {
+		if !found[c] {
+			return nil, fmt.Errorf("table %s has no column %s", table, c)
+		}
+	}
+	out := []*Migration{}
+	seen := map[int]bool{}
+	for rows.Next() {
+		m := &Migration{}
+		dest := make([]interface{}, len(cols))
+		for i, c := range cols {
+			switch c {
+			case "idx":
+				dest[i] = &m.Idx
+			case "statement":
+				dest[i] = &m.Statement
+			case "created_at":
+				dest[i] = (*scanTime)(&m.CreatedAt)
+			default:
+				dest[i] = new(interface{})
+			}
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		if seen[m.Idx] {
+			return nil, fmt.Errorf("there are two migrations with idx=%d in %s", m.Idx, table)
+		}
+		seen[m.Idx] = true
+		out = append(out, m)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Idx < out[b].Idx })
+	return out, rows.Err()
+}
@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dynport/dgtk/gosql/dialect"
+)
+
+func TestImport(t *testing.T) {
+	db := testSQLite(t)
+	defer db.Close()
+
+	for _, s := range []string{
+		"CREATE TABLE users (id INTEGER NOT NULL PRIMARY KEY)",
+		// layout written by postgres with md5 as UUID and created_at as text
+		"CREATE TABLE old_migrations (idx INTEGER PRIMARY KEY NOT NULL, md5 UUID NOT NULL, statement VARCHAR NOT NULL, created_at VARCHAR NOT NULL)",
+		"INSERT INTO old_migrations VALUES (1, 'a5e27b2c-3b11-4e6f-8d1e-2f8d1c3a9e01', 'CREATE TABLE users (id INTEGER NOT NULL PRIMARY KEY)', '2015-03-04T05:06:07.123456Z')",
+		"INSERT INTO old_migrations VALUES (2, 'ffe27b2c-3b11-4e6f-8d1e-2f8d1c3a9e01', 'SELECT 1', '2015-03-04T05:06:08Z')",
+	} {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	migs := New("CREATE TABLE users (id INTEGER NOT NULL PRIMARY KEY)", "SELECT 1", "SELECT 2")
+	migs.Dialect = dialect.SQLite
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := migs.Import(tx, "old_migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := migs.Import(tx, "old_migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	status, err := migs.Status(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 3 {
+		t.Fatalf("expected 3 status, got %d", len(status))
+	}
+	tests := map[int]struct{ Has, Want interface{} }{
+		1: {imported, 2},
+		2: {again, 0},
+		3: {status[0].State, StateExecuted},
+		4: {status[0].CreatedAt.Equal(time.Date(2015, 3, 4, 5, 6, 7, 123456000, time.UTC)), true},
+		5: {status[1].State, StateExecuted},
+		6: {status[2].State, StatePending},
+	}
+	for i, tc := range tests {
+		if tc.Has != tc.Want {
+			t.Errorf("%d: want=%#v has=%#v", i, tc.Want, tc.Has)
+		}
+	}
+	if err := migs.Execute(db); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec("INSERT INTO old_migrations VALUES (3, 'x', 'SELECT 3', '2015-03-04T05:06:09Z')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migs.Import(db, "old_migrations"); err == nil {
+		t.Errorf("expected mismatch error importing a different statement")
+	}
+}
@@ -0,0 +1,186 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/dynport/dgtk/gosql/dialect"
+)
+
+// Locker serializes migrations of processes starting at the same time. The
+// lock is taken inside the migration transaction and must be released when
+// the transaction ends.
+type Locker interface {
+	Lock(tx Tx, timeout time.Duration) error
+}
+
+// DefaultLockTimeout is used when Migrations.LockTimeout is not set.
+const DefaultLockTimeout = 1 * time.Minute
+
+var defaultAdvisoryLockKey = func() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("github.com/dynport/dgtk/migrations"))
+	return int64(h.Sum64())
+}()
+
+// AdvisoryLock uses a Postgres transaction level advisory lock. It is the
+// default Locker with Postgres.
+type AdvisoryLock struct {
+	Key          int64         // defaults to a key derived from the package name
+	PollInterval time.Duration // defaults to 100ms
+}
+
+func (l *AdvisoryLock) Lock(tx Tx, timeout time.Duration) error {
+	key := l.Key
+	if key == 0 {
+		key = defaultAdvisoryLockKey
+	}
+	interval := l.PollInterval
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+	started := time.Now()
+	for {
+		var locked bool
+		if err := tx.QueryRow("SELECT pg_try_advisory_xact_lock($1)", key).Scan(&locked); err != nil {
+			return err
+		}
+		if locked {
+			return nil
+		}
+		if time.Since(started) >= timeout {
+			return lockTimeoutError(timeout)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// LockSetup is implemented by Lockers which need to create objects before
+// the lock can be taken concurrently, e.g. the table of RowLock. Execute and
+// MigrateTo call SetupLock with the *sql.DB outside of the migration
+// transaction.
+type LockSetup interface {
+	SetupLock(con Con, timeout time.Duration) error
+}
+
+// RowLock locks a row in a dedicated table for databases without advisory
+// locks. The table and the row are created by SetupLock, or inside the
+// migration transaction when ExecuteTx is used directly. MySQL commits
+// implicitly on DDL statements, so there neither the lock nor the migrations
+// are transactional.
+type RowLock struct {
+	Table string // defaults to "migrations_lock"
+	// Dialect defaults to the dialect of the migrations with the default
+	// locker and to the dialect of the connection otherwise.
+	Dialect dialect.Dialect
+}
+
+type execContexter interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (l *RowLock) table() string {
+	if l.Table == "" {
+		return "migrations_lock"
+	}
+	return l.Table
+}
+
+// setupStatements create the table and row idempotently, concurrent inserts
+// of the row do not fail but wait for each other.
+func (l *RowLock) setupStatements(con Con) []string {
+	d := l.Dialect
+	if d == nil {
+		d = dialect.Of(con)
+	}
+	insert := "INSERT INTO " + l.table() + " (id) VALUES (1) ON CONFLICT DO NOTHING"
+	if d == dialect.MySQL {
+		insert = "INSERT IGNORE INTO " + l.table() + " (id) VALUES (1)"
+	}
+	return []string{
+		"CREATE TABLE IF NOT EXISTS " + l.table() + " (id INTEGER PRIMARY KEY NOT NULL, locked_at TIMESTAMP)",
+		insert,
+	}
+}
+
+func (l *RowLock) SetupLock(con Con, timeout time.Duration) error {
+	statements := l.setupStatements(con)
+	if err := execWithTimeout(con, timeout, statements[0]); err != nil {
+		// concurrent CREATE TABLE IF NOT EXISTS may fail on a unique index of
+		// the catalog (Postgres), the table exists when retrying
+		if err := execWithTimeout(con, timeout, statements[0]); err != nil {
+			return err
+		}
+	}
+	return execWithTimeout(con, timeout, statements[1:]...)
+}
+
+func (l *RowLock) Lock(tx Tx, timeout time.Duration) error {
+	statements := append(l.setupStatements(tx), "UPDATE "+l.table()+" SET locked_at = CURRENT_TIMESTAMP WHERE id = 1")
+	return execWithTimeout(tx, timeout, statements...)
+}
+
+// execWithTimeout executes the statements and returns a lock timeout error
+// when they do not finish within timeout.
+func execWithTimeout(con Con, timeout time.Duration, statements ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for _, s := range statements {
+		var err error
+		if ec, ok := con.(execContexter); ok {
+			_, err = ec.ExecContext(ctx, s)
+		} else {
+			_, err = con.Exec(s)
+		}
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
+			return lockTimeoutError(timeout)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lockTimeoutError(timeout time.Duration) error {
+	return fmt.Errorf("timeout after %s waiting for migration lock", timeout)
+}
+
+func (list Migrations) lockTimeout() time.Duration {
+	if list.LockTimeout <= 0 {
+		return DefaultLockTimeout
+	}
+	return list.LockTimeout
+}
+
+func (list Migrations) locker(con Con) Locker {
+	if list.Locker != nil {
+		return list.Locker
+	}
+	if d := list.dialect(con); d != dialect.Postgres {
+		return &RowLock{Dialect: d}
+	}
+	return &AdvisoryLock{}
+}
+
+// setupLock prepares the locker outside of the migration transaction.
+func (list Migrations) setupLock(db *sql.DB) error {
+	if s, ok := list.locker(db).(LockSetup); ok {
+		return s.SetupLock(db, list.lockTimeout())
+	}
+	return nil
+}
+
+func (list Migrations) lock(tx Tx) error {
+	started := time.Now()
+	if err := list.locker(tx).Lock(tx, list.lockTimeout()); err != nil {
+		return err
+	}
+	if list.Logger != nil {
+		list.Logger.Printf("acquired migration lock in %.06f", time.Since(started).Seconds())
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSchemaURL creates a new schema and returns a database url using it
+// together with a func dropping the schema.
+func testSchemaURL(t *testing.T) (string, func()) {
+	db, err := sql.Open("postgres", databaseURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("lock_test_%d", time.Now().UnixNano())
+	if _, err := db.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	sep := "?"
+	if strings.Contains(databaseURL(), "?") {
+		sep = "&"
+	}
+	return databaseURL() + sep + "search_path=" + schema, func() {
+		db.Exec("DROP SCHEMA " + schema + " CASCADE")
+		db.Close()
+	}
+}
+
+func TestConcurrentMigrators(t *testing.T) {
+	if os.Getenv("TEST_WITH_DB") != "true" {
+		t.SkipNow()
+	}
+	for _, locker := range []Locker{&AdvisoryLock{}, &RowLock{}} {
+		testConcurrentMigrators(t, locker)
+	}
+}
+
+// testConcurrentMigrators races migrators against an empty schema.
+func testConcurrentMigrators(t *testing.T, locker Locker) {
+	url, cleanup := testSchemaURL(t)
+	defer cleanup()
+
+	migs := New(
+		"CREATE TABLE users (id SERIAL NOT NULL PRIMARY KEY, name VARCHAR NOT NULL)",
+		"SELECT pg_sleep(0.2)",
+		insertUsers,
+	)
+	migs.Locker = locker
+	const migrators = 5
+	errs := make(chan error, migrators)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < migrators; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db, err := sql.Open("postgres", url)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer db.Close()
+			errs <- migs.Execute(db)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("%T: error migrating: %s", locker, err)
+		}
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var users, executed int
+	if err := db.QueryRow("SELECT COUNT(1) FROM users").Scan(&users); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("SELECT COUNT(1) FROM migrations").Scan(&executed); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[int]struct{ Has, Want interface{} }{
+		1: {users, 1},
+		2: {executed, 3},
+	}
+	for i, tc := range tests {
+		if tc.Has != tc.Want {
+			t.Errorf("%T %d: want=%#v has=%#v", locker, i, tc.Want, tc.Has)
+		}
+	}
+}
+
+func TestLockTimeout(t *testing.T) {
+	if os.Getenv("TEST_WITH_DB") != "true" {
+		t.SkipNow()
+	}
+	for _, locker := range []Locker{&AdvisoryLock{}, &RowLock{}} {
+		url, cleanup := testSchemaURL(t)
+		defer cleanup()
+		db, err := sql.Open("postgres", url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		holder, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the row lock table is created by the holder and not committed yet
+		if err := locker.Lock(holder, time.Second); err != nil {
+			t.Fatal(err)
+		}
+
+		migs := New("CREATE TABLE users (id SERIAL NOT NULL PRIMARY KEY)")
+		migs.Locker = locker
+		migs.LockTimeout = 200 * time.Millisecond
+		started := time.Now()
+		err = migs.Execute(db)
+		if err == nil || !strings.Contains(err.Error(), "timeout after 200ms waiting for migration lock") {
+			t.Errorf("%T: expected timeout error, got %v", locker, err)
+		}
+		if d := time.Since(started); d > 2*time.Second {
+			t.Errorf("%T: expected to give up after 200ms, took %s", locker, d)
+		}
+		holder.Rollback()
+		db.Close()
+	}
+}
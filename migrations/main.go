@@ -8,6 +8,8 @@ import (
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/dynport/dgtk/gosql/dialect"
 )
 
 type Con interface {
@@ -17,10 +19,6 @@ type Con interface {
 	Exec(query string, args ...interface{}) (sql.Result, error)
 }
 
-const createMigrationsSql = `
-  CREATE TABLE migrations (idx INTEGER PRIMARY KEY NOT NULL, md5 UUID NOT NULL, statement VARCHAR NOT NULL, created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL)
-`
-
 func New(migrations ...interface{}) *Migrations {
 	return &Migrations{steps: migrations}
 }
@@ -31,16 +29,53 @@ type logger interface {
 
 type Migrations struct {
 	Logger logger
-	steps  []interface{}
+	// Dialect defaults to the dialect detected from the driver of the
+	// *sql.DB passed to Execute and MigrateTo. A transaction passed to
+	// ExecuteTx or ExecuteTo does not expose its driver and is treated as
+	// Postgres (dialect.Default), including the advisory lock. Set Dialect
+	// when calling them with a MySQL or SQLite transaction.
+	Dialect dialect.Dialect
+	// Locker defaults to an AdvisoryLock with Postgres and a RowLock with
+	// all other dialects, LockTimeout to DefaultLockTimeout.
+	Locker      Locker
+	LockTimeout time.Duration
+	steps       []interface{}
 }
 
 func (list Migrations) Execute(db *sql.DB) error {
+	list.Dialect = list.dialect(db)
+	if err := list.setupLock(db); err != nil {
+		return err
+	}
+	return inTx(db, list.ExecuteTx)
+}
+
+func (list Migrations) dialect(con interface{}) dialect.Dialect {
+	if list.Dialect != nil {
+		return list.Dialect
+	}
+	return dialect.Of(con)
+}
+
+// MigrateTo executes all pending migrations up to idx or rolls back all
+// executed migrations after idx in a single transaction.
+func (list Migrations) MigrateTo(db *sql.DB, idx int) error {
+	list.Dialect = list.dialect(db)
+	if err := list.setupLock(db); err != nil {
+		return err
+	}
+	return inTx(db, func(tx Tx) error {
+		return list.ExecuteTo(tx, idx)
+	})
+}
+
+func inTx(db *sql.DB, f func(Tx) error) error {
 	tx, e := db.Begin()
 	if e != nil {
 		return e
 	}
 
-	e = list.ExecuteTx(tx)
+	e = f(tx)
 
 	if e != nil {
 		tx.Rollback()
@@ -55,9 +90,14 @@ type Tx interface {
 	Rollback() error
 }
 
+// ExecuteTx executes all pending migrations in tx. Set Dialect unless tx is
+// a Postgres transaction, see Migrations.Dialect.
 func (list Migrations) ExecuteTx(tx Tx) error {
 	started := time.Now()
-	if _, err := list.setup(tx); err != nil {
+	if err := list.lock(tx); err != nil {
+		return err
+	}
+	if err := list.Setup(tx); err != nil {
 		return err
 	}
 
@@ -76,9 +116,57 @@ func (list Migrations) ExecuteTx(tx Tx) error {
 	return nil
 }
 
-func (list Migrations) loadMigrationStatus(tx Tx) (m map[int]*Migration, err error) {
+// ExecuteTo migrates up or down to idx. Pending migrations up to idx are
+// executed in order, executed migrations after idx are rolled back in reverse
+// order. Rolling back fails for migrations without a Down step. Set Dialect
+// unless tx is a Postgres transaction, see Migrations.Dialect.
+func (list Migrations) ExecuteTo(tx Tx, idx int) error {
+	if idx < 0 || idx > len(list.steps) {
+		return fmt.Errorf("idx must be between 0 and %d, was %d", len(list.steps), idx)
+	}
+	started := time.Now()
+	if err := list.lock(tx); err != nil {
+		return err
+	}
+	if err := list.Setup(tx); err != nil {
+		return err
+	}
+	migrations, err := list.All(tx)
+	if err != nil {
+		return err
+	}
+	status, err := list.loadMigrationStatus(tx)
+	if err != nil {
+		return err
+	}
+	for i := range status {
+		if i > len(migrations) && i > idx {
+			return fmt.Errorf("migration %d is not defined and can not be rolled back", i)
+		}
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if m := migrations[i]; m.Executed && m.Idx > idx {
+			if err := m.Rollback(tx); err != nil {
+				return err
+			}
+		}
+	}
+	for _, m := range migrations {
+		if !m.Executed && m.Idx <= idx {
+			if err := m.Execute(tx); err != nil {
+				return err
+			}
+		}
+	}
+	if list.Logger != nil {
+		list.Logger.Printf("migrated to %d in %.06f", idx, time.Since(started).Seconds())
+	}
+	return nil
+}
+
+func (list Migrations) loadMigrationStatus(tx Con) (m map[int]*Migration, err error) {
 	m = map[int]*Migration{}
-	q := "SELECT idx, md5, statement FROM migrations ORDER BY idx"
+	q := "SELECT idx, md5, statement, created_at FROM migrations ORDER BY idx"
 	rows, err := tx.Query(q)
 	if err != nil {
 		return nil, err
@@ -87,7 +175,7 @@ func (list Migrations) loadMigrationStatus(tx Tx) (m map[int]*Migration, err err
 	for rows.Next() {
 		s := &Migration{}
 		var cs string
-		if err := rows.Scan(&s.Idx, &cs, &s.Statement); err != nil {
+		if err := rows.Scan(&s.Idx, &cs, &s.Statement, (*scanTime)(&s.CreatedAt)); err != nil {
 			return nil, err
 		}
 		s.MD5 = strings.Replace(cs, "-", "", -1)
@@ -108,8 +196,10 @@ type migrationStatus struct {
 	MD5       string
 }
 
-func (list Migrations) All(tx Tx) (out []*Migration, err error) {
-	status, err := list.loadMigrationStatus(tx)
+// All returns the defined migrations marked as executed when found in the
+// migrations table, which must exist (see Setup).
+func (list Migrations) All(con Con) (out []*Migration, err error) {
+	status, err := list.loadMigrationStatus(con)
 	if err != nil {
 		return nil, err
 	}
@@ -118,6 +208,7 @@ func (list Migrations) All(tx Tx) (out []*Migration, err error) {
 			return nil, err
 		} else {
 			m.Logger = list.Logger
+			m.dialect = list.dialect(con)
 			m.MD5 = strings.Replace(m.Statement, "-", "", -1)
 			executed, ok := status[m.Idx]
 			if ok {
@@ -125,6 +216,7 @@ func (list Migrations) All(tx Tx) (out []*Migration, err error) {
 					return nil, fmt.Errorf("MIGRATION MISMATCH:\n<<<<<<< code migration %d\n%q\n=======\n%q\n>>>>>>> db migration\n", m.Idx, m.Statement, executed.Statement)
 				}
 				m.Executed = true
+				m.CreatedAt = executed.CreatedAt
 			}
 			out = append(out, m)
 		}
@@ -132,44 +224,85 @@ func (list Migrations) All(tx Tx) (out []*Migration, err error) {
 	return out, nil
 }
 
+// Step is a migration which can be rolled back. Up and Down can be a
+// statement (string or fmt.Stringer) or a func(Con) error. Only Up is stored
+// in the migrations table, so a Down can be added to existing migrations.
+type Step struct {
+	Up   interface{}
+	Down interface{}
+}
+
 func newMigration(idx int, statement interface{}) (*Migration, error) {
 	m := &Migration{Idx: idx}
 
+	if statement == nil {
+		return nil, fmt.Errorf("migration %d is not defined", idx)
+	}
+	if step, ok := statement.(*Step); ok {
+		statement = step.Up
+		if step.Down != nil {
+			var err error
+			if m.Down, m.DownFunc, err = statementOrFunc(step.Down); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var err error
+	if m.Statement, m.Func, err = statementOrFunc(statement); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func statementOrFunc(statement interface{}) (string, func(Con) error, error) {
 	switch casted := statement.(type) {
 	case string:
-		m.Statement = casted
+		return casted, nil, nil
 	case fmt.Stringer:
-		m.Statement = casted.String()
+		return casted.String(), nil, nil
 	case func(Con) error:
-		m.Statement = runtime.FuncForPC(reflect.ValueOf(statement).Pointer()).Name()
-		m.Func = casted
+		return runtime.FuncForPC(reflect.ValueOf(statement).Pointer()).Name(), casted, nil
 	default:
-		return nil, fmt.Errorf("type %T not supported", casted)
+		return "", nil, fmt.Errorf("type %T not supported", casted)
 	}
-	return m, nil
 }
 
 type Migration struct {
 	Idx       int
 	Statement string
 	Func      func(Con) error
+	Down      string
+	DownFunc  func(Con) error
 	Logger    logger
 	MD5       string
 	Executed  bool
+	CreatedAt time.Time // when the migration was executed
+	dialect   dialect.Dialect
 }
 
-func (list Migrations) setup(tx Tx) (sql.Result, error) {
-	row := tx.QueryRow("SELECT COUNT(1) FROM pg_tables WHERE schemaname = $1 AND tablename = $2", "public", "migrations")
-	var cnt int
-	e := row.Scan(&cnt)
-	if e != nil {
-		return nil, e
+// Reversible returns true when the migration has a Down step.
+func (m *Migration) Reversible() bool {
+	return m.Down != "" || m.DownFunc != nil
+}
+
+// Setup creates the migrations table when it does not exist.
+func (list Migrations) Setup(con Con) error {
+	d := list.dialect(con)
+	exists, e := migrationsTableExists(con, d)
+	if e != nil || exists {
+		return e
 	}
+	_, e = con.Exec(d.MigrationsTable("migrations"))
+	return e
+}
 
-	if cnt == 0 {
-		return tx.Exec(createMigrationsSql)
+func migrationsTableExists(con Con, d dialect.Dialect) (bool, error) {
+	row := con.QueryRow(d.TableExistsQuery(), "migrations")
+	var cnt int
+	if e := row.Scan(&cnt); e != nil {
+		return false, e
 	}
-	return nil, nil
+	return cnt > 0, nil
 }
 
 func (m *Migration) log(t string, dur time.Duration) {
@@ -191,39 +324,127 @@ func (m *Migration) checksum() string {
 	return fmt.Sprintf("%x", md5.Sum([]byte(m.Statement)))
 }
 
-func (m *Migration) Execute(tx Tx) error {
-	rows, err := tx.Query("SELECT md5, statement FROM migrations where idx = $1", m.Idx)
+func (m *Migration) sql(con Con, q string) string {
+	d := m.dialect
+	if d == nil {
+		d = dialect.Of(con)
+	}
+	return dialect.Rebind(d, q)
+}
+
+// Execute executes the migration unless it is found in the migrations table.
+func (m *Migration) Execute(tx Con) error {
+	_, err := m.ExecuteResult(tx)
+	return err
+}
+
+// ExecuteResult is like Execute but also returns the result of Statement. The
+// result is nil when the migration was skipped or is executed by Func.
+func (m *Migration) ExecuteResult(tx Con) (sql.Result, error) {
+	rows, err := tx.Query(m.sql(tx, "SELECT md5, statement FROM migrations where idx = $1"), m.Idx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var cs, statement string
 		if err := rows.Scan(&cs, &statement); err != nil {
-			return err
+			return nil, err
 		}
 		cs = strings.Replace(cs, "-", "", -1)
 		if statement == m.Statement {
 			m.log("SKIP", 0)
-			return nil
+			return nil, nil
 		} else {
-			return fmt.Errorf("MIGRATION MISMATCH:\n<<<<<<< code migration %d\n%q\n=======\n%q\n>>>>>>> db migration\n", m.Idx, m.Statement, statement)
+			return nil, fmt.Errorf("MIGRATION MISMATCH:\n<<<<<<< code migration %d\n%q\n=======\n%q\n>>>>>>> db migration\n", m.Idx, m.Statement, statement)
 		}
 	}
 	started := time.Now()
+	var res sql.Result
 	if m.Func != nil {
 		if err := m.Func(tx); err != nil {
+			return nil, err
+		}
+	} else {
+		if res, err = tx.Exec(m.Statement); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.record(tx, time.Now()); err != nil {
+		return nil, err
+	}
+	m.log("EXEC", time.Since(started))
+	return res, nil
+}
+
+// record adds the migration to the migrations table.
+func (m *Migration) record(con Con, createdAt time.Time) error {
+	createdAt = createdAt.UTC()
+	_, err := con.Exec(m.sql(con, "INSERT INTO migrations (idx, md5, statement, created_at) VALUES ($1, $2, $3, $4)"), m.Idx, m.checksum(), m.Statement, createdAt)
+	if err != nil {
+		return err
+	}
+	m.Executed, m.CreatedAt = true, createdAt
+	return nil
+}
+
+// Rollback executes the Down step and removes the migration from the
+// migrations table.
+func (m *Migration) Rollback(tx Con) error {
+	if !m.Reversible() {
+		return fmt.Errorf("migration %d has no down step and can not be rolled back", m.Idx)
+	}
+	started := time.Now()
+	if m.DownFunc != nil {
+		if err := m.DownFunc(tx); err != nil {
 			return err
 		}
 	} else {
-		if _, err := tx.Exec(m.Statement); err != nil {
+		if _, err := tx.Exec(m.Down); err != nil {
 			return err
 		}
 	}
-	_, err = tx.Exec("INSERT INTO migrations (idx, md5, statement, created_at) VALUES ($1, $2, $3, $4)", m.Idx, m.checksum(), m.Statement, time.Now().UTC().Format(time.RFC3339Nano))
-	if err != nil {
+	if _, err := tx.Exec(m.sql(tx, "DELETE FROM migrations WHERE idx = $1"), m.Idx); err != nil {
 		return err
 	}
-	m.log("EXEC", time.Since(started))
-	return err
+	m.Executed = false
+	m.CreatedAt = time.Time{}
+	m.log("UNDO", time.Since(started))
+	return nil
+}
+
+// scanTime scans timestamps returned as time.Time or text, e.g. by MySQL
+// without parseTime=true.
+type scanTime time.Time
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+func (t *scanTime) Scan(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		*t = scanTime{}
+		return nil
+	case time.Time:
+		*t = scanTime(v)
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	}
+	return fmt.Errorf("unable to scan %T into time", v)
+}
+
+func (t *scanTime) parse(s string) error {
+	for _, l := range timeLayouts {
+		if parsed, err := time.Parse(l, s); err == nil {
+			*t = scanTime(parsed)
+			return nil
+		}
+	}
+	return fmt.Errorf("unable to parse time %q", s)
 }
@@ -89,7 +89,7 @@ func TestMigrations(t *testing.T) {
 	tx := testConnect(t)
 	defer tx.Rollback()
 	migs := New("CREATE TABLE users (id SERIAL NOT NULL PRIMARY KEY, email VARCHAR)", migFunc)
-	_, err := migs.setup(tx)
+	err := migs.Setup(tx)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -116,3 +116,106 @@ func TestMigrations(t *testing.T) {
 func migFunc(tx Con) error {
 	return nil
 }
+
+func deleteUsers(con Con) error {
+	_, err := con.Exec("DELETE FROM users WHERE name = $1", "Linux")
+	return err
+}
+
+func TestExecuteTo(t *testing.T) {
+	if os.Getenv("TEST_WITH_DB") != "true" {
+		t.SkipNow()
+	}
+	tx := testConnect(t)
+	defer tx.Rollback()
+	migs := New(
+		&Step{Up: "CREATE TABLE users (id SERIAL NOT NULL PRIMARY KEY, name VARCHAR NOT NULL)", Down: "DROP TABLE users"},
+		&Step{Up: insertUsers, Down: deleteUsers},
+		"CREATE INDEX users_name ON users (name)",
+	)
+	if err := migs.ExecuteTo(tx, 2); err != nil {
+		t.Fatal(err)
+	}
+	status, err := migs.Status(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[int]struct{ Has, Want interface{} }{
+		1: {len(status), 3},
+		2: {status[0].State, StateExecuted},
+		3: {status[1].State, StateExecuted},
+		4: {status[2].State, StatePending},
+		5: {status[0].CreatedAt.IsZero(), false},
+		6: {status[2].Reversible, false},
+	}
+	for i, tc := range tests {
+		if tc.Has != tc.Want {
+			t.Errorf("%d: want=%#v has=%#v", i, tc.Want, tc.Has)
+		}
+	}
+
+	if err := migs.ExecuteTo(tx, 0); err != nil {
+		t.Fatal(err)
+	}
+	var cnt int
+	if err := tx.QueryRow("SELECT COUNT(1) FROM pg_tables WHERE tablename = $1", "users").Scan(&cnt); err != nil {
+		t.Fatal(err)
+	}
+	if cnt != 0 {
+		t.Errorf("expected users table to be dropped")
+	}
+
+	if err := migs.ExecuteTo(tx, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := migs.ExecuteTo(tx, 1); err == nil {
+		t.Errorf("expected error rolling back migration without down step")
+	}
+}
+
+func TestStatusMismatch(t *testing.T) {
+	if os.Getenv("TEST_WITH_DB") != "true" {
+		t.SkipNow()
+	}
+	tx := testConnect(t)
+	defer tx.Rollback()
+	if err := New("CREATE TABLE a (id INTEGER)", "CREATE TABLE b (id INTEGER)").ExecuteTx(tx); err != nil {
+		t.Fatal(err)
+	}
+	status, err := New("CREATE TABLE a (id BIGINT)").Status(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[int]struct{ Has, Want interface{} }{
+		1: {len(status), 2},
+		2: {status[0].State, StateMismatch},
+		3: {status[1].State, StateUnknown},
+		4: {status[1].Idx, 2},
+	}
+	for i, tc := range tests {
+		if tc.Has != tc.Want {
+			t.Errorf("%d: want=%#v has=%#v", i, tc.Want, tc.Has)
+		}
+	}
+}
+
+func TestStep(t *testing.T) {
+	m, err := newMigration(1, &Step{Up: "CREATE TABLE users (id INTEGER)", Down: deleteUsers})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[int]struct{ Has, Want interface{} }{
+		1: {m.Statement, "CREATE TABLE users (id INTEGER)"},
+		2: {m.Down, "github.com/dynport/dgtk/migrations.deleteUsers"},
+		3: {m.DownFunc != nil, true},
+		4: {m.Reversible(), true},
+	}
+	for i, tc := range tests {
+		if tc.Has != tc.Want {
+			t.Errorf("%d: want=%#v has=%#v", i, tc.Want, tc.Has)
+		}
+	}
+	if _, err := newMigration(1, &Step{Up: "SELECT 1", Down: 1}); err == nil {
+		t.Errorf("expected error for invalid down step")
+	}
+}
@@ -0,0 +1,83 @@
+package migrations
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// testMySQL connects to TEST_MYSQL_URL, e.g.
+// "root@tcp(127.0.0.1:3306)/dgtk_migrations" (see docker-compose.yml).
+func testMySQL(t *testing.T) *sql.DB {
+	url := os.Getenv("TEST_MYSQL_URL")
+	if os.Getenv("TEST_WITH_DB") != "true" || url == "" {
+		t.SkipNow()
+	}
+	db, err := sql.Open("mysql", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("DROP TABLE IF EXISTS users, migrations, migrations_lock"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMySQL(t *testing.T) {
+	db := testMySQL(t)
+	defer db.Close()
+
+	migs := New(
+		&Step{
+			Up:   "CREATE TABLE users (id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT, name VARCHAR(255) NOT NULL)",
+			Down: "DROP TABLE users",
+		},
+		&Step{Up: "INSERT INTO users (name) VALUES ('Linux')", Down: "DELETE FROM users"},
+	)
+	if err := migs.Execute(db); err != nil {
+		t.Fatal(err)
+	}
+	// executing twice must skip all migrations
+	if err := migs.Execute(db); err != nil {
+		t.Fatal(err)
+	}
+	var users, locks int
+	if err := db.QueryRow("SELECT COUNT(1) FROM users").Scan(&users); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("SELECT COUNT(1) FROM migrations_lock").Scan(&locks); err != nil {
+		t.Fatal(err)
+	}
+	status, err := migs.Status(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 2 {
+		t.Fatalf("expected 2 status, got %d", len(status))
+	}
+	tests := map[int]struct{ Has, Want interface{} }{
+		1: {users, 1},
+		2: {locks, 1},
+		3: {status[0].State, StateExecuted},
+		4: {status[1].State, StateExecuted},
+		5: {status[1].CreatedAt.IsZero(), false},
+	}
+	for i, tc := range tests {
+		if tc.Has != tc.Want {
+			t.Errorf("%d: want=%#v has=%#v", i, tc.Want, tc.Has)
+		}
+	}
+
+	if err := migs.MigrateTo(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(1) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'users'").Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Errorf("expected users table to be dropped")
+	}
+}
@@ -0,0 +1,120 @@
+package migrations
+
+import (
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func testSQLite(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSQLite(t *testing.T) {
+	db := testSQLite(t)
+	defer db.Close()
+
+	migs := New(
+		&Step{
+			Up:   "CREATE TABLE users (id INTEGER NOT NULL PRIMARY KEY, name VARCHAR NOT NULL)",
+			Down: "DROP TABLE users",
+		},
+		&Step{Up: insertUsers, Down: "DELETE FROM users"},
+	)
+	if err := migs.Execute(db); err != nil {
+		t.Fatal(err)
+	}
+	// executing twice must skip all migrations
+	if err := migs.Execute(db); err != nil {
+		t.Fatal(err)
+	}
+	var users int
+	if err := db.QueryRow("SELECT COUNT(1) FROM users").Scan(&users); err != nil {
+		t.Fatal(err)
+	}
+	status, err := migs.Status(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 2 {
+		t.Fatalf("expected 2 status, got %d", len(status))
+	}
+	tests := map[int]struct{ Has, Want interface{} }{
+		1: {users, 1},
+		2: {status[0].State, StateExecuted},
+		3: {status[1].State, StateExecuted},
+		4: {status[1].CreatedAt.IsZero(), false},
+	}
+	for i, tc := range tests {
+		if tc.Has != tc.Want {
+			t.Errorf("%d: want=%#v has=%#v", i, tc.Want, tc.Has)
+		}
+	}
+
+	if err := migs.MigrateTo(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(1) FROM sqlite_master WHERE name = 'users'").Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Errorf("expected users table to be dropped")
+	}
+}
+
+func TestConcurrentMigratorsSQLite(t *testing.T) {
+	url := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000&_txlock=immediate"
+	migs := New("CREATE TABLE users (id INTEGER NOT NULL PRIMARY KEY, name VARCHAR NOT NULL)", insertUsers)
+	const migrators = 2
+	errs := make(chan error, migrators)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < migrators; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db, err := sql.Open("sqlite3", url)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer db.Close()
+			errs <- migs.Execute(db)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("error migrating: %s", err)
+		}
+	}
+	db, err := sql.Open("sqlite3", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var users, locks int
+	if err := db.QueryRow("SELECT COUNT(1) FROM users").Scan(&users); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("SELECT COUNT(1) FROM migrations_lock").Scan(&locks); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[int]struct{ Has, Want interface{} }{
+		1: {users, 1},
+		2: {locks, 1},
+	}
+	for i, tc := range tests {
+		if tc.Has != tc.Want {
+			t.Errorf("%d: want=%#v has=%#v", i, tc.Want, tc.Has)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// State of a migration compared to the migrations table.
+type State string
+
+const (
+	StatePending  State = "pending"
+	StateExecuted State = "executed"
+	StateMismatch State = "mismatch" // executed with a different statement
+	StateUnknown  State = "unknown"  // executed but not defined anymore
+)
+
+type Status struct {
+	Idx        int
+	State      State
+	Statement  string
+	CreatedAt  time.Time // zero for pending migrations
+	Reversible bool
+}
+
+// Status compares the defined migrations with the migrations table. Unlike
+// All it does not fail on mismatches and does not create the table.
+func (list Migrations) Status(con Con) ([]*Status, error) {
+	exists, err := migrationsTableExists(con, list.dialect(con))
+	if err != nil {
+		return nil, err
+	}
+	executed := map[int]*Migration{}
+	if exists {
+		if executed, err = list.loadMigrationStatus(con); err != nil {
+			return nil, err
+		}
+	}
+	out := []*Status{}
+	for i, step := range list.steps {
+		m, err := newMigration(i+1, step)
+		if err != nil {
+			return nil, err
+		}
+		s := &Status{Idx: m.Idx, State: StatePending, Statement: m.Statement, Reversible: m.Reversible()}
+		if e, ok := executed[m.Idx]; ok {
+			s.State, s.CreatedAt = StateExecuted, e.CreatedAt
+			if e.Statement != m.Statement {
+				s.State = StateMismatch
+			}
+			delete(executed, m.Idx)
+		}
+		out = append(out, s)
+	}
+	unknown := []*Status{}
+	for _, e := range executed {
+		unknown = append(unknown, &Status{Idx: e.Idx, State: StateUnknown, Statement: e.Statement, CreatedAt: e.CreatedAt})
+	}
+	sort.Slice(unknown, func(a, b int) bool { return unknown[a].Idx < unknown[b].Idx })
+	return append(out, unknown...), nil
+}
+
+func (s *Status) String() string {
+	createdAt := "-"
+	if !s.CreatedAt.IsZero() {
+		createdAt = s.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprintf("%d %s %s %q", s.Idx, s.State, createdAt, s.Statement)
+}
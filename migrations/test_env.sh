@@ -15,5 +15,12 @@ if [[ -z $port ]]; then
   abort "unable to get port of database"
 fi
 
+mysql_port=$(docker-compose port mysql_test 3306 | cut -d ":" -f 2)
+
+if [[ -z $mysql_port ]]; then
+  abort "unable to get port of mysql"
+fi
+
 echo "export TEST_WITH_DB=true"
 echo "export TEST_DATABSE_URL=postgres://postgres@127.0.0.1:${port}/dgtk_migrations?sslmode=disable"
+echo "export TEST_MYSQL_URL='root@tcp(127.0.0.1:${mysql_port})/dgtk_migrations'"
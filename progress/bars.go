@@ -0,0 +1,159 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IsTerminal returns true when f is a character device, e.g. when stdout is
+// not redirected to a file or pipe.
+func IsTerminal(f *os.File) bool {
+	stat, e := f.Stat()
+	if e != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// NewBars creates a container for progress bars writing to w. Bars are
+// redrawn in place when w is a terminal and printed as log lines otherwise.
+func NewBars(w io.Writer) *Bars {
+	b := &Bars{Writer: w}
+	if f, ok := w.(*os.File); ok {
+		b.Terminal = IsTerminal(f)
+	}
+	return b
+}
+
+// Bars renders one line per progress bar. Use Bar to get a Printer for every
+// Progress, e.g. one per parallel upload.
+type Bars struct {
+	Writer        io.Writer
+	Terminal      bool          // redraw bars in place
+	Width         int           // width of the bar, defaults to 40
+	HumanReadable bool          // print sizes with SizePretty
+	LogInterval   time.Duration // min time between log lines of a bar when not on a terminal, defaults to 10s
+
+	mutex sync.Mutex
+	bars  []*bar
+	drawn int
+}
+
+// Bar adds a bar with the given name to the container.
+func (b *Bars) Bar(name string) Printer {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	br := &bar{name: name, bars: b}
+	b.bars = append(b.bars, br)
+	return br
+}
+
+type bar struct {
+	name    string
+	bars    *Bars
+	status  *Status
+	printed time.Time
+}
+
+func (br *bar) Print(s *Status) {
+	b := br.bars
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	br.status = s
+	if b.Terminal {
+		b.redraw()
+		return
+	}
+	interval := b.LogInterval
+	if interval == 0 {
+		interval = 10 * time.Second
+	}
+	if !s.Finished && !br.printed.IsZero() && s.Now.Sub(br.printed) < interval {
+		return
+	}
+	br.printed = s.Now
+	line := statusToString(s, b.HumanReadable)
+	if br.name != "" {
+		line = br.name + ": " + line
+	}
+	fmt.Fprintln(b.writer(), line)
+}
+
+func (b *Bars) writer() io.Writer {
+	if b.Writer == nil {
+		return os.Stdout
+	}
+	return b.Writer
+}
+
+// redraw moves the cursor to the first line drawn before and rewrites all
+// bars.
+func (b *Bars) redraw() {
+	out := ""
+	if b.drawn > 0 {
+		out += fmt.Sprintf("\x1b[%dA", b.drawn)
+	}
+	nameWidth := 0
+	for _, br := range b.bars {
+		if len(br.name) > nameWidth {
+			nameWidth = len(br.name)
+		}
+	}
+	for _, br := range b.bars {
+		out += "\r\x1b[2K" + b.line(br, nameWidth) + "\n"
+	}
+	b.drawn = len(b.bars)
+	fmt.Fprint(b.writer(), out)
+}
+
+func (b *Bars) line(br *bar, nameWidth int) string {
+	s := br.status
+	out := ""
+	if nameWidth > 0 {
+		out = fmt.Sprintf("%-*s ", nameWidth, br.name)
+	}
+	if s == nil {
+		return out + "waiting"
+	}
+	width := b.Width
+	if width <= 0 {
+		width = 40
+	}
+	size := func(i int) string {
+		if b.HumanReadable {
+			return SizePretty(float64(i))
+		}
+		return fmt.Sprintf("%d", i)
+	}
+	if s.Total != nil {
+		perc := s.Fraction()
+		full := int(perc * float64(width))
+		head := ""
+		if full < width {
+			head = ">"
+		}
+		out += "[" + strings.Repeat("=", full) + head + strings.Repeat(" ", width-full-len(head)) + "]"
+		out += fmt.Sprintf(" %5.1f%% %s/%s", 100*perc, size(s.Current), size(*s.Total))
+	} else {
+		out += size(s.Current)
+	}
+	out += " " + size(int(s.CurrentRate())) + "/s"
+	switch {
+	case s.Finished:
+		out += " done in " + s.RunningSince().Truncate(time.Second).String()
+	case s.Total != nil:
+		if eta := s.ETA(); eta != nil {
+			out += " eta " + eta.Truncate(time.Second).String()
+		} else {
+			out += " eta ?"
+		}
+	}
+	if s.Message != "" {
+		out += " " + s.Message
+	}
+	return out
+}
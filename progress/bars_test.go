@@ -0,0 +1,61 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testStatus(current, total int, finished bool) *Status {
+	now := time.Date(2017, 1, 1, 0, 0, 10, 0, time.UTC)
+	return &Status{Started: now.Add(-10 * time.Second), Now: now, Current: current, Total: &total, Finished: finished}
+}
+
+func TestBarsTerminal(t *testing.T) {
+	buf := &bytes.Buffer{}
+	bars := &Bars{Writer: buf, Terminal: true, Width: 10, HumanReadable: true}
+	a := bars.Bar("a")
+	b := bars.Bar("bb")
+
+	a.Print(testStatus(512, 1024, false))
+	out := buf.String()
+	for _, s := range []string{"a  [=====>    ]  50.0% 512B/1.00KB 51B/s eta 10s\n", "bb waiting\n"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q to contain %q", out, s)
+		}
+	}
+	if strings.Contains(out, "\x1b[2A") {
+		t.Errorf("did not expect cursor movement on first draw: %q", out)
+	}
+
+	buf.Reset()
+	b.Print(testStatus(2048, 2048, true))
+	out = buf.String()
+	for _, s := range []string{"\x1b[2A", "bb [==========] 100.0% 2.00KB/2.00KB 204B/s done in 10s\n"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q to contain %q", out, s)
+		}
+	}
+}
+
+func TestBarsLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	bars := NewBars(buf)
+	if bars.Terminal {
+		t.Fatal("expected buffer not to be a terminal")
+	}
+	a := bars.Bar("a")
+	a.Print(testStatus(1, 10, false))
+	a.Print(testStatus(2, 10, false))
+	a.Print(testStatus(10, 10, true))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), lines)
+	}
+	for i, s := range []string{"a: cnt=01/10", "a: cnt=10/10"} {
+		if !strings.HasPrefix(lines[i], s) {
+			t.Errorf("expected line %d (%q) to start with %q", i, lines[i], s)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event is the machine readable form of a Status.
+type Event struct {
+	Name           string    `json:"name,omitempty"`
+	Time           time.Time `json:"time"`
+	Started        time.Time `json:"started"`
+	RunningSeconds float64   `json:"running_seconds"`
+	Current        int       `json:"current"`
+	Total          *int      `json:"total,omitempty"`
+	Fraction       *float64  `json:"fraction,omitempty"`
+	PerSecond      float64   `json:"per_second"`
+	Rate           float64   `json:"rate"`
+	ETASeconds     *float64  `json:"eta_seconds,omitempty"`
+	Finished       bool      `json:"finished"`
+	Message        string    `json:"message,omitempty"`
+	MemAlloc       uint64    `json:"mem_alloc,omitempty"`
+	MemSys         uint64    `json:"mem_sys,omitempty"`
+	NumGC          uint32    `json:"num_gc,omitempty"`
+}
+
+// Event converts the status into an Event with the given name.
+func (s *Status) Event(name string) *Event {
+	e := &Event{
+		Name:           name,
+		Time:           s.Now,
+		Started:        s.Started,
+		RunningSeconds: s.RunningSince().Seconds(),
+		Current:        s.Current,
+		Total:          s.Total,
+		PerSecond:      s.PerSecond(),
+		Rate:           s.CurrentRate(),
+		Finished:       s.Finished,
+		Message:        s.Message,
+		MemAlloc:       s.MemStats.Alloc,
+		MemSys:         s.MemStats.Sys,
+		NumGC:          s.MemStats.NumGC,
+	}
+	if s.Total != nil {
+		f := s.Fraction()
+		e.Fraction = &f
+	}
+	if eta := s.ETA(); eta != nil {
+		secs := eta.Seconds()
+		e.ETASeconds = &secs
+	}
+	return e
+}
+
+// JSONPrinter writes one JSON encoded Event per line, e.g. for wrappers
+// following the progress of a job.
+type JSONPrinter struct {
+	Name   string
+	Writer io.Writer // defaults to os.Stdout
+
+	mutex sync.Mutex
+}
+
+func (p *JSONPrinter) Print(s *Status) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	w := p.Writer
+	if w == nil {
+		w = os.Stdout
+	}
+	json.NewEncoder(w).Encode(s.Event(p.Name))
+}
@@ -0,0 +1,133 @@
+package progress
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// NewMetrics creates a collector for the status of named progresses.
+func NewMetrics() *Metrics {
+	return &Metrics{events: map[string]*Event{}}
+}
+
+// Metrics keeps the latest status of every progress printing to one of its
+// printers. It serves them in the Prometheus text format and can publish them
+// with expvar:
+//
+//	m := progress.NewMetrics()
+//	if err := m.PublishExpvar("progress"); err != nil {
+//		...
+//	}
+//	http.Handle("/metrics", m)
+//	p := progress.Start(l, progress.WithPrinter(m.Printer("import")))
+type Metrics struct {
+	mutex  sync.Mutex
+	events map[string]*Event
+}
+
+// Printer returns a Printer storing the status under name.
+func (m *Metrics) Printer(name string) Printer {
+	return &metricsPrinter{name: name, metrics: m}
+}
+
+type metricsPrinter struct {
+	name    string
+	metrics *Metrics
+}
+
+func (p *metricsPrinter) Print(s *Status) {
+	p.metrics.mutex.Lock()
+	defer p.metrics.mutex.Unlock()
+	p.metrics.events[p.name] = s.Event(p.name)
+}
+
+// Events returns the latest events sorted by name.
+func (m *Metrics) Events() []*Event {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	list := make([]*Event, 0, len(m.events))
+	for _, e := range m.events {
+		c := *e
+		list = append(list, &c)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].Name < list[b].Name })
+	return list
+}
+
+var expvarMutex sync.Mutex
+
+// PublishExpvar publishes the events as expvar variable name (served on
+// /debug/vars of http.DefaultServeMux). It returns an error when a variable
+// with name was already published, expvar.Publish would panic.
+func (m *Metrics) PublishExpvar(name string) error {
+	expvarMutex.Lock()
+	defer expvarMutex.Unlock()
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("expvar %q already published", name)
+	}
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		out := map[string]*Event{}
+		for _, e := range m.Events() {
+			out[e.Name] = e
+		}
+		return out
+	}))
+	return nil
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type gauge struct {
+	name  string
+	help  string
+	value func(*Event) (float64, bool)
+}
+
+var gauges = []gauge{
+	{"progress_current", "Current count of the progress.", func(e *Event) (float64, bool) { return float64(e.Current), true }},
+	{"progress_total", "Expected total of the progress.", func(e *Event) (float64, bool) {
+		if e.Total == nil {
+			return 0, false
+		}
+		return float64(*e.Total), true
+	}},
+	{"progress_rate", "Moving average rate per second.", func(e *Event) (float64, bool) { return e.Rate, true }},
+	{"progress_eta_seconds", "Estimated seconds until the total is reached.", func(e *Event) (float64, bool) {
+		if e.ETASeconds == nil {
+			return 0, false
+		}
+		return *e.ETASeconds, true
+	}},
+	{"progress_running_seconds", "Seconds since the progress was started.", func(e *Event) (float64, bool) { return e.RunningSeconds, true }},
+	{"progress_finished", "1 when the progress was closed.", func(e *Event) (float64, bool) {
+		if e.Finished {
+			return 1, true
+		}
+		return 0, true
+	}},
+	{"progress_mem_alloc_bytes", "runtime.MemStats.Alloc at the last status.", func(e *Event) (float64, bool) { return float64(e.MemAlloc), e.MemAlloc > 0 }},
+	{"progress_mem_sys_bytes", "runtime.MemStats.Sys at the last status.", func(e *Event) (float64, bool) { return float64(e.MemSys), e.MemSys > 0 }},
+}
+
+// ServeHTTP writes all gauges in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	events := m.Events()
+	for _, g := range gauges {
+		lines := []string{}
+		for _, e := range events {
+			if v, ok := g.value(e); ok {
+				lines = append(lines, fmt.Sprintf("%s{name=\"%s\"} %s", g.name, labelEscaper.Replace(e.Name), strconv.FormatFloat(v, 'g', -1, 64)))
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s\n", g.name, g.help, g.name, strings.Join(lines, "\n"))
+	}
+}
@@ -0,0 +1,67 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	m.Printer("import").Print(testStatus(5, 10, false))
+	m.Printer(`say "hi"`).Print(&Status{Current: 1})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, s := range []string{
+		"# TYPE progress_current gauge\n",
+		`progress_current{name="import"} 5` + "\n",
+		`progress_current{name="say \"hi\""} 1` + "\n",
+		`progress_total{name="import"} 10` + "\n",
+		`progress_eta_seconds{name="import"} 10` + "\n",
+		`progress_finished{name="import"} 0` + "\n",
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("expected %q to contain %q", body, s)
+		}
+	}
+	if strings.Contains(body, `progress_total{name="say`) {
+		t.Errorf("did not expect a total for a progress without total: %q", body)
+	}
+}
+
+func TestPublishExpvar(t *testing.T) {
+	m := NewMetrics()
+	m.Printer("import").Print(testStatus(5, 10, false))
+	if err := m.PublishExpvar("progress_test"); err != nil {
+		t.Fatal(err)
+	}
+	if v := expvar.Get("progress_test"); v == nil || !strings.Contains(v.String(), `"import"`) {
+		t.Errorf("expected published events to contain import, got %v", v)
+	}
+	if err := NewMetrics().PublishExpvar("progress_test"); err == nil {
+		t.Errorf("expected error publishing the same name twice")
+	}
+}
+
+func TestJSONPrinter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := &JSONPrinter{Name: "import", Writer: buf}
+	p.Print(testStatus(5, 10, false))
+	p.Print(testStatus(10, 10, true))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	e := &Event{}
+	if err := json.Unmarshal([]byte(lines[1]), e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Name != "import" || e.Current != 10 || *e.Total != 10 || !e.Finished || *e.Fraction != 1 {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
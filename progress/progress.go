@@ -1,10 +1,14 @@
 package progress
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -34,25 +38,78 @@ func WithPrinter(printer Printer) StartFunc {
 	}
 }
 
+// WithInterval sets how often the status is printed (defaults to 1s).
+func WithInterval(d time.Duration) StartFunc {
+	return func(p *Progress) {
+		if d > 0 {
+			p.interval = d
+		}
+	}
+}
+
+// WithRateWindow sets the time span used for the moving average rate
+// (defaults to 10s).
+func WithRateWindow(d time.Duration) StartFunc {
+	return func(p *Progress) {
+		if d > 0 {
+			p.window = d
+		}
+	}
+}
+
+// WithoutMemStats skips reading runtime.MemStats for every Status, which
+// stops the world.
+func WithoutMemStats(p *Progress) {
+	p.skipMemStats = true
+}
+
+// WithContext closes the progress when ctx is done.
+func WithContext(ctx context.Context) StartFunc {
+	return func(p *Progress) {
+		p.ctx = ctx
+	}
+}
+
 type Progress struct {
 	total         int
 	humanReadable bool
-	current       int
+	current       atomic.Int64
 	started       time.Time
 	printer       Printer
+	interval      time.Duration
+	window        time.Duration
+	ctx           context.Context
+	skipMemStats  bool
 
-	inc      chan int
+	mutex    sync.Mutex
+	samples  []sample
+	message  string
 	closer   chan struct{}
 	closed   chan struct{}
 	isClosed bool
 }
 
+type sample struct {
+	at      time.Time
+	current int
+}
+
 func newProgress() *Progress {
-	return &Progress{started: time.Now().UTC()}
+	return &Progress{started: time.Now().UTC(), interval: 1 * time.Second, window: 10 * time.Second}
+}
+
+// New creates a progress without starting it. Without calling Start it can
+// be used to count and calculate the Status only.
+func New(funcs ...StartFunc) *Progress {
+	p := newProgress()
+	for _, f := range funcs {
+		f(p)
+	}
+	return p
 }
 
 func (p *Progress) Write(b []byte) (int, error) {
-	p.inc <- len(b)
+	p.IncBy(len(b))
 	return len(b), nil
 }
 
@@ -68,9 +125,9 @@ func (p *LogPrinter) Print(s *Status) {
 func statusToString(status *Status, humanReadable bool) string {
 	perSecond := func() string {
 		if humanReadable {
-			return SizePretty(status.PerSecond())
+			return SizePretty(status.CurrentRate())
 		} else {
-			return fmt.Sprintf("%.01f", status.PerSecond())
+			return fmt.Sprintf("%.01f", status.CurrentRate())
 		}
 	}()
 	s := fmt.Sprintf("total_time=%.06f per_second=%s", status.RunningSince().Seconds(), perSecond)
@@ -82,39 +139,80 @@ func statusToString(status *Status, humanReadable bool) string {
 			}
 			return fmt.Sprintf("cnt=%0*d/%0*d", l, status.Current, l, *status.Total)
 		}()
-		s = prefix + " " + s + fmt.Sprintf(" eta=%.01f", status.ETA().Seconds())
+		s = prefix + " " + s + fmt.Sprintf(" eta=%.01f", etaSeconds(status))
 	} else {
 		s = fmt.Sprintf("cnt=%d ", status.Current) + s
 	}
 	if status.MemStats.Alloc > 0 {
 		s += fmt.Sprintf(" mem_alloc=%s", SizePretty(float64(status.MemStats.Alloc)))
 	}
+	if status.Message != "" {
+		s += " " + status.Message
+	}
 	return s
 }
 
+func etaSeconds(s *Status) float64 {
+	if eta := s.ETA(); eta != nil {
+		return eta.Seconds()
+	}
+	return 0
+}
+
 func Start(l Logger, funcs ...func(*Progress)) *Progress {
 	p := newProgress()
 	for _, f := range funcs {
 		f(p)
 	}
-	p.printer = &LogPrinter{Logger: l, HumanReadable: p.humanReadable}
+	if p.printer == nil {
+		p.printer = &LogPrinter{Logger: l, HumanReadable: p.humanReadable}
+	}
 	p.Start()
 	return p
 }
 
 func (p *Progress) Inc() {
-	p.inc <- 1
+	p.IncBy(1)
 }
 
 func (p *Progress) IncBy(i int) {
-	p.inc <- i
+	p.current.Add(int64(i))
+}
+
+// Current returns the current count.
+func (p *Progress) Current() int {
+	return int(p.current.Load())
+}
+
+// SetMessage sets a message printed with every status, e.g. the name of the
+// file currently processed.
+func (p *Progress) SetMessage(m string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.message = m
+}
+
+// WrapReader returns a reader counting all bytes read from r.
+func (p *Progress) WrapReader(r io.Reader) io.Reader {
+	return &countingReader{Reader: r, p: p}
+}
+
+// WrapWriter returns a writer counting all bytes written to w.
+func (p *Progress) WrapWriter(w io.Writer) io.Writer {
+	return &countingWriter{Writer: w, p: p}
 }
 
 func (p *Progress) Reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
 	p.total = 0
-	p.current = 0
+	p.current.Store(0)
 	p.started = time.Now().UTC()
-	p.isClosed = false
+	p.samples = nil
+	if p.isClosed {
+		// allows to Start the progress again
+		p.closer, p.isClosed = nil, false
+	}
 }
 
 func (p *Progress) Diff() time.Duration {
@@ -122,29 +220,46 @@ func (p *Progress) Diff() time.Duration {
 }
 
 func (p *Progress) Start() {
-	dur := 1 * time.Second
 	printer := p.printer
 	if printer == nil {
 		printer = &LogPrinter{Logger: DefaultLogger}
 	}
-	t := time.Tick(dur)
-	p.closer = make(chan struct{})
-	p.closed = make(chan struct{})
-	p.inc = make(chan int)
+	interval := p.interval
+	if interval <= 0 {
+		interval = 1 * time.Second
+	}
+	var done <-chan struct{}
+	if p.ctx != nil {
+		done = p.ctx.Done()
+	}
+	p.mutex.Lock()
+	closer, closed := make(chan struct{}), make(chan struct{})
+	p.closer, p.closed = closer, closed
+	p.samples = []sample{{at: time.Now(), current: p.Current()}}
+	p.mutex.Unlock()
 	go func() {
-		defer close(p.closed)
+		defer close(closed)
+		t := time.NewTicker(interval)
+		defer t.Stop()
 		printedMax := false
+		printFinished := func() {
+			s := p.Status()
+			s.Finished = true
+			printer.Print(s)
+		}
 		for {
 			select {
-			case i := <-p.inc:
-				p.current += i
-			case <-t:
+			case <-t.C:
+				p.sample()
 				if !printedMax {
 					printer.Print(p.Status())
 				}
-				printedMax = p.total > 0 && p.current >= p.total
-			case <-p.closer:
-				printer.Print(p.Status())
+				printedMax = p.reachedTotal()
+			case <-closer:
+				printFinished()
+				return
+			case <-done:
+				printFinished()
 				return
 			}
 		}
@@ -152,25 +267,90 @@ func (p *Progress) Start() {
 	return
 }
 
+// Done returns a channel which is closed once the progress printed its
+// final status, either because Close was called or its context is done.
+func (p *Progress) Done() <-chan struct{} {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.closed
+}
+
+// Close prints the final status. It can be called more than once and also
+// after the context of the progress is done.
 func (p *Progress) Close() error {
-	if p.isClosed {
+	p.mutex.Lock()
+	if p.isClosed || p.closer == nil {
+		p.mutex.Unlock()
 		return nil
 	}
-	p.closer <- struct{}{}
-	<-p.closed
 	p.isClosed = true
+	closer, closed := p.closer, p.closed
+	p.mutex.Unlock()
+	close(closer)
+	<-closed
 	return nil
 }
 
+func (p *Progress) reachedTotal() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.total > 0 && p.Current() >= p.total
+}
+
+func (p *Progress) sample() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	now := time.Now()
+	p.samples = append(p.samples, sample{at: now, current: p.Current()})
+	// keep the newest sample older than the window as starting point
+	for len(p.samples) > 1 && now.Sub(p.samples[1].at) >= p.window {
+		p.samples = p.samples[1:]
+	}
+}
+
 func (p *Progress) Status() *Status {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
 	s := &Status{
-		Current: p.current,
+		Current: p.Current(),
 		Started: p.started,
 		Now:     time.Now(),
+		Message: p.message,
+	}
+	if !p.skipMemStats {
+		runtime.ReadMemStats(&s.MemStats)
 	}
-	runtime.ReadMemStats(&s.MemStats)
 	if p.total > 0 {
-		s.Total = &p.total
+		total := p.total
+		s.Total = &total
+	}
+	if len(p.samples) > 0 {
+		first := p.samples[0]
+		if secs := s.Now.Sub(first.at).Seconds(); secs > 0 {
+			s.Rate = float64(s.Current-first.current) / secs
+		}
 	}
 	return s
 }
+
+type countingReader struct {
+	io.Reader
+	p *Progress
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, e := r.Reader.Read(b)
+	r.p.IncBy(n)
+	return n, e
+}
+
+type countingWriter struct {
+	io.Writer
+	p *Progress
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, e := w.Writer.Write(b)
+	w.p.IncBy(n)
+	return n, e
+}
@@ -1,10 +1,13 @@
 package progress
 
 import (
+	"context"
 	"io/ioutil"
 	"log"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestProgressString(t *testing.T) {
@@ -31,3 +34,95 @@ func TestProgressString(t *testing.T) {
 		}
 	}
 }
+
+type recordingPrinter struct {
+	mutex    sync.Mutex
+	statuses []*Status
+}
+
+func (r *recordingPrinter) Print(s *Status) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.statuses = append(r.statuses, s)
+}
+
+func TestWrapReaderAndWriter(t *testing.T) {
+	p := New(WithTotal(8))
+	r := p.WrapReader(strings.NewReader("hello"))
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := p.WrapWriter(ioutil.Discard)
+	if _, err := w.Write([]byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello" || p.Current() != 8 {
+		t.Errorf("expected to read %q and count 8, got %q and %d", "hello", b, p.Current())
+	}
+	if f := p.Status().Fraction(); f != 1 {
+		t.Errorf("expected fraction to be 1, was %v", f)
+	}
+}
+
+func TestProgressContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	printer := &recordingPrinter{}
+	p := New(WithContext(ctx), WithPrinter(printer), WithInterval(time.Hour))
+	p.Start()
+	p.IncBy(3)
+	cancel()
+	select {
+	case <-p.Done():
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for progress to finish")
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	printer.mutex.Lock()
+	defer printer.mutex.Unlock()
+	if len(printer.statuses) != 1 {
+		t.Fatalf("expected 1 status, got %d", len(printer.statuses))
+	}
+	if s := printer.statuses[0]; !s.Finished || s.Current != 3 {
+		t.Errorf("expected finished status with current=3, got %+v", s)
+	}
+}
+
+func TestProgressResetWhileRunning(t *testing.T) {
+	p := New(WithTotal(10), WithPrinter(&recordingPrinter{}), WithInterval(time.Millisecond))
+	p.Start()
+	for i := 0; i < 20; i++ {
+		p.IncBy(10)
+		p.Reset()
+		time.Sleep(time.Millisecond)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMovingAverageRate(t *testing.T) {
+	p := New(WithRateWindow(time.Second))
+	now := time.Now()
+	p.samples = []sample{
+		{at: now.Add(-30 * time.Second), current: 0},
+		{at: now.Add(-2 * time.Second), current: 100},
+		{at: now.Add(-1 * time.Second), current: 110},
+	}
+	p.started = now.Add(-30 * time.Second)
+	p.IncBy(120)
+	p.sample()
+	s := p.Status()
+	// the samples older than the window are dropped except for the newest one
+	if len(p.samples) != 2 {
+		t.Errorf("expected 2 samples, got %d", len(p.samples))
+	}
+	if s.Rate < 9 || s.Rate > 11 {
+		t.Errorf("expected moving rate of ~10/s, got %v", s.Rate)
+	}
+	if s.PerSecond() < 3.9 || s.PerSecond() > 4.1 {
+		t.Errorf("expected average rate of ~4/s, got %v", s.PerSecond())
+	}
+}
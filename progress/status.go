@@ -12,13 +12,16 @@ type Status struct {
 	Current  int
 	MemStats runtime.MemStats
 	Total    *int
+	Finished bool    // set for the status printed when the progress is closed
+	Rate     float64 // moving average per second, 0 when not known yet
+	Message  string
 }
 
 func (status *Status) String() string {
-	s := fmt.Sprintf("total_time=%.06f per_second=%.01f", status.RunningSince().Seconds(), status.PerSecond())
+	s := fmt.Sprintf("total_time=%.06f per_second=%.01f", status.RunningSince().Seconds(), status.CurrentRate())
 	if status.Total != nil {
 		l := IntLen(*status.Total)
-		s = fmt.Sprintf("cnt=%0*d/%0*d ", l, status.Current, l, *status.Total) + s + fmt.Sprintf(" eta=%.01f", status.ETA().Seconds())
+		s = fmt.Sprintf("cnt=%0*d/%0*d ", l, status.Current, l, *status.Total) + s + fmt.Sprintf(" eta=%.01f", etaSeconds(status))
 	} else {
 		s = fmt.Sprintf("cnt=%d ", status.Current) + s
 	}
@@ -29,17 +32,44 @@ func (status *Status) String() string {
 }
 
 func (s *Status) PerSecond() float64 {
-	return float64(s.Current) / s.RunningSince().Seconds()
+	secs := s.RunningSince().Seconds()
+	if secs <= 0 {
+		return 0
+	}
+	return float64(s.Current) / secs
+}
+
+// CurrentRate returns the moving average rate when known and the average
+// since the start otherwise.
+func (s *Status) CurrentRate() float64 {
+	if s.Rate > 0 {
+		return s.Rate
+	}
+	return s.PerSecond()
 }
 
+// ETA returns nil when the total is unknown or nothing happened yet.
 func (s *Status) ETA() *time.Duration {
-	if s.Total == nil {
+	rate := s.CurrentRate()
+	if s.Total == nil || rate <= 0 {
 		return nil
 	}
-	d := time.Duration(float64(*s.Total-s.Current)/s.PerSecond()) * time.Second
+	d := time.Duration(float64(*s.Total-s.Current) / rate * float64(time.Second))
 	return &d
 }
 
+// Fraction returns the completed part of the total between 0 and 1.
+func (s *Status) Fraction() float64 {
+	if s.Total == nil || *s.Total <= 0 {
+		return 0
+	}
+	f := float64(s.Current) / float64(*s.Total)
+	if f > 1 {
+		return 1
+	}
+	return f
+}
+
 func (s *Status) RunningSince() time.Duration {
 	return s.Now.Sub(s.Started)
 }
@@ -0,0 +1,134 @@
+package pubsub
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// RemoteMessage is the encoded form of a Message sent through a Transport.
+type RemoteMessage struct {
+	Key         string
+	ContentType string
+	Body        []byte
+}
+
+// Transport connects a Bridge to a remote message broker. See the amqp
+// package for an implementation.
+type Transport interface {
+	// Send publishes the message to the broker.
+	Send(m *RemoteMessage) error
+	// Receive starts consuming messages from the broker and calls handler for
+	// every received message. It returns once consuming has started.
+	Receive(handler func(m *RemoteMessage)) error
+	Close() error
+}
+
+// NewBridge creates a bridge between ps and transport.
+func NewBridge(ps *PubSub, transport Transport) *Bridge {
+	return &Bridge{PubSub: ps, Transport: transport, Codec: JSONCodec{}}
+}
+
+// Bridge forwards local messages to a Transport and publishes messages
+// received from the transport as local *Message values. Messages received
+// from the transport are never forwarded again.
+type Bridge struct {
+	PubSub    *PubSub
+	Transport Transport
+	Codec     Codec
+
+	mutex         sync.Mutex
+	subscriptions []*Subscription
+	decoders      []*bridgeDecoder
+}
+
+type bridgeDecoder struct {
+	pattern    string
+	newPayload func() interface{}
+}
+
+// Forward sends all local messages with a key matching pattern (see
+// WithTopic) to the transport.
+func (bridge *Bridge) Forward(pattern string, options ...SubscribeOption) *Subscription {
+	s := bridge.PubSub.SubscribeTopic(pattern, func(m *Message) {
+		if err := bridge.send(m); err != nil {
+			log.Printf("ERROR: forwarding message %q: %s", m.Key(), err)
+		}
+	}, append([]SubscribeOption{WithFilter(func(m *Message) bool { return !m.remote })}, options...)...)
+	bridge.mutex.Lock()
+	bridge.subscriptions = append(bridge.subscriptions, s)
+	bridge.mutex.Unlock()
+	return s
+}
+
+// Decode registers the payload type of received messages with a key
+// matching pattern. newPayload must return a pointer the codec can decode
+// into, e.g. func() interface{} { return &User{} }. Payloads of messages not
+// matching any pattern are decoded into an interface{}.
+func (bridge *Bridge) Decode(pattern string, newPayload func() interface{}) {
+	bridge.mutex.Lock()
+	defer bridge.mutex.Unlock()
+	bridge.decoders = append(bridge.decoders, &bridgeDecoder{pattern: pattern, newPayload: newPayload})
+}
+
+// Start receives messages from the transport and publishes them locally.
+func (bridge *Bridge) Start() error {
+	return bridge.Transport.Receive(func(m *RemoteMessage) {
+		msg, err := bridge.decode(m)
+		if err != nil {
+			log.Printf("ERROR: decoding message %q: %s", m.Key, err)
+			return
+		}
+		bridge.PubSub.Publish(msg)
+	})
+}
+
+// Close stops forwarding and closes the transport.
+func (bridge *Bridge) Close() error {
+	bridge.mutex.Lock()
+	subscriptions := bridge.subscriptions
+	bridge.subscriptions = nil
+	bridge.mutex.Unlock()
+	for _, s := range subscriptions {
+		if err := s.Close(); err != nil {
+			return err
+		}
+	}
+	return bridge.Transport.Close()
+}
+
+func (bridge *Bridge) send(m *Message) error {
+	b, err := bridge.Codec.Marshal(m.Payload())
+	if err != nil {
+		return err
+	}
+	return bridge.Transport.Send(&RemoteMessage{Key: m.Key(), ContentType: bridge.Codec.ContentType(), Body: b})
+}
+
+func (bridge *Bridge) decode(m *RemoteMessage) (*Message, error) {
+	if m.ContentType != "" && m.ContentType != bridge.Codec.ContentType() {
+		return nil, fmt.Errorf("unsupported content type %q", m.ContentType)
+	}
+	bridge.mutex.Lock()
+	var newPayload func() interface{}
+	for _, d := range bridge.decoders {
+		if TopicMatches(d.pattern, m.Key) {
+			newPayload = d.newPayload
+			break
+		}
+	}
+	bridge.mutex.Unlock()
+
+	var payload interface{}
+	if newPayload != nil {
+		payload = newPayload()
+		if err := bridge.Codec.Unmarshal(m.Body, payload); err != nil {
+			return nil, err
+		}
+	} else if err := bridge.Codec.Unmarshal(m.Body, &payload); err != nil {
+		return nil, err
+	}
+	msg := NewMessage(m.Key, payload)
+	msg.remote = true
+	return msg, nil
+}
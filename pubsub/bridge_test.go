@@ -0,0 +1,98 @@
+package pubsub
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// memoryBroker delivers every sent message to all receiving transports,
+// including the sending one.
+type memoryBroker struct {
+	mutex    sync.Mutex
+	handlers []func(*RemoteMessage)
+	sent     int
+}
+
+func (b *memoryBroker) Transport() Transport {
+	return &memoryTransport{broker: b}
+}
+
+type memoryTransport struct {
+	broker *memoryBroker
+}
+
+func (t *memoryTransport) Send(m *RemoteMessage) error {
+	t.broker.mutex.Lock()
+	t.broker.sent++
+	handlers := t.broker.handlers
+	t.broker.mutex.Unlock()
+	for _, h := range handlers {
+		go h(m)
+	}
+	return nil
+}
+
+func (t *memoryTransport) Receive(handler func(*RemoteMessage)) error {
+	t.broker.mutex.Lock()
+	defer t.broker.mutex.Unlock()
+	t.broker.handlers = append(t.broker.handlers, handler)
+	return nil
+}
+
+func (t *memoryTransport) Close() error {
+	return nil
+}
+
+type bridgedUser struct {
+	Name string `json:"name"`
+}
+
+func TestBridge(t *testing.T) {
+	Convey("Bridge", t, func() {
+		broker := &memoryBroker{}
+		local, remote := New(), New()
+		localBridge := NewBridge(local, broker.Transport())
+		remoteBridge := NewBridge(remote, broker.Transport())
+		So(localBridge.Start(), ShouldBeNil)
+		So(remoteBridge.Start(), ShouldBeNil)
+		localBridge.Forward("user.#")
+		remoteBridge.Forward("user.#")
+		remoteBridge.Decode("user.*.created", func() interface{} { return &bridgedUser{} })
+
+		c := make(chan *Message, 10)
+		s := remote.SubscribeTopic("user.#", func(m *Message) {
+			c <- m
+		})
+		So(local.Publish(NewMessage("user.1.created", &bridgedUser{Name: "hans"})), ShouldBeNil)
+		So(local.Publish(NewMessage("user.1.score", 10)), ShouldBeNil)
+		So(local.Publish(NewMessage("order.1.created", 1)), ShouldBeNil)
+
+		received := map[string]*Message{}
+		timer := time.NewTimer(time.Second)
+		for len(received) < 2 {
+			select {
+			case <-timer.C:
+				t.Fatal("timeout waiting for messages")
+			case m := <-c:
+				received[m.Key()] = m
+			}
+		}
+		So(s.Close(), ShouldBeNil)
+		So(localBridge.Close(), ShouldBeNil)
+		So(remoteBridge.Close(), ShouldBeNil)
+
+		created := received["user.1.created"]
+		So(created, ShouldNotBeNil)
+		So(created.Remote(), ShouldBeTrue)
+		So(created.Payload(), ShouldResemble, &bridgedUser{Name: "hans"})
+		So(received["user.1.score"].Payload(), ShouldEqual, 10.0)
+
+		// messages received from the broker are not forwarded again
+		broker.mutex.Lock()
+		defer broker.mutex.Unlock()
+		So(broker.sent, ShouldEqual, 2)
+	})
+}
@@ -12,6 +12,7 @@ type Message struct {
 	key       string
 	createdAt time.Time
 	payload   interface{}
+	remote    bool
 }
 
 func (message *Message) Payload() interface{} {
@@ -25,3 +26,8 @@ func (message *Message) Key() string {
 func (message *Message) CreatedAt() time.Time {
 	return message.createdAt
 }
+
+// Remote returns true for messages received through a Bridge.
+func (message *Message) Remote() bool {
+	return message.remote
+}
@@ -0,0 +1,81 @@
+package pubsub
+
+import "time"
+
+// OverflowPolicy defines what Publish does when the buffer of a subscription
+// is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropNewest drops the published message and makes Publish return
+	// an error. This is the default.
+	OverflowDropNewest OverflowPolicy = iota
+	// OverflowDropOldest removes the oldest buffered message to make room for
+	// the published one.
+	OverflowDropOldest
+	// OverflowBlock waits until there is room in the buffer or the block
+	// timeout is reached.
+	OverflowBlock
+	// OverflowSpill queues messages in an unbounded in-memory queue.
+	OverflowSpill
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDropNewest:
+		return "drop_newest"
+	case OverflowDropOldest:
+		return "drop_oldest"
+	case OverflowBlock:
+		return "block"
+	case OverflowSpill:
+		return "spill"
+	}
+	return "unknown"
+}
+
+type SubscribeOption func(*Subscription)
+
+// WithBufferSize sets the number of messages buffered for the subscription
+// (defaults to 1000).
+func WithBufferSize(size int) SubscribeOption {
+	return func(s *Subscription) {
+		if size > 0 {
+			s.bufferSize = size
+		}
+	}
+}
+
+// WithWorkers sets the number of goroutines calling the callback. With more
+// than one worker messages are no longer processed in order.
+func WithWorkers(workers int) SubscribeOption {
+	return func(s *Subscription) {
+		if workers > 0 {
+			s.workers = workers
+		}
+	}
+}
+
+// DropNewest discards published messages when the buffer is full.
+func DropNewest(s *Subscription) {
+	s.policy = OverflowDropNewest
+}
+
+// DropOldest discards the oldest buffered message when the buffer is full.
+func DropOldest(s *Subscription) {
+	s.policy = OverflowDropOldest
+}
+
+// Spill queues messages without limit when the buffer is full.
+func Spill(s *Subscription) {
+	s.policy = OverflowSpill
+}
+
+// BlockFor makes Publish wait up to timeout for room in the buffer. A
+// timeout <= 0 waits without limit.
+func BlockFor(timeout time.Duration) SubscribeOption {
+	return func(s *Subscription) {
+		s.policy = OverflowBlock
+		s.blockTimeout = timeout
+	}
+}
@@ -0,0 +1,147 @@
+package pubsub
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// blockingSubscriber subscribes a callback which waits for release to be
+// closed. The first published message is taken by the worker, so the buffer
+// fills up with the following ones.
+func blockingSubscriber(ps *PubSub, options ...SubscribeOption) (s *Subscription, release chan struct{}, received func() []int) {
+	release = make(chan struct{})
+	started := make(chan struct{}, 1)
+	var mutex sync.Mutex
+	values := []int{}
+	s = ps.Subscribe(func(i int) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		mutex.Lock()
+		values = append(values, i)
+		mutex.Unlock()
+	}, options...)
+	ps.Publish(0)
+	<-started
+	return s, release, func() []int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]int{}, values...)
+	}
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	Convey("Overflow policies", t, func() {
+		ps := New()
+
+		Convey("drop newest by default", func() {
+			s, release, received := blockingSubscriber(ps, WithBufferSize(2))
+			So(ps.Publish(1), ShouldBeNil)
+			So(ps.Publish(2), ShouldBeNil)
+			So(ps.Publish(3), ShouldNotBeNil)
+			close(release)
+			So(s.Close(), ShouldBeNil)
+			So(received(), ShouldResemble, []int{0, 1, 2})
+			So(ps.Stats.Dispatched(), ShouldEqual, 3)
+			So(ps.Stats.Ignored(), ShouldEqual, 1)
+		})
+
+		Convey("drop oldest", func() {
+			s, release, received := blockingSubscriber(ps, WithBufferSize(2), DropOldest)
+			for i := 1; i <= 4; i++ {
+				So(ps.Publish(i), ShouldBeNil)
+			}
+			close(release)
+			So(s.Close(), ShouldBeNil)
+			So(received(), ShouldResemble, []int{0, 3, 4})
+			So(ps.Stats.Dispatched(), ShouldEqual, 5)
+			So(ps.Stats.DroppedOldest(), ShouldEqual, 2)
+		})
+
+		Convey("block with timeout", func() {
+			s, release, received := blockingSubscriber(ps, WithBufferSize(1), BlockFor(100*time.Millisecond))
+			So(ps.Publish(1), ShouldBeNil)
+			So(ps.Publish(2), ShouldNotBeNil)
+			So(ps.Stats.TimedOut(), ShouldEqual, 1)
+
+			go func() {
+				time.Sleep(5 * time.Millisecond)
+				close(release)
+			}()
+			So(ps.Publish(3), ShouldBeNil)
+			So(s.Close(), ShouldBeNil)
+			So(received(), ShouldResemble, []int{0, 1, 3})
+			So(ps.Stats.Dispatched(), ShouldEqual, 3)
+		})
+
+		Convey("spill", func() {
+			s, release, received := blockingSubscriber(ps, WithBufferSize(1), Spill)
+			for i := 1; i <= 5; i++ {
+				So(ps.Publish(i), ShouldBeNil)
+			}
+			So(s.Pending(), ShouldEqual, 5)
+			So(ps.Stats.Spilled(), ShouldBeGreaterThanOrEqualTo, 4)
+			close(release)
+			So(s.Close(), ShouldBeNil)
+			So(received(), ShouldResemble, []int{0, 1, 2, 3, 4, 5})
+			So(ps.Stats.Dispatched()+ps.Stats.Spilled(), ShouldEqual, 6)
+		})
+	})
+}
+
+func TestSpillCloseTimeout(t *testing.T) {
+	ps := New()
+	s, release, received := blockingSubscriber(ps, WithBufferSize(1), Spill)
+	s.closeTimeout = 10 * time.Millisecond
+	for i := 1; i <= 5; i++ {
+		if err := ps.Publish(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err == nil {
+		t.Fatal("expected Close to time out")
+	}
+	close(release)
+	for name, c := range map[string]<-chan struct{}{"pump": s.pumped, "workers": waitFinished(s)} {
+		select {
+		case <-c:
+		case <-time.After(time.Second):
+			t.Fatalf("expected %s to stop after Close timed out", name)
+		}
+	}
+	if r := received(); len(r) > 2 {
+		t.Errorf("expected pending messages to be discarded, received %v", r)
+	}
+}
+
+func waitFinished(s *Subscription) <-chan struct{} {
+	c := make(chan struct{})
+	go func() {
+		<-s.finished
+		close(c)
+	}()
+	return c
+}
+
+func TestWorkers(t *testing.T) {
+	Convey("Workers", t, func() {
+		ps := New()
+		wg := &sync.WaitGroup{}
+		wg.Add(3)
+		s := ps.Subscribe(func(int) {
+			// only returns when all three workers are busy at the same time
+			wg.Done()
+			wg.Wait()
+		}, WithWorkers(3))
+		for i := 0; i < 3; i++ {
+			So(ps.Publish(i), ShouldBeNil)
+		}
+		So(s.Close(), ShouldBeNil)
+		So(ps.Stats.Dispatched(), ShouldEqual, 3)
+	})
+}
@@ -1,57 +1,87 @@
 package pubsub
 
 import (
-	"fmt"
 	"reflect"
+	"sync"
 )
 
 func New() *PubSub {
 	return &PubSub{}
 }
 
+// PubSub dispatches published values to all subscriptions with a matching
+// callback type. It is safe for concurrent use.
 type PubSub struct {
-	subscriptions []*Subscription
 	Stats
+
+	mutex         sync.RWMutex
+	subscriptions []*Subscription
 }
 
 func (pubsub *PubSub) Publish(i interface{}) (e error) {
 	pubsub.Stats.MessageReceived()
-	value := reflect.ValueOf(i)
-	for _, s := range pubsub.subscriptions {
-		if !s.closed && s.Matches(value) {
-			select {
-			case s.buffer <- value:
-				pubsub.Stats.MessageDispatched()
-			default:
-				pubsub.Stats.MessageIgnored()
-				e = fmt.Errorf("unable to publish (subscriber buffer full) to %+v", s)
-			}
+	for _, s := range pubsub.currentSubscriptions() {
+		if !s.matches(i) {
+			continue
+		}
+		if err := s.deliver(i, &pubsub.Stats); err != nil {
+			e = err
 		}
 	}
 	return e
 }
 
-func (pubsub *PubSub) Subscribe(i interface{}) *Subscription {
+// Subscribe registers a callback with exactly one argument. Published values
+// assignable to that argument are passed to the callback. The options control
+// buffering, the overflow policy and the number of workers.
+func (pubsub *PubSub) Subscribe(i interface{}, options ...SubscribeOption) *Subscription {
 	value := reflect.ValueOf(i)
 	type_ := reflect.TypeOf(i)
-	if type_.Kind() != reflect.Func || type_.NumIn() != 1 {
+	if type_ == nil || type_.Kind() != reflect.Func || type_.NumIn() != 1 {
 		panic("you must provide a callback with exactly one argument like func(m *Message) {}")
 	}
 
-	s := &Subscription{
-		callback: value,
-		type_:    type_.In(0),
+	callback := func(v interface{}) {
+		value.Call([]reflect.Value{reflect.ValueOf(v)})
 	}
-	pubsub.subscriptions = append(pubsub.subscriptions, s)
+	return pubsub.add(newSubscription(pubsub, callback, reflectMatcher(type_.In(0)), options...))
+}
+
+func (pubsub *PubSub) add(s *Subscription) *Subscription {
 	s.start()
+	pubsub.mutex.Lock()
+	pubsub.subscriptions = append(pubsub.subscriptions, s)
+	pubsub.mutex.Unlock()
 	return s
 }
 
+// Unsubscribe removes the subscription and waits for its pending messages to
+// be processed. It is the same as calling Close on the subscription.
+func (pubsub *PubSub) Unsubscribe(s *Subscription) error {
+	return s.Close()
+}
+
+// SubscribersCount returns the number of subscriptions which are not closed.
 func (pubsub *PubSub) SubscribersCount() int {
+	pubsub.mutex.RLock()
+	defer pubsub.mutex.RUnlock()
 	return len(pubsub.subscriptions)
 }
 
-//  could not be bother to fight with locking just now
-// func (pubsub *PubSub) Unsubscribe(s *Subscription) {
-// implement me
-// }
+func (pubsub *PubSub) currentSubscriptions() []*Subscription {
+	pubsub.mutex.RLock()
+	defer pubsub.mutex.RUnlock()
+	return pubsub.subscriptions
+}
+
+func (pubsub *PubSub) remove(s *Subscription) {
+	pubsub.mutex.Lock()
+	defer pubsub.mutex.Unlock()
+	subscriptions := make([]*Subscription, 0, len(pubsub.subscriptions))
+	for _, existing := range pubsub.subscriptions {
+		if existing != s {
+			subscriptions = append(subscriptions, existing)
+		}
+	}
+	pubsub.subscriptions = subscriptions
+}
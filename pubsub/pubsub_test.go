@@ -2,9 +2,11 @@ package pubsub
 
 import (
 	"fmt"
-	. "github.com/smartystreets/goconvey/convey"
+	"sync"
 	"testing"
 	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
 )
 
 type TestUser struct {
@@ -80,6 +82,67 @@ func TestPubSub(t *testing.T) {
 	})
 }
 
+func TestUnsubscribe(t *testing.T) {
+	Convey("Unsubscribe", t, func() {
+		ps := New()
+		c := make(chan string, 10)
+		s1 := ps.Subscribe(func(m string) { c <- "s1:" + m })
+		s2 := ps.Subscribe(func(m string) { c <- "s2:" + m })
+		So(ps.SubscribersCount(), ShouldEqual, 2)
+
+		So(ps.Unsubscribe(s1), ShouldBeNil)
+		So(s1.Closed(), ShouldBeTrue)
+		So(ps.SubscribersCount(), ShouldEqual, 1)
+
+		So(ps.Publish("hello"), ShouldBeNil)
+		So(s2.Close(), ShouldBeNil)
+		So(ps.SubscribersCount(), ShouldEqual, 0)
+		So(<-c, ShouldEqual, "s2:hello")
+		So(len(c), ShouldEqual, 0)
+
+		Convey("closing twice", func() {
+			So(s2.Close(), ShouldBeNil)
+			So(ps.SubscribersCount(), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestConcurrentSubscriptions(t *testing.T) {
+	Convey("Concurrent subscribe, publish and unsubscribe", t, func() {
+		ps := New()
+		var received int64
+		var mutex sync.Mutex
+		wg := &sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 10; j++ {
+					s := ps.Subscribe(func(int) {
+						mutex.Lock()
+						received++
+						mutex.Unlock()
+					})
+					ps.Publish(j)
+					s.Close()
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					ps.Publish(j)
+				}
+			}()
+		}
+		wg.Wait()
+		So(ps.SubscribersCount(), ShouldEqual, 0)
+		So(ps.Stats.Received(), ShouldEqual, 1100)
+		mutex.Lock()
+		defer mutex.Unlock()
+		So(received, ShouldEqual, ps.Stats.Dispatched())
+	})
+}
+
 func BenchmarkPublish(b *testing.B) {
 	ps := &PubSub{}
 	c := make(chan int)
@@ -108,7 +171,7 @@ func BenchmarkPublish(b *testing.B) {
 		b.Fatal("timeout waiting for total")
 	case total = <-ready:
 	}
-	b.Logf("%d: %+v", total, ps.Stats)
+	b.Logf("%d: %+v", total, &ps.Stats)
 }
 
 func BenchmarkSubscribe(b *testing.B) {
@@ -1,60 +1,77 @@
 package pubsub
 
+import "sync/atomic"
+
+// Stats counts messages passing through a PubSub. All counters are updated
+// atomically and can be read while messages are being published.
+//
+// Every message delivered to a subscription increments exactly one of
+// Dispatched, Ignored, TimedOut or Spilled. DroppedOldest counts buffered
+// messages evicted by subscriptions using DropOldest.
 type Stats struct {
-	received       int64
-	receivedChan   chan interface{}
-	dispatchedChan chan interface{}
-	dispatched     int64
-	collecting     bool
-	ignored        int64
-	ignoredChan    chan interface{}
+	received      atomic.Int64
+	dispatched    atomic.Int64
+	ignored       atomic.Int64
+	droppedOldest atomic.Int64
+	timedOut      atomic.Int64
+	spilled       atomic.Int64
 }
 
 func (stats *Stats) Dispatched() int64 {
-	return stats.dispatched
+	return stats.dispatched.Load()
 }
 
 func (stats *Stats) Received() int64 {
-	return stats.received
+	return stats.received.Load()
 }
 
 func (stats *Stats) Ignored() int64 {
-	return stats.ignored
+	return stats.ignored.Load()
+}
+
+// DroppedOldest returns the number of buffered messages which were removed to
+// make room for newer ones.
+func (stats *Stats) DroppedOldest() int64 {
+	return stats.droppedOldest.Load()
+}
+
+// TimedOut returns the number of messages which could not be delivered to a
+// blocking subscription in time.
+func (stats *Stats) TimedOut() int64 {
+	return stats.timedOut.Load()
+}
+
+// Spilled returns the number of messages queued because the buffer of the
+// subscription was full.
+func (stats *Stats) Spilled() int64 {
+	return stats.spilled.Load()
 }
 
 func (stats *Stats) MessageDispatched() {
-	stats.StartCollecting()
-	stats.dispatchedChan <- nil
+	stats.dispatched.Add(1)
 }
 
+// StartCollecting is kept for compatibility. Counters no longer need a
+// collecting goroutine.
 func (stats *Stats) StartCollecting() {
-	if stats.collecting {
-		return
-	}
-	stats.dispatchedChan = make(chan interface{}, 1000)
-	stats.receivedChan = make(chan interface{}, 1000)
-	stats.ignoredChan = make(chan interface{}, 1000)
-	go func() {
-		for {
-			select {
-			case <-stats.receivedChan:
-				stats.received++
-			case <-stats.dispatchedChan:
-				stats.dispatched++
-			case <-stats.ignoredChan:
-				stats.ignored++
-			}
-		}
-	}()
-	stats.collecting = true
 }
 
 func (stats *Stats) MessageReceived() {
-	stats.StartCollecting()
-	stats.receivedChan <- nil
+	stats.received.Add(1)
 }
 
 func (stats *Stats) MessageIgnored() {
-	stats.StartCollecting()
-	stats.ignoredChan <- nil
+	stats.ignored.Add(1)
+}
+
+func (stats *Stats) MessageDroppedOldest() {
+	stats.droppedOldest.Add(1)
+}
+
+func (stats *Stats) MessageTimedOut() {
+	stats.timedOut.Add(1)
+}
+
+func (stats *Stats) MessageSpilled() {
+	stats.spilled.Add(1)
 }
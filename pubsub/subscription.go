@@ -4,58 +4,298 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"sync"
 	"time"
 )
 
 type Subscription struct {
-	buffer   chan reflect.Value
-	finished chan interface{}
-	callback reflect.Value
-	type_    reflect.Type
-	closed   bool
+	buffer    chan interface{}
+	finished  chan interface{}
+	callback  func(interface{})
+	matchType func(interface{}) bool
+	pubsub    *PubSub
+
+	bufferSize   int
+	workers      int
+	closeTimeout time.Duration
+	policy       OverflowPolicy
+	blockTimeout time.Duration
+	topic        []string
+	filters      []func(interface{}) bool
+
+	// senders hold a read lock, Close takes the write lock before closing the
+	// buffer
+	mutex  sync.RWMutex
+	closed bool
+
+	// only used with OverflowSpill
+	queueMutex  sync.Mutex
+	queue       []interface{}
+	queueSignal chan struct{}
+	pumped      chan struct{}
+
+	// closed by Close when the drain timeout expires to stop the pump and
+	// the workers
+	stop chan struct{}
 }
 
 const defaultBufferSize = 1000
 
+func newSubscription(pubsub *PubSub, callback func(interface{}), matchType func(interface{}) bool, options ...SubscribeOption) *Subscription {
+	s := &Subscription{
+		callback:     callback,
+		matchType:    matchType,
+		pubsub:       pubsub,
+		bufferSize:   defaultBufferSize,
+		workers:      1,
+		closeTimeout: 5 * time.Second,
+	}
+	for _, o := range options {
+		o(s)
+	}
+	return s
+}
+
+// Close removes the subscription from its PubSub and waits until all
+// buffered messages were handed to the callback. When that takes longer than
+// 5 seconds, Close discards the pending messages, stops the workers after
+// their current callback and returns an error. Calling Close more than once
+// is a no-op.
 func (subscription *Subscription) Close() error {
+	subscription.mutex.Lock()
+	if subscription.closed {
+		subscription.mutex.Unlock()
+		return nil
+	}
 	subscription.closed = true
+	subscription.mutex.Unlock()
+
+	if subscription.pubsub != nil {
+		subscription.pubsub.remove(subscription)
+	}
+	timer := time.NewTimer(subscription.closeTimeout)
+	defer timer.Stop()
+	if subscription.pumped != nil {
+		subscription.signalQueue()
+		select {
+		case <-timer.C:
+			close(subscription.stop)
+			return fmt.Errorf("timeout waiting for spilled messages")
+		case <-subscription.pumped:
+		}
+	}
 	close(subscription.buffer)
-	timer := time.NewTimer(5 * time.Second)
 	select {
 	case <-timer.C:
+		close(subscription.stop)
 		return fmt.Errorf("timeout waiting for finish")
 	case <-subscription.finished:
 		return nil
 	}
 }
 
+// Closed returns true once Close was called.
+func (subscription *Subscription) Closed() bool {
+	subscription.mutex.RLock()
+	defer subscription.mutex.RUnlock()
+	return subscription.closed
+}
+
+// Pending returns the number of messages waiting to be processed.
+func (subscription *Subscription) Pending() int {
+	subscription.queueMutex.Lock()
+	defer subscription.queueMutex.Unlock()
+	return len(subscription.buffer) + len(subscription.queue)
+}
+
+// Matches returns true when v can be passed to the callback and matches the
+// topic and filters of the subscription.
 func (subscription *Subscription) Matches(v reflect.Value) bool {
-	t := v.Type()
-	if subscription.type_ == t {
-		return true
+	if !v.IsValid() {
+		return false
+	}
+	return subscription.matches(v.Interface())
+}
+
+func (subscription *Subscription) matches(v interface{}) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Print("PANIC matching: ", r)
+			ok = false
+		}
+	}()
+	if v == nil || !subscription.matchType(v) {
+		return false
+	}
+	return subscription.matchesTopic(v) && subscription.passesFilters(v)
+}
+
+// reflectMatcher returns a func matching values assignable to t the same way
+// a callback with an argument of type t would accept them.
+func reflectMatcher(t reflect.Type) func(interface{}) bool {
+	return func(v interface{}) bool {
+		vt := reflect.TypeOf(v)
+		if vt == t {
+			return true
+		}
+		if t.Kind() == reflect.Interface {
+			return vt.Implements(t)
+		}
+		return false
+	}
+}
+
+// deliver hands the value to the subscription according to its overflow
+// policy and records the outcome in stats. Delivering to a closed
+// subscription is a no-op.
+func (subscription *Subscription) deliver(v interface{}, stats *Stats) error {
+	subscription.mutex.RLock()
+	defer subscription.mutex.RUnlock()
+	if subscription.closed {
+		return nil
 	}
-	if subscription.type_.Kind() == reflect.Interface {
-		return t.Implements(subscription.type_)
+	switch subscription.policy {
+	case OverflowDropOldest:
+		for {
+			select {
+			case subscription.buffer <- v:
+				stats.MessageDispatched()
+				return nil
+			default:
+			}
+			select {
+			case <-subscription.buffer:
+				stats.MessageDroppedOldest()
+			default:
+			}
+		}
+	case OverflowBlock:
+		select {
+		case subscription.buffer <- v:
+			stats.MessageDispatched()
+			return nil
+		default:
+		}
+		var timeout <-chan time.Time
+		if subscription.blockTimeout > 0 {
+			timer := time.NewTimer(subscription.blockTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case subscription.buffer <- v:
+			stats.MessageDispatched()
+			return nil
+		case <-timeout:
+			stats.MessageTimedOut()
+			return fmt.Errorf("timeout after %s publishing to %+v", subscription.blockTimeout, subscription)
+		}
+	case OverflowSpill:
+		subscription.queueMutex.Lock()
+		full := len(subscription.queue) > 0 || len(subscription.buffer) == cap(subscription.buffer)
+		subscription.queue = append(subscription.queue, v)
+		subscription.queueMutex.Unlock()
+		subscription.signalQueue()
+		if full {
+			stats.MessageSpilled()
+		} else {
+			stats.MessageDispatched()
+		}
+		return nil
+	default:
+		select {
+		case subscription.buffer <- v:
+			stats.MessageDispatched()
+			return nil
+		default:
+			stats.MessageIgnored()
+			return fmt.Errorf("unable to publish (subscriber buffer full) to %+v", subscription)
+		}
 	}
-	return false
 }
 
-func (subscription *Subscription) trigger(v reflect.Value) {
+func (subscription *Subscription) signalQueue() {
+	select {
+	case subscription.queueSignal <- struct{}{}:
+	default:
+	}
+}
+
+// pump moves spilled messages into the buffer in the order they were
+// published. It returns once the subscription is closed and the queue is
+// empty or when Close timed out.
+func (subscription *Subscription) pump() {
+	defer close(subscription.pumped)
+	for {
+		subscription.queueMutex.Lock()
+		if len(subscription.queue) > 0 {
+			v := subscription.queue[0]
+			subscription.queue[0] = nil
+			subscription.queue = subscription.queue[1:]
+			subscription.queueMutex.Unlock()
+			select {
+			case subscription.buffer <- v:
+			case <-subscription.stop:
+				return
+			}
+			continue
+		}
+		subscription.queue = nil
+		subscription.queueMutex.Unlock()
+		if subscription.Closed() {
+			return
+		}
+		select {
+		case <-subscription.queueSignal:
+		case <-subscription.stop:
+			return
+		}
+	}
+}
+
+func (subscription *Subscription) trigger(v interface{}) {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Print("PANIC: ", r)
 		}
 	}()
-	subscription.callback.Call([]reflect.Value{v})
+	subscription.callback(v)
 }
 
 func (subscription *Subscription) start() {
-	subscription.buffer = make(chan reflect.Value, defaultBufferSize)
+	subscription.buffer = make(chan interface{}, subscription.bufferSize)
 	subscription.finished = make(chan interface{})
+	subscription.stop = make(chan struct{})
+	if subscription.policy == OverflowSpill {
+		subscription.queueSignal = make(chan struct{}, 1)
+		subscription.pumped = make(chan struct{})
+		go subscription.pump()
+	}
+	wg := &sync.WaitGroup{}
+	for i := 0; i < subscription.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-subscription.stop:
+					return
+				default:
+				}
+				select {
+				case value, ok := <-subscription.buffer:
+					if !ok {
+						return
+					}
+					subscription.trigger(value)
+				case <-subscription.stop:
+					return
+				}
+			}
+		}()
+	}
 	go func() {
-		for value := range subscription.buffer {
-			subscription.trigger(value)
-		}
-		subscription.finished <- nil
+		wg.Wait()
+		close(subscription.finished)
 	}()
 }
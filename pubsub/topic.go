@@ -0,0 +1,99 @@
+package pubsub
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Keyed is implemented by values which can be routed by topic, e.g. *Message.
+type Keyed interface {
+	Key() string
+}
+
+// WithTopic restricts the subscription to values implementing Keyed whose key
+// matches pattern. Keys and patterns are split into segments by ".". A "*"
+// segment matches exactly one segment, a "#" segment matches zero or more, e.g.
+// "user.*.created" matches "user.1.created" and "user.#" matches "user" as well
+// as "user.1.created".
+func WithTopic(pattern string) SubscribeOption {
+	return func(s *Subscription) {
+		s.topic = strings.Split(pattern, ".")
+	}
+}
+
+// WithFilter adds a predicate like func(m *Message) bool. It is evaluated
+// before the message is dispatched, messages are only delivered when all
+// filters return true. Values not assignable to the argument of the predicate
+// are not delivered either.
+func WithFilter(f interface{}) SubscribeOption {
+	value := reflect.ValueOf(f)
+	t := reflect.TypeOf(f)
+	if t == nil || t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 1 || t.Out(0).Kind() != reflect.Bool {
+		panic(fmt.Sprintf("filter must be a func with exactly one argument returning bool like func(m *Message) bool {}, got %T", f))
+	}
+	in := t.In(0)
+	return func(s *Subscription) {
+		s.filters = append(s.filters, func(v interface{}) bool {
+			if !reflect.TypeOf(v).AssignableTo(in) {
+				return false
+			}
+			return value.Call([]reflect.Value{reflect.ValueOf(v)})[0].Bool()
+		})
+	}
+}
+
+// SubscribeTopic subscribes the callback to all values with a key matching
+// pattern (see WithTopic).
+func (pubsub *PubSub) SubscribeTopic(pattern string, i interface{}, options ...SubscribeOption) *Subscription {
+	return pubsub.Subscribe(i, append([]SubscribeOption{WithTopic(pattern)}, options...)...)
+}
+
+// TopicMatches returns true when key matches the topic pattern.
+func TopicMatches(pattern, key string) bool {
+	return topicMatches(strings.Split(pattern, "."), strings.Split(key, "."))
+}
+
+func topicMatches(pattern, key []string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case "#":
+			for i := 0; i <= len(key); i++ {
+				if topicMatches(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case "*":
+			if len(key) == 0 {
+				return false
+			}
+		default:
+			if len(key) == 0 || key[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, key = pattern[1:], key[1:]
+	}
+	return len(key) == 0
+}
+
+func (subscription *Subscription) matchesTopic(v interface{}) bool {
+	if subscription.topic == nil {
+		return true
+	}
+	k, ok := v.(Keyed)
+	if !ok {
+		return false
+	}
+	return topicMatches(subscription.topic, strings.Split(k.Key(), "."))
+}
+
+func (subscription *Subscription) passesFilters(v interface{}) bool {
+	for _, f := range subscription.filters {
+		if !f(v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package pubsub
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTopicMatches(t *testing.T) {
+	Convey("TopicMatches", t, func() {
+		for _, tc := range []struct {
+			Pattern string
+			Key     string
+			Matches bool
+		}{
+			{"user.created", "user.created", true},
+			{"user.created", "user.deleted", false},
+			{"user.*.created", "user.1.created", true},
+			{"user.*.created", "user.created", false},
+			{"user.*.created", "user.1.2.created", false},
+			{"user.*", "user.1", true},
+			{"user.*", "user.1.created", false},
+			{"user.#", "user", true},
+			{"user.#", "user.1.created", true},
+			{"#.created", "user.1.created", true},
+			{"#.created", "user.1.deleted", false},
+			{"#", "anything.at.all", true},
+		} {
+			So(TopicMatches(tc.Pattern, tc.Key), ShouldEqual, tc.Matches)
+		}
+	})
+}
+
+func TestTopicSubscriptions(t *testing.T) {
+	Convey("Topic subscriptions", t, func() {
+		ps := New()
+		c := make(chan string, 10)
+		s := ps.SubscribeTopic("user.*.created", func(m *Message) {
+			c <- m.Key()
+		})
+		ps.Publish(NewMessage("user.1.created", nil))
+		ps.Publish(NewMessage("user.1.deleted", nil))
+		ps.Publish(NewMessage("user.2.created", nil))
+		ps.Publish("user.3.created")
+		So(s.Close(), ShouldBeNil)
+		close(c)
+		keys := []string{}
+		for k := range c {
+			keys = append(keys, k)
+		}
+		So(keys, ShouldResemble, []string{"user.1.created", "user.2.created"})
+		So(ps.Stats.Dispatched(), ShouldEqual, 2)
+	})
+
+	Convey("Filters", t, func() {
+		ps := New()
+		c := make(chan int, 10)
+		s := ps.Subscribe(func(i int) {
+			c <- i
+		}, WithFilter(func(i int) bool { return i%2 == 0 }), WithFilter(func(i int) bool { return i > 2 }))
+		for i := 0; i < 8; i++ {
+			ps.Publish(i)
+		}
+		So(s.Close(), ShouldBeNil)
+		close(c)
+		values := []int{}
+		for i := range c {
+			values = append(values, i)
+		}
+		So(values, ShouldResemble, []int{4, 6})
+
+		Convey("with invalid signature", func() {
+			So(func() { WithFilter(func(i int) {}) }, ShouldPanic)
+			So(func() { WithFilter("not a func") }, ShouldPanic)
+		})
+	})
+}
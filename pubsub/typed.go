@@ -0,0 +1,33 @@
+package pubsub
+
+// SubscribeTyped subscribes f to all published values of type T. Unlike
+// PubSub.Subscribe the callback is checked at compile time and called
+// without reflection. When T is an interface type all values implementing it
+// are delivered.
+func SubscribeTyped[T any](ps *PubSub, f func(T), options ...SubscribeOption) *Subscription {
+	callback := func(v interface{}) {
+		f(v.(T))
+	}
+	return ps.add(newSubscription(ps, callback, matchTyped[T], options...))
+}
+
+// Publish publishes v to ps. Subscriptions created with Subscribe and
+// SubscribeTyped both receive the value.
+func Publish[T any](ps *PubSub, v T) error {
+	return ps.Publish(v)
+}
+
+// Where is a type safe variant of WithFilter.
+func Where[T any](f func(T) bool) SubscribeOption {
+	return func(s *Subscription) {
+		s.filters = append(s.filters, func(v interface{}) bool {
+			t, ok := v.(T)
+			return ok && f(t)
+		})
+	}
+}
+
+func matchTyped[T any](v interface{}) bool {
+	_, ok := v.(T)
+	return ok
+}
@@ -0,0 +1,98 @@
+package pubsub
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTyped(t *testing.T) {
+	Convey("SubscribeTyped", t, func() {
+		ps := New()
+		users := make(chan *TestUser, 10)
+		stringers := make(chan fmt.Stringer, 10)
+		s1 := SubscribeTyped(ps, func(u *TestUser) {
+			users <- u
+		}, Where(func(u *TestUser) bool { return u.name != "" }))
+		s2 := SubscribeTyped(ps, func(s fmt.Stringer) {
+			stringers <- s
+		})
+		reflected := make(chan *TestUser, 10)
+		s3 := ps.Subscribe(func(u *TestUser) {
+			reflected <- u
+		})
+
+		So(Publish(ps, &TestUser{name: "hans"}), ShouldBeNil)
+		So(Publish(ps, &TestUser{}), ShouldBeNil)
+		So(Publish(ps, "not a user"), ShouldBeNil)
+		So(ps.Publish(&TestUser{name: "meyer"}), ShouldBeNil)
+		for _, s := range []*Subscription{s1, s2, s3} {
+			So(s.Close(), ShouldBeNil)
+		}
+		So(len(users), ShouldEqual, 2)
+		So((<-users).name, ShouldEqual, "hans")
+		So((<-users).name, ShouldEqual, "meyer")
+		So(len(stringers), ShouldEqual, 3)
+		So(len(reflected), ShouldEqual, 3)
+	})
+}
+
+func BenchmarkPublishTyped(b *testing.B) {
+	ps := &PubSub{}
+	c := make(chan int)
+	ready := make(chan int)
+	go func() {
+		total := 0
+		for i := range c {
+			total += i
+		}
+		ready <- total
+	}()
+	s := SubscribeTyped(ps, func(int) {
+		c <- 1
+	})
+	for i := 0; i < b.N; i++ {
+		Publish(ps, 10)
+		time.Sleep(1 * time.Microsecond)
+	}
+	s.Close()
+	close(c)
+
+	timer := time.NewTicker(5 * time.Second)
+	var total int
+	select {
+	case <-timer.C:
+		b.Fatal("timeout waiting for total")
+	case total = <-ready:
+	}
+	b.Logf("%d: %+v", total, &ps.Stats)
+}
+
+// benchmarkDispatch measures publishing and calling the callback without the
+// sleep used in BenchmarkPublish.
+func benchmarkDispatch(b *testing.B, subscribe func(ps *PubSub, done chan struct{}) *Subscription) {
+	ps := New()
+	done := make(chan struct{})
+	s := subscribe(ps, done)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ps.Publish(i)
+		<-done
+	}
+	b.StopTimer()
+	s.Close()
+}
+
+func BenchmarkDispatchReflect(b *testing.B) {
+	benchmarkDispatch(b, func(ps *PubSub, done chan struct{}) *Subscription {
+		return ps.Subscribe(func(int) { done <- struct{}{} })
+	})
+}
+
+func BenchmarkDispatchTyped(b *testing.B) {
+	benchmarkDispatch(b, func(ps *PubSub, done chan struct{}) *Subscription {
+		return SubscribeTyped(ps, func(int) { done <- struct{}{} })
+	})
+}
@@ -0,0 +1,241 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	"github.com/dynport/dgtk/gosql"
+	"github.com/dynport/dgtk/gosql/dialect"
+)
+
+type BatchOpt struct {
+	// Key is the column to page by, defaults to "id". Its values must be
+	// unique and totally ordered (e.g. not NULL), rows sharing the key of
+	// the last row of a batch are skipped otherwise.
+	Key       string
+	BatchSize int // defaults to 1000
+	// Cursor uses a server-side cursor (DECLARE/FETCH) instead of paging by
+	// Key. It is only supported with Postgres. Cursors only exist within a
+	// transaction so con must be a *sql.Tx, which does not expose its
+	// dialect: set Dialect to dialect.Postgres.
+	Cursor bool
+	// Dialect defaults to the dialect of con, see dialect.Of. It is not
+	// guessed for a transaction with Cursor.
+	Dialect dialect.Dialect
+}
+
+const defaultBatchSize = 1000
+
+// BatchIterator iterates over the rows of a query in batches of structs
+// unmarshalled like with gosql.UnmarshalRow.
+//
+//	it := sequel.IterateBatches[User](ctx, db, "SELECT * FROM users WHERE active = $1", nil, true)
+//	defer it.Close()
+//	for it.Next() {
+//		for _, u := range it.Batch() {
+//			...
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type BatchIterator[T any] struct {
+	ctx    context.Context
+	con    Con
+	q      string
+	args   []interface{}
+	opt    BatchOpt
+	last   interface{} // key of the last row, nil before the first batch
+	cursor string      // name of the declared cursor
+	batch  []*T
+	done   bool
+	err    error
+}
+
+// IterateBatches returns an iterator over the rows of q. Without cursor the
+// rows are paged by key (WHERE key > last ORDER BY key LIMIT n) so q is
+// wrapped in a subquery and must select the key column. Placeholders of q
+// use $1 to $n like the args.
+func IterateBatches[T any](ctx context.Context, con Con, q string, opt *BatchOpt, args ...interface{}) *BatchIterator[T] {
+	it := &BatchIterator[T]{ctx: ctx, con: con, q: q, args: args}
+	if opt != nil {
+		it.opt = *opt
+	}
+	if it.opt.Key == "" {
+		it.opt.Key = "id"
+	}
+	if it.opt.BatchSize <= 0 {
+		it.opt.BatchSize = defaultBatchSize
+	}
+	if it.opt.Dialect == nil {
+		if d, err := dialect.Lookup(con); err == nil {
+			it.opt.Dialect = d
+		} else if !it.opt.Cursor {
+			it.opt.Dialect = dialect.Default
+		}
+	}
+	return it
+}
+
+// Next loads the next batch and returns false when all rows were read or an
+// error occurred.
+func (it *BatchIterator[T]) Next() bool {
+	it.batch = nil
+	if it.done || it.err != nil {
+		return false
+	}
+	if it.err = it.ctx.Err(); it.err != nil {
+		return false
+	}
+	if it.opt.Cursor {
+		it.err = it.fetch()
+	} else {
+		it.err = it.page()
+	}
+	if it.err != nil || len(it.batch) == 0 {
+		it.done = true
+		return false
+	}
+	if len(it.batch) < it.opt.BatchSize {
+		it.done = true // saves querying an empty batch
+	}
+	return true
+}
+
+// Batch returns the structs loaded by the last call to Next.
+func (it *BatchIterator[T]) Batch() []*T {
+	return it.batch
+}
+
+func (it *BatchIterator[T]) Err() error {
+	return it.err
+}
+
+// Close closes the cursor. It must be called before the transaction is
+// used otherwise when the iteration was stopped early.
+func (it *BatchIterator[T]) Close() error {
+	it.done = true
+	if it.cursor == "" {
+		return nil
+	}
+	name := it.cursor
+	it.cursor = ""
+	_, err := it.exec("CLOSE " + name)
+	return err
+}
+
+func (it *BatchIterator[T]) page() error {
+	d := it.opt.Dialect
+	q := "SELECT * FROM (" + it.q + ") keyset"
+	args := append([]interface{}{}, it.args...)
+	if it.last != nil {
+		args = append(args, it.last)
+		q += " WHERE " + it.opt.Key + " > " + d.Placeholder(len(args))
+	}
+	q += fmt.Sprintf(" ORDER BY %s LIMIT %d", it.opt.Key, it.opt.BatchSize)
+	return it.load(dialect.Rebind(d, q), args, true)
+}
+
+var cursorID int64
+
+func (it *BatchIterator[T]) fetch() error {
+	if it.cursor == "" {
+		if it.opt.Dialect == nil {
+			return fmt.Errorf("cursor iteration is only supported with Postgres, set BatchOpt.Dialect for transactions")
+		} else if it.opt.Dialect != dialect.Postgres {
+			return fmt.Errorf("cursor iteration is only supported with Postgres, dialect is %s", it.opt.Dialect.Name())
+		}
+		if _, ok := it.con.(*sql.DB); ok {
+			// every statement could run on a different connection of the pool
+			return fmt.Errorf("cursor iteration requires a transaction, got *sql.DB")
+		}
+		name := fmt.Sprintf("sequel_cursor_%d", atomic.AddInt64(&cursorID, 1))
+		if _, err := it.exec("DECLARE "+name+" NO SCROLL CURSOR FOR "+it.q, it.args...); err != nil {
+			return err
+		}
+		it.cursor = name
+	}
+	return it.load(fmt.Sprintf("FETCH FORWARD %d FROM %s", it.opt.BatchSize, it.cursor), nil, false)
+}
+
+type contextCon interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (it *BatchIterator[T]) query(q string, args ...interface{}) (*sql.Rows, error) {
+	if c, ok := it.con.(contextCon); ok {
+		return c.QueryContext(it.ctx, q, args...)
+	}
+	return it.con.Query(q, args...)
+}
+
+func (it *BatchIterator[T]) exec(q string, args ...interface{}) (sql.Result, error) {
+	if c, ok := it.con.(contextCon); ok {
+		return c.ExecContext(it.ctx, q, args...)
+	}
+	return it.con.Exec(q, args...)
+}
+
+// load unmarshals all rows of q into the batch and remembers the key of the
+// last row when withKey is set.
+func (it *BatchIterator[T]) load(q string, args []interface{}, withKey bool) error {
+	rows, err := it.query(q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	r := &keyRows{Rows: rows, idx: -1}
+	if withKey {
+		cols, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+		for i, c := range cols {
+			if strings.EqualFold(c, it.opt.Key) {
+				r.idx = i
+			}
+		}
+		if r.idx < 0 {
+			return fmt.Errorf("key column %s not selected by query", it.opt.Key)
+		}
+	}
+	batch := []*T{}
+	for r.Next() {
+		v := new(T)
+		if err := gosql.UnmarshalRow(r, v); err != nil {
+			return err
+		}
+		batch = append(batch, v)
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+	it.batch = batch
+	if withKey && len(batch) > 0 {
+		it.last = r.last
+	}
+	return nil
+}
+
+// keyRows remembers the value scanned for the column at idx, either into a
+// struct field or the placeholder for columns without field.
+type keyRows struct {
+	*sql.Rows
+	idx  int
+	last interface{}
+}
+
+func (r *keyRows) Scan(dest ...interface{}) error {
+	if err := r.Rows.Scan(dest...); err != nil {
+		return err
+	}
+	if r.idx >= 0 && r.idx < len(dest) {
+		r.last = reflect.ValueOf(dest[r.idx]).Elem().Interface()
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package sequel
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dynport/dgtk/gosql/dialect"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type batchUser struct {
+	ID   int    `sql:"id"`
+	Name string `sql:"name"`
+}
+
+func testSQLite(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR NOT NULL, active BOOLEAN NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 25; i++ {
+		if _, err := db.Exec("INSERT INTO users (name, active) VALUES (?, ?)", "user", i%5 != 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestIterateBatches(t *testing.T) {
+	db := testSQLite(t)
+	tests := []struct {
+		Description string
+		Query       string
+		Args        []interface{}
+		Opt         *BatchOpt
+		Expected    []int // sizes of the batches
+	}{
+		{"defaults", "SELECT * FROM users", nil, nil, []int{25}},
+		{"batches", "SELECT * FROM users", nil, &BatchOpt{BatchSize: 10}, []int{10, 10, 5}},
+		{"full last batch", "SELECT * FROM users", nil, &BatchOpt{BatchSize: 5}, []int{5, 5, 5, 5, 5}},
+		{"args", "SELECT id, name FROM users WHERE active = $1", []interface{}{true}, &BatchOpt{BatchSize: 7}, []int{7, 7, 6}},
+		{"key without field", "SELECT id AS uid, name FROM users", nil, &BatchOpt{Key: "uid", BatchSize: 10}, []int{10, 10, 5}},
+	}
+	for _, tst := range tests {
+		it := IterateBatches[batchUser](context.Background(), db, tst.Query, tst.Opt, tst.Args...)
+		sizes := []int{}
+		ids := map[int]bool{}
+		for it.Next() {
+			sizes = append(sizes, len(it.Batch()))
+			for _, u := range it.Batch() {
+				if u.Name != "user" {
+					t.Errorf("%s: expected name to be %q, was %q", tst.Description, "user", u.Name)
+				}
+				ids[u.ID] = true
+			}
+		}
+		if err := it.Err(); err != nil {
+			t.Errorf("%s: %s", tst.Description, err)
+		}
+		if err := it.Close(); err != nil {
+			t.Errorf("%s: %s", tst.Description, err)
+		}
+		if !reflect.DeepEqual(sizes, tst.Expected) {
+			t.Errorf("%s: expected batch sizes to be %v, was %v", tst.Description, tst.Expected, sizes)
+		}
+		if tst.Opt == nil || tst.Opt.Key == "" {
+			total := 0
+			for _, s := range sizes {
+				total += s
+			}
+			if len(ids) != total {
+				t.Errorf("%s: expected %d distinct ids, got %d", tst.Description, total, len(ids))
+			}
+		}
+	}
+}
+
+func TestIterateBatchesErrors(t *testing.T) {
+	db := testSQLite(t)
+	it := IterateBatches[batchUser](context.Background(), db, "SELECT name FROM users", nil)
+	if it.Next() {
+		t.Errorf("expected Next to be false without key column")
+	}
+	if it.Err() == nil {
+		t.Errorf("expected error without key column")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	for _, opt := range []*BatchOpt{
+		{Cursor: true},
+		{Cursor: true, Dialect: dialect.SQLite},
+	} {
+		it = IterateBatches[batchUser](context.Background(), tx, "SELECT * FROM users", opt)
+		if it.Next() {
+			t.Errorf("expected Next to be false for a cursor with SQLite")
+		}
+		if err := it.Err(); err == nil || !strings.Contains(err.Error(), "only supported with Postgres") {
+			t.Errorf("expected error for a cursor with SQLite, got %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it = IterateBatches[batchUser](ctx, db, "SELECT * FROM users", &BatchOpt{BatchSize: 10})
+	if !it.Next() {
+		t.Fatalf("expected first batch, got error %v", it.Err())
+	}
+	cancel()
+	if it.Next() {
+		t.Errorf("expected Next to be false after cancel")
+	}
+	if it.Err() != context.Canceled {
+		t.Errorf("expected error to be %v, was %v", context.Canceled, it.Err())
+	}
+}
+
+func testDatabaseURL() string {
+	if env := os.Getenv("TEST_DATABASE_URL"); env != "" {
+		return env
+	}
+	return "postgres://127.0.0.1/template1?sslmode=disable"
+}
+
+func TestIterateBatchesCursor(t *testing.T) {
+	if os.Getenv("TEST_WITH_DB") != "true" {
+		t.SkipNow()
+	}
+	db, err := sql.Open("postgres", testDatabaseURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	for _, q := range []string{
+		"CREATE TEMPORARY TABLE users (id SERIAL PRIMARY KEY, name VARCHAR NOT NULL, active BOOLEAN NOT NULL)",
+		"INSERT INTO users (name, active) SELECT 'user', i % 5 <> 0 FROM generate_series(1, 25) i",
+	} {
+		if _, err := tx.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	openCursors := func() (cnt int) {
+		if err := tx.QueryRow("SELECT COUNT(1) FROM pg_cursors WHERE name LIKE 'sequel_cursor_%'").Scan(&cnt); err != nil {
+			t.Fatal(err)
+		}
+		return cnt
+	}
+
+	tests := []struct {
+		Description string
+		Query       string
+		Args        []interface{}
+		BatchSize   int
+		Expected    []int // sizes of the batches
+	}{
+		{"batches", "SELECT * FROM users ORDER BY id", nil, 10, []int{10, 10, 5}},
+		{"full last batch", "SELECT * FROM users ORDER BY id", nil, 5, []int{5, 5, 5, 5, 5}},
+		{"args", "SELECT id, name FROM users WHERE active = $1", []interface{}{true}, 7, []int{7, 7, 6}},
+	}
+	for _, tst := range tests {
+		it := IterateBatches[batchUser](context.Background(), tx, tst.Query, &BatchOpt{Cursor: true, BatchSize: tst.BatchSize, Dialect: dialect.Postgres}, tst.Args...)
+		sizes := []int{}
+		for it.Next() {
+			sizes = append(sizes, len(it.Batch()))
+		}
+		if err := it.Err(); err != nil {
+			t.Errorf("%s: %s", tst.Description, err)
+		}
+		if err := it.Close(); err != nil {
+			t.Errorf("%s: %s", tst.Description, err)
+		}
+		if !reflect.DeepEqual(sizes, tst.Expected) {
+			t.Errorf("%s: expected batch sizes to be %v, was %v", tst.Description, tst.Expected, sizes)
+		}
+	}
+
+	it := IterateBatches[batchUser](context.Background(), tx, "SELECT * FROM users ORDER BY id", &BatchOpt{Cursor: true, BatchSize: 10, Dialect: dialect.Postgres})
+	if !it.Next() {
+		t.Fatalf("expected first batch, got error %v", it.Err())
+	}
+	if cnt := openCursors(); cnt != 1 {
+		t.Errorf("expected 1 open cursor, got %d", cnt)
+	}
+	if err := it.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if it.Next() {
+		t.Errorf("expected Next to be false after Close")
+	}
+	if cnt := openCursors(); cnt != 0 {
+		t.Errorf("expected cursor to be closed after Close, got %d open", cnt)
+	}
+
+	it = IterateBatches[batchUser](context.Background(), db, "SELECT * FROM users", &BatchOpt{Cursor: true, Dialect: dialect.Postgres})
+	if it.Next() {
+		t.Errorf("expected Next to be false for a cursor without transaction")
+	}
+	if it.Err() == nil {
+		t.Errorf("expected error for a cursor without transaction")
+	}
+}
@@ -0,0 +1,265 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultCompression is used by NewDigest for a compression <= 0. Higher
+// values use more memory and give more accurate percentiles.
+const DefaultCompression = 100
+
+// NewDigest returns a streaming percentile estimator (a merging t-digest).
+// Memory usage only depends on the compression, not on the number of values.
+func NewDigest(compression float64) *Digest {
+	if compression <= 0 {
+		compression = DefaultCompression
+	}
+	return &Digest{compression: compression, min: math.Inf(1), max: math.Inf(-1)}
+}
+
+// Digest provides the same methods as Stats without keeping every value in
+// memory. Min, Max, Sum, Avg and StdDeviation are exact, percentiles are
+// estimated. Digests can be merged, e.g. to combine results of workers.
+type Digest struct {
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	weight      float64
+
+	count int
+	min   float64
+	max   float64
+	sum   float64
+	mean  float64 // running mean and m2 for the variance (Welford)
+	m2    float64
+}
+
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+func (d *Digest) Add(values ...float64) {
+	for _, v := range values {
+		d.add(v, 1)
+		d.count++
+		d.sum += v
+		delta := v - d.mean
+		d.mean += delta / float64(d.count)
+		d.m2 += delta * (v - d.mean)
+	}
+}
+
+func (d *Digest) add(v, weight float64) {
+	if v < d.min {
+		d.min = v
+	}
+	if v > d.max {
+		d.max = v
+	}
+	d.buffer = append(d.buffer, centroid{mean: v, weight: weight})
+	d.weight += weight
+	if len(d.buffer) >= d.bufferSize() {
+		d.process()
+	}
+}
+
+func (d *Digest) bufferSize() int {
+	return int(5 * d.compression)
+}
+
+// Merge adds all values of other to the digest.
+func (d *Digest) Merge(other *Digest) {
+	other.process()
+	if other.count == 0 {
+		return
+	}
+	for _, c := range other.centroids {
+		d.add(c.mean, c.weight)
+	}
+	if other.min < d.min {
+		d.min = other.min
+	}
+	if other.max > d.max {
+		d.max = other.max
+	}
+	// combine running means and variances (Chan et al.)
+	n := float64(d.count + other.count)
+	delta := other.mean - d.mean
+	d.m2 += other.m2 + delta*delta*float64(d.count)*float64(other.count)/n
+	d.mean += delta * float64(other.count) / n
+	d.count += other.count
+	d.sum += other.sum
+}
+
+// process merges the buffered values into the centroids. The size of a
+// centroid is limited by the k1 scale function of the t-digest paper, which
+// keeps centroids small at both ends of the distribution.
+func (d *Digest) process() {
+	if len(d.buffer) == 0 {
+		return
+	}
+	all := make([]centroid, 0, len(d.centroids)+len(d.buffer))
+	all = append(all, d.centroids...)
+	all = append(all, d.buffer...)
+	d.buffer = d.buffer[:0]
+	sort.Slice(all, func(a, b int) bool { return all[a].mean < all[b].mean })
+
+	merged := all[:1]
+	soFar := 0.0
+	limit := d.qLimit(0)
+	for _, c := range all[1:] {
+		cur := &merged[len(merged)-1]
+		if (soFar+cur.weight+c.weight)/d.weight <= limit {
+			cur.weight += c.weight
+			cur.mean += (c.mean - cur.mean) * c.weight / cur.weight
+			continue
+		}
+		soFar += cur.weight
+		limit = d.qLimit(soFar / d.weight)
+		merged = append(merged, c)
+	}
+	d.centroids = merged
+}
+
+// qLimit returns the max quantile a centroid starting at q may reach.
+func (d *Digest) qLimit(q float64) float64 {
+	k := d.compression / (2 * math.Pi) * math.Asin(2*q-1)
+	k += 1
+	if k >= d.compression/4 {
+		return 1
+	}
+	return (math.Sin(k*2*math.Pi/d.compression) + 1) / 2
+}
+
+// points returns the cumulative weight at the center of every centroid,
+// including min and max at both ends.
+func (d *Digest) points() (values, weights []float64) {
+	d.process()
+	values = append(values, d.min)
+	weights = append(weights, 0)
+	soFar := 0.0
+	for _, c := range d.centroids {
+		values = append(values, c.mean)
+		weights = append(weights, soFar+c.weight/2)
+		soFar += c.weight
+	}
+	values = append(values, d.max)
+	weights = append(weights, soFar)
+	return values, weights
+}
+
+// Quantile estimates the value at quantile q (between 0 and 1).
+func (d *Digest) Quantile(q float64) float64 {
+	if d.count == 0 {
+		return math.NaN()
+	}
+	values, weights := d.points()
+	index := q * d.weight
+	for i := 1; i < len(values); i++ {
+		if index <= weights[i] {
+			return interpolate(index, weights[i-1], weights[i], values[i-1], values[i])
+		}
+	}
+	return d.max
+}
+
+// CDF estimates the fraction of values <= v.
+func (d *Digest) CDF(v float64) float64 {
+	if d.count == 0 || v < d.min {
+		return 0
+	}
+	if v >= d.max {
+		return 1
+	}
+	values, weights := d.points()
+	for i := 1; i < len(values); i++ {
+		if v < values[i] {
+			return interpolate(v, values[i-1], values[i], weights[i-1], weights[i]) / d.weight
+		}
+	}
+	return 1
+}
+
+// interpolate maps x between x0 and x1 linearly to y0 and y1.
+func interpolate(x, x0, x1, y0, y1 float64) float64 {
+	if x1 == x0 {
+		return y0
+	}
+	return y0 + (x-x0)/(x1-x0)*(y1-y0)
+}
+
+// Perc estimates the percentile, use 50 for median
+func (d *Digest) Perc(perc float64) float64 {
+	return d.Quantile(perc / 100)
+}
+
+func (d *Digest) Median() float64 {
+	return d.Perc(50)
+}
+
+func (d *Digest) Len() int {
+	return d.count
+}
+
+func (d *Digest) Sum() float64 {
+	return d.sum
+}
+
+func (d *Digest) Avg() float64 {
+	return d.sum / float64(d.count)
+}
+
+func (d *Digest) Min() float64 {
+	if d.count == 0 {
+		return 0
+	}
+	return d.min
+}
+
+func (d *Digest) Max() float64 {
+	if d.count == 0 {
+		return 0
+	}
+	return d.max
+}
+
+func (d *Digest) Variance() float64 {
+	return d.m2 / float64(d.count-1)
+}
+
+func (d *Digest) StdDeviation() float64 {
+	return math.Sqrt(d.Variance())
+}
+
+// Centroids returns the number of centroids used to store the distribution.
+func (d *Digest) Centroids() int {
+	d.process()
+	return len(d.centroids)
+}
+
+// Histogram estimates the number of values in the buckets with the given
+// upper bounds.
+func (d *Digest) Histogram(bounds []float64) *Histogram {
+	h := NewHistogram(bounds...)
+	prev := 0
+	for i, b := range h.bounds {
+		cum := int(math.Round(d.CDF(b) * float64(d.count)))
+		h.counts[i] = cum - prev
+		prev = cum
+	}
+	h.counts[len(h.bounds)] = d.count - prev
+	h.count = d.count
+	h.sum = d.sum
+	return h
+}
+
+func (d *Digest) String() string {
+	return fmt.Sprintf("len: %d, avg: %.1f, med: %.1f, perc_95: %.1f, perc_99: %.1f, max: %.1f, min: %.1f",
+		d.Len(), d.Avg(), d.Median(), d.Perc(95), d.Perc(99),
+		d.Max(),
+		d.Min(),
+	)
+}
@@ -0,0 +1,110 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestDigest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	exact := New()
+	d := NewDigest(100)
+	for i := 0; i < 100000; i++ {
+		v := r.ExpFloat64() * 100
+		exact.Add(v)
+		d.Add(v)
+	}
+	f := func(in float64) string { return fmt.Sprintf("%.06f", in) }
+
+	tests := []struct{ Has, Want interface{} }{
+		{d.Len(), exact.Len()},
+		{f(d.Min()), f(exact.Min())},
+		{f(d.Max()), f(exact.Max())},
+		{f(d.Avg()), f(exact.Avg())},
+		{f(d.StdDeviation()), f(exact.StdDeviation())},
+		{d.Centroids() < 500, true},
+	}
+	for i, tc := range tests {
+		if tc.Want != tc.Has {
+			t.Errorf("%d: want %#v, was %#v", i+1, tc.Want, tc.Has)
+		}
+	}
+	// compare the rank of the estimated value with the requested percentile
+	exact.Sort()
+	values := exact.Values()
+	for _, perc := range []float64{0.1, 1, 10, 50, 90, 95, 99, 99.9} {
+		has := d.Perc(perc)
+		rank := 100 * float64(sort.SearchFloat64s(values, has)) / float64(len(values))
+		if diff := math.Abs(rank - perc); diff > 0.1 {
+			t.Errorf("perc %v: estimated %.3f has rank %.3f (diff %.4f)", perc, has, rank, diff)
+		}
+	}
+}
+
+func TestDigestMerge(t *testing.T) {
+	all := NewDigest(0)
+	merged := NewDigest(0)
+	for i := 0; i < 4; i++ {
+		part := NewDigest(0)
+		for j := 0; j < 10000; j++ {
+			v := float64(i*10000 + j)
+			part.Add(v)
+			all.Add(v)
+		}
+		merged.Merge(part)
+	}
+	f := func(in float64) string { return fmt.Sprintf("%.03f", in) }
+	tests := []struct{ Has, Want interface{} }{
+		{merged.Len(), 40000},
+		{merged.Min(), 0.0},
+		{merged.Max(), 39999.0},
+		{f(merged.Avg()), f(all.Avg())},
+		{f(merged.StdDeviation()), f(all.StdDeviation())},
+		{math.Abs(merged.Median()-20000) < 200, true},
+		{math.Abs(merged.Perc(99)-39600) < 100, true},
+	}
+	for i, tc := range tests {
+		if tc.Want != tc.Has {
+			t.Errorf("%d: want %#v, was %#v", i+1, tc.Want, tc.Has)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram(ExponentialBuckets(1, 10, 3)...)
+	h.Add(0.5, 1, 5, 10, 50, 100, 1000)
+	want := []struct {
+		Bound      float64
+		Count, Cum int
+	}{
+		{1, 2, 2}, {10, 2, 4}, {100, 2, 6}, {math.Inf(1), 1, 7},
+	}
+	buckets := h.Buckets()
+	if len(buckets) != len(want) {
+		t.Fatalf("expected %d buckets, got %d", len(want), len(buckets))
+	}
+	for i, w := range want {
+		b := buckets[i]
+		if b.UpperBound != w.Bound || b.Count != w.Count || b.Cumulative != w.Cum {
+			t.Errorf("%d: want %+v, was %+v", i, w, b)
+		}
+	}
+
+	other := NewHistogram(LinearBuckets(1, 1, 3)...)
+	if err := h.Merge(other); err == nil {
+		t.Error("expected error merging histograms with different bounds")
+	}
+
+	d := NewDigest(0)
+	for i := 1; i <= 1000; i++ {
+		d.Add(float64(i))
+	}
+	for i, b := range d.Histogram(LinearBuckets(250, 250, 3)).Buckets() {
+		if math.Abs(float64(b.Count-250)) > 5 {
+			t.Errorf("%d: expected ~250 values in bucket, got %d", i, b.Count)
+		}
+	}
+}
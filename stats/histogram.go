@@ -0,0 +1,109 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// LinearBuckets returns count upper bounds starting at start, each width
+// apart.
+func LinearBuckets(start, width float64, count int) []float64 {
+	bounds := make([]float64, 0, count)
+	for i := 0; i < count; i++ {
+		bounds = append(bounds, start+float64(i)*width)
+	}
+	return bounds
+}
+
+// ExponentialBuckets returns count upper bounds starting at start, each
+// factor times the previous one.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	bounds := make([]float64, 0, count)
+	for i := 0; i < count; i++ {
+		bounds = append(bounds, start*math.Pow(factor, float64(i)))
+	}
+	return bounds
+}
+
+// NewHistogram creates a histogram with buckets for the given upper bounds
+// and one bucket for all larger values.
+func NewHistogram(bounds ...float64) *Histogram {
+	b := append([]float64{}, bounds...)
+	sort.Float64s(b)
+	return &Histogram{bounds: b, counts: make([]int, len(b)+1)}
+}
+
+// Histogram counts values in buckets with fixed upper bounds.
+type Histogram struct {
+	bounds []float64
+	counts []int
+	count  int
+	sum    float64
+}
+
+// Bucket contains the number of values <= UpperBound and > the bound of the
+// previous bucket. The last bucket has an UpperBound of +Inf.
+type Bucket struct {
+	UpperBound float64
+	Count      int
+	Cumulative int
+}
+
+func (h *Histogram) Add(values ...float64) {
+	for _, v := range values {
+		i := sort.SearchFloat64s(h.bounds, v)
+		h.counts[i]++
+		h.count++
+		h.sum += v
+	}
+}
+
+// Merge adds the counts of other, which must use the same bounds.
+func (h *Histogram) Merge(other *Histogram) error {
+	if len(h.bounds) != len(other.bounds) {
+		return fmt.Errorf("unable to merge histograms with %d and %d buckets", len(h.bounds), len(other.bounds))
+	}
+	for i, b := range h.bounds {
+		if other.bounds[i] != b {
+			return fmt.Errorf("unable to merge histograms with different bounds %v and %v", h.bounds, other.bounds)
+		}
+	}
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	h.count += other.count
+	h.sum += other.sum
+	return nil
+}
+
+func (h *Histogram) Buckets() []*Bucket {
+	out := make([]*Bucket, 0, len(h.counts))
+	cum := 0
+	for i, c := range h.counts {
+		cum += c
+		bound := math.Inf(1)
+		if i < len(h.bounds) {
+			bound = h.bounds[i]
+		}
+		out = append(out, &Bucket{UpperBound: bound, Count: c, Cumulative: cum})
+	}
+	return out
+}
+
+func (h *Histogram) Len() int {
+	return h.count
+}
+
+func (h *Histogram) Sum() float64 {
+	return h.sum
+}
+
+func (h *Histogram) String() string {
+	out := []string{}
+	for _, b := range h.Buckets() {
+		out = append(out, fmt.Sprintf("le_%g: %d", b.UpperBound, b.Count))
+	}
+	return strings.Join(out, ", ")
+}
@@ -1,41 +1,47 @@
 package stats
 
-import "time"
+import (
+	"time"
 
-func NewProgress(total int) *progress {
-	return &progress{
-		total: total, started: time.Now(),
+	"github.com/dynport/dgtk/progress"
+)
+
+// NewProgress returns a counter calculating rate and ETA with
+// progress.Progress.
+func NewProgress(total int) *progressCounter {
+	return &progressCounter{
+		total: total, progress: progress.New(progress.WithTotal(total), progress.WithoutMemStats),
 	}
 }
 
-type progress struct {
-	total   int
-	count   int
-	started time.Time
+type progressCounter struct {
+	total    int
+	progress *progress.Progress
 }
 
-func (p *progress) Inc() {
-	p.count++
+func (p *progressCounter) Inc() {
+	p.progress.Inc()
 }
 
-func (s *progress) Count() int {
-	return s.count
+func (s *progressCounter) Count() int {
+	return s.progress.Current()
 }
 
-func (s *progress) Total() int {
+func (s *progressCounter) Total() int {
 	return s.total
 }
 
-func (s *progress) Perc() float64 {
+func (s *progressCounter) Perc() float64 {
 	return 100.0 * float64(s.Count()) / float64(s.Total())
 }
 
-func (s *progress) ToGo() time.Duration {
-	pending := float64(s.total - s.count)
-	return time.Duration(pending/s.PerSecond()) * time.Second
+func (s *progressCounter) ToGo() time.Duration {
+	if eta := s.progress.Status().ETA(); eta != nil {
+		return *eta
+	}
+	return 0
 }
 
-func (s *progress) PerSecond() float64 {
-	diff := time.Since(s.started)
-	return float64(s.count) / diff.Seconds()
+func (s *progressCounter) PerSecond() float64 {
+	return s.progress.Status().CurrentRate()
 }
@@ -1,35 +1,34 @@
 package stats
 
 import (
-	"fmt"
-	"math"
-	"strings"
+	"os"
+
+	"github.com/dynport/dgtk/progress"
 )
 
+// ProgressWriter draws a progress bar for the bytes written to it on stdout.
+// It is a shim over progress.Progress.
 type ProgressWriter struct {
 	Total    int64
 	Written  int64
-	lastLine string
+	progress *progress.Progress
 }
 
 func (p *ProgressWriter) Write(b []byte) (int, error) {
+	if p.progress == nil {
+		bars := progress.NewBars(os.Stdout)
+		bars.HumanReadable = true
+		p.progress = progress.New(progress.WithTotal(int(p.Total)), progress.WithPrinter(bars.Bar("")))
+		p.progress.Start()
+	}
 	p.Written += int64(len(b))
-	perc := float64(p.Written) / float64(p.Total)
-
-	ballsCount := 64
-
-	full := int(math.Ceil(float64(ballsCount) * perc))
-	empty := ballsCount - full
-
-	prefix := "[" + strings.Repeat("*", full) + strings.Repeat("-", empty) + "]"
-
-	line := fmt.Sprintf("%s (%.02f%%)", prefix, 100.0*perc)
-	fmt.Print("\r" + strings.Repeat(" ", len(p.lastLine)) + "\r" + line)
-	p.lastLine = line
+	p.progress.IncBy(len(b))
 	return len(b), nil
 }
 
 func (p *ProgressWriter) Close() error {
-	fmt.Printf("\n")
-	return nil
+	if p.progress == nil {
+		return nil
+	}
+	return p.progress.Close()
 }
@@ -0,0 +1,172 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// ring maps points in time to a fixed number of buckets of resolution
+// length. Buckets are reused once they are older than the ring covers.
+type ring struct {
+	resolution time.Duration
+	slots      []int64
+	now        func() time.Time
+}
+
+func newRing(size, resolution time.Duration) ring {
+	if resolution <= 0 {
+		resolution = time.Second
+	}
+	n := int(size / resolution)
+	if size%resolution != 0 {
+		n++
+	}
+	// one more bucket for the one currently filled
+	return ring{resolution: resolution, slots: make([]int64, n+1), now: time.Now}
+}
+
+func (r *ring) slot(t time.Time) int64 {
+	return t.UnixNano() / int64(r.resolution)
+}
+
+// current returns the index of the bucket for now and whether it needs to be
+// reset because it was used for an older slot.
+func (r *ring) current() (idx int, reset bool) {
+	s := r.slot(r.now())
+	idx = int(s % int64(len(r.slots)))
+	if r.slots[idx] != s {
+		r.slots[idx] = s
+		return idx, true
+	}
+	return idx, false
+}
+
+// each calls f with the index of all buckets within the last d.
+func (r *ring) each(d time.Duration, f func(idx int)) {
+	cur := r.slot(r.now())
+	n := int64(d / r.resolution)
+	if d%r.resolution != 0 || n == 0 {
+		n++
+	}
+	if max := int64(len(r.slots) - 1); n > max {
+		n = max
+	}
+	for i, s := range r.slots {
+		if s > cur-n && s <= cur {
+			f(i)
+		}
+	}
+}
+
+// NewWindow creates rolling statistics over the last size, split into
+// buckets of resolution length. NewWindow(15*time.Minute, 10*time.Second)
+// can answer queries for the last 1, 5 and 15 minutes.
+func NewWindow(size, resolution time.Duration) *Window {
+	w := &Window{ring: newRing(size, resolution), size: size}
+	w.buckets = make([]*Digest, len(w.ring.slots))
+	return w
+}
+
+// Window keeps time bucketed digests. It is safe for concurrent use.
+type Window struct {
+	mutex   sync.Mutex
+	ring    ring
+	size    time.Duration
+	buckets []*Digest
+}
+
+func (w *Window) Add(values ...float64) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	idx, reset := w.ring.current()
+	if reset || w.buckets[idx] == nil {
+		w.buckets[idx] = NewDigest(0)
+	}
+	w.buckets[idx].Add(values...)
+}
+
+// Since returns the statistics of all values added within the last d (at
+// most the size of the window).
+func (w *Window) Since(d time.Duration) *Digest {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	out := NewDigest(0)
+	w.ring.each(d, func(idx int) {
+		if w.buckets[idx] != nil {
+			out.Merge(w.buckets[idx])
+		}
+	})
+	return out
+}
+
+// Count returns the number of values added within the last d.
+func (w *Window) Count(d time.Duration) int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	cnt := 0
+	w.ring.each(d, func(idx int) {
+		if w.buckets[idx] != nil {
+			cnt += w.buckets[idx].Len()
+		}
+	})
+	return cnt
+}
+
+// Rate returns the number of values per second added within the last d. It
+// returns 0 for d <= 0.
+func (w *Window) Rate(d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	if d > w.size {
+		d = w.size
+	}
+	return float64(w.Count(d)) / d.Seconds()
+}
+
+// NewWindowedMap creates a Map counting keys over the last size, split into
+// buckets of resolution length.
+func NewWindowedMap(size, resolution time.Duration) *WindowedMap {
+	m := &WindowedMap{ring: newRing(size, resolution)}
+	m.buckets = make([]Map, len(m.ring.slots))
+	return m
+}
+
+// WindowedMap is the rolling variant of Map. It is safe for concurrent use.
+type WindowedMap struct {
+	mutex   sync.Mutex
+	ring    ring
+	buckets []Map
+}
+
+func (m *WindowedMap) Inc(key string) {
+	m.IncBy(key, 1)
+}
+
+func (m *WindowedMap) IncBy(key string, value int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	idx, reset := m.ring.current()
+	if reset || m.buckets[idx] == nil {
+		m.buckets[idx] = Map{}
+	}
+	m.buckets[idx].IncBy(key, value)
+}
+
+// Since returns the counts of all keys within the last d.
+func (m *WindowedMap) Since(d time.Duration) Map {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	out := Map{}
+	m.ring.each(d, func(idx int) {
+		for k, v := range m.buckets[idx] {
+			out.IncBy(k, v.Value)
+		}
+	})
+	return out
+}
+
+// TopN returns the n keys with the highest counts within the last d.
+func (m *WindowedMap) TopN(d time.Duration, n int) Values {
+	return m.Since(d).ReversedValues().TopN(n)
+}
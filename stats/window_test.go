@@ -0,0 +1,96 @@
+package stats
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	mutex sync.Mutex
+	t     time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.t
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func TestWindow(t *testing.T) {
+	clock := &testClock{t: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}
+	w := NewWindow(15*time.Minute, 10*time.Second)
+	w.ring.now = clock.Now
+
+	// one value per second for 20 minutes, value is the minute
+	for i := 0; i < 20*60; i++ {
+		w.Add(float64(i / 60))
+		clock.Add(time.Second)
+	}
+	clock.Add(-time.Second)
+
+	tests := []struct{ Has, Want interface{} }{
+		{w.Count(time.Minute), 60},
+		{w.Count(5 * time.Minute), 300},
+		{w.Count(15 * time.Minute), 900},
+		{w.Count(time.Hour), 900},
+		{w.Rate(5 * time.Minute), 1.0},
+		{w.Rate(0), 0.0},
+		{w.Rate(-time.Minute), 0.0},
+		{w.Since(time.Minute).Min(), 19.0},
+		{w.Since(5 * time.Minute).Min(), 15.0},
+		{w.Since(15 * time.Minute).Max(), 19.0},
+		{w.Since(15 * time.Minute).Min(), 5.0},
+	}
+	for i, tc := range tests {
+		if tc.Want != tc.Has {
+			t.Errorf("%d: want %#v, was %#v", i+1, tc.Want, tc.Has)
+		}
+	}
+
+	clock.Add(time.Hour)
+	if cnt := w.Count(15 * time.Minute); cnt != 0 {
+		t.Errorf("expected window to be empty after an hour, got %d", cnt)
+	}
+}
+
+func TestWindowedMap(t *testing.T) {
+	clock := &testClock{t: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}
+	m := NewWindowedMap(5*time.Minute, time.Minute)
+	m.ring.now = clock.Now
+
+	m.IncBy("old", 100)
+	clock.Add(10 * time.Minute)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Inc("a")
+			m.IncBy("b", 2)
+		}()
+	}
+	wg.Wait()
+	m.Inc("c")
+
+	top := m.TopN(5*time.Minute, 2)
+	tests := []struct{ Has, Want interface{} }{
+		{len(top), 2},
+		{top[0].Key, "b"},
+		{top[0].Value, 20},
+		{top[1].Key, "a"},
+		{top[1].Value, 10},
+		{m.Since(time.Hour)["old"] == nil, true},
+	}
+	for i, tc := range tests {
+		if tc.Want != tc.Has {
+			t.Errorf("%d: want %#v, was %#v", i+1, tc.Want, tc.Has)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package txdbg
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dynport/dgtk/gosql"
+	"github.com/dynport/dgtk/gosql/dialect"
+)
+
+type Option func(*console)
+
+// WithDialect sets the dialect of the transaction used to browse the schema
+// and explain queries. Defaults to Postgres as a transaction does not expose
+// its driver.
+func WithDialect(d dialect.Dialect) Option {
+	return func(c *console) {
+		c.dialect = d
+	}
+}
+
+type console struct {
+	tx         *sql.Tx
+	query      string // default query
+	queryRan   bool   // the default query only runs on the first render
+	dialect    dialect.Dialect
+	mu         sync.Mutex // the transaction can only run one statement at a time
+	savepoints int
+	history    []*historyEntry
+	closed     chan struct{}
+	closeOnce  sync.Once
+}
+
+type historyEntry struct {
+	Query    string
+	Started  time.Time
+	Duration time.Duration
+	Rows     int
+	Error    string
+}
+
+func newConsole(tx *sql.Tx, query string, opts ...Option) *console {
+	c := &console{tx: tx, query: query, dialect: dialect.Default, closed: make(chan struct{})}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+func (c *console) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", c.index)
+	mux.HandleFunc("/table", c.table)
+	mux.HandleFunc("/export", c.export)
+	return mux
+}
+
+type page struct {
+	Query   string
+	Table   *table
+	Error   string
+	Columns []*gosql.Column // of the selected table
+	Tables  []*gosql.Table
+	History []*historyEntry
+}
+
+func (c *console) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.Form.Get("quit") == "true" {
+		io.WriteString(w, "Quit")
+		c.closeOnce.Do(func() { close(c.closed) })
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := &page{Query: r.Form.Get("query")}
+	run := p.Query != ""
+	if !run {
+		p.Query = c.query
+		run = c.query != "" && !c.queryRan
+		c.queryRan = true
+	}
+	explain := r.Form.Get("explain") == "true"
+	if run && r.Method != http.MethodPost && r.Form.Get("query") != "" && !explain && !isQuery(p.Query) {
+		// reloads, history links and prefetches must not repeat statements
+		p.Error = "statements are only executed when submitted with Run"
+		run = false
+	}
+	if run {
+		q := p.Query
+		if explain {
+			q = c.explain(q)
+		}
+		var err error
+		if p.Table, err = c.load(q); err != nil {
+			p.Error = err.Error()
+		}
+	}
+	c.render(w, p)
+}
+
+func (c *console) table(w http.ResponseWriter, r *http.Request) {
+	schema, name := r.FormValue("schema"), r.FormValue("name")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := &page{Query: "SELECT * FROM " + qualifiedName(schema, name) + " LIMIT 100"}
+	err := c.savepoint(func() (err error) {
+		p.Columns, err = gosql.Columns(gosql.WithDialect(c.tx, c.dialect), gosql.WithSchema(schema), gosql.WithName(name))
+		return err
+	})
+	if err != nil {
+		p.Error = err.Error()
+	}
+	c.render(w, p)
+}
+
+// export writes the result of a query as CSV or JSON (an array of objects).
+// Only queries are exported, the export buttons submit the textarea again and
+// must not repeat statements modifying the transaction.
+func (c *console) export(w http.ResponseWriter, r *http.Request) {
+	format := r.FormValue("format")
+	if format != "csv" && format != "json" {
+		http.Error(w, "format must be csv or json", http.StatusBadRequest)
+		return
+	}
+	if !isQuery(r.FormValue("query")) {
+		http.Error(w, "only SELECT statements can be exported", http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var values []value
+	var names []string
+	err := c.run(r.FormValue("query"), func() (err error) {
+		values, names, err = LoadQuery(c.tx, r.FormValue("query"))
+		return err
+	}, func() int { return len(values) })
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Disposition", "attachment; filename=result."+format)
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		if values == nil {
+			values = []value{}
+		}
+		json.NewEncoder(w).Encode(values)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	cw := csv.NewWriter(w)
+	cw.Write(names)
+	for _, v := range values {
+		rec := row{}
+		for _, n := range names {
+			if v[n] != nil {
+				rec = append(rec, valueToString(v[n]))
+			} else {
+				rec = append(rec, "")
+			}
+		}
+		cw.Write(rec)
+	}
+	cw.Flush()
+}
+
+var queryKeywords = []string{"SELECT", "WITH", "VALUES", "TABLE"}
+
+func isQuery(q string) bool {
+	fields := strings.Fields(q)
+	if len(fields) == 0 {
+		return false
+	}
+	for _, k := range queryKeywords {
+		if strings.EqualFold(fields[0], k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *console) load(q string) (*table, error) {
+	t := &table{}
+	err := c.run(q, func() error {
+		values, names, err := LoadQuery(c.tx, q)
+		if err != nil {
+			return err
+		}
+		t.Header = names
+		for _, v := range values {
+			r := row{}
+			for _, n := range names {
+				r = append(r, valueToString(v[n]))
+			}
+			t.Rows = append(t.Rows, r)
+		}
+		return nil
+	}, func() int { return len(t.Rows) })
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// run executes f in a savepoint and records q in the history.
+func (c *console) run(q string, f func() error, rows func() int) error {
+	h := &historyEntry{Query: q, Started: time.Now()}
+	err := c.savepoint(f)
+	h.Duration = time.Since(h.Started)
+	if err != nil {
+		h.Error = err.Error()
+	} else {
+		h.Rows = rows()
+	}
+	c.history = append(c.history, h)
+	return err
+}
+
+// savepoint executes f in a savepoint which is rolled back when f fails, a
+// failed statement would abort the whole transaction otherwise.
+func (c *console) savepoint(f func() error) error {
+	c.savepoints++
+	name := fmt.Sprintf("txdbg_%d", c.savepoints)
+	if _, err := c.tx.Exec("SAVEPOINT " + name); err != nil {
+		return err
+	}
+	if err := f(); err != nil {
+		if _, rerr := c.tx.Exec("ROLLBACK TO SAVEPOINT " + name); rerr != nil {
+			return fmt.Errorf("%s (rolling back to savepoint: %s)", err, rerr)
+		}
+		c.tx.Exec("RELEASE SAVEPOINT " + name)
+		return err
+	}
+	_, err := c.tx.Exec("RELEASE SAVEPOINT " + name)
+	return err
+}
+
+func (c *console) explain(q string) string {
+	if c.dialect == dialect.SQLite {
+		return "EXPLAIN QUERY PLAN " + q
+	}
+	return "EXPLAIN " + q
+}
+
+// tables returns all tables but the ones of the system schemas.
+func (c *console) tables() ([]*gosql.Table, error) {
+	var all []*gosql.Table
+	err := c.savepoint(func() (err error) {
+		all, err = gosql.Tables(gosql.WithDialect(c.tx, c.dialect))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := []*gosql.Table{}
+	for _, t := range all {
+		switch t.TableSchema {
+		case "pg_catalog", "information_schema", "mysql", "performance_schema", "sys":
+			continue
+		}
+		out = append(out, t)
+	}
+	sort.Slice(out, func(a, b int) bool {
+		if out[a].TableSchema != out[b].TableSchema {
+			return out[a].TableSchema < out[b].TableSchema
+		}
+		return out[a].TableName < out[b].TableName
+	})
+	return out, nil
+}
+
+func (c *console) render(w http.ResponseWriter, p *page) {
+	var err error
+	if p.Tables, err = c.tables(); err != nil && p.Error == "" {
+		p.Error = "loading tables: " + err.Error()
+	}
+	for i := len(c.history) - 1; i >= 0; i-- {
+		p.History = append(p.History, c.history[i])
+	}
+	if err := consoleTpl.Execute(w, p); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func qualifiedName(schema, name string) string {
+	if schema == "" {
+		return name
+	}
+	return schema + "." + name
+}
+
+var consoleTpl = template.Must(template.New("console").Funcs(template.FuncMap{
+	"qualifiedName": qualifiedName,
+	"deref": func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	},
+}).Parse(`<html>
+<head>
+<style>
+body { font-family: sans-serif; font-size: 13px; margin: 0; display: flex; }
+#tables { width: 220px; padding: 8px; border-right: 1px solid #ccc; height: 100vh; overflow: auto; }
+#main { flex: 1; padding: 8px; overflow: auto; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 2px 4px; font-family: monospace; white-space: pre; text-align: left; vertical-align: top; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+
+<div id="tables">
+<b>Tables</b>
+<ul>
+{{ range .Tables }}<li><a href="/table?schema={{ .TableSchema }}&amp;name={{ .TableName }}">{{ qualifiedName .TableSchema .TableName }}</a>{{ if ne .TableType "BASE TABLE" }} <i>{{ .TableType }}</i>{{ end }}</li>
+{{ end }}</ul>
+</div>
+
+<div id="main">
+<form method="post" action="/" id="query_form">
+<textarea id="query_text" style="width:100%;height:200px" name="query">{{ .Query }}</textarea>
+<input type="submit" value="Run" />
+<button type="submit" name="explain" value="true">Explain</button>
+<button type="submit" formaction="/export" name="format" value="csv">Export CSV</button>
+<button type="submit" formaction="/export" name="format" value="json">Export JSON</button>
+</form>
+
+<form method="post" action="/">
+<input type="submit" value="Quit" />
+<input type="hidden" name="quit" value="true" />
+</form>
+
+{{ with .Error }}<p class="error">{{ . }}</p>{{ end }}
+
+{{ with .Columns }}
+	<table>
+	<tr><th>column</th><th>type</th><th>nullable</th><th>default</th></tr>
+	{{ range . }}<tr><td>{{ .ColumnName }}</td><td>{{ .DataType }}</td><td>{{ .IsNullable }}</td><td>{{ deref .ColumnDefault }}</td></tr>
+	{{ end }}
+	</table>
+{{ end }}
+
+{{ with .Table }}
+	<p>{{ len .Rows }} rows</p>
+	<table>
+	<tr>{{ range .Header }}<th>{{ . }}</th>{{ end }}</tr>
+	{{ range .Rows }}<tr>{{ range . }}<td>{{ . }}</td>{{ end }}</tr>
+	{{ end }}
+	</table>
+{{ end }}
+
+{{ with .History }}
+	<h3>History</h3>
+	<table>
+	<tr><th>started</th><th>duration</th><th>rows</th><th>query</th></tr>
+	{{ range . }}<tr><td>{{ .Started.Format "15:04:05" }}</td><td>{{ .Duration }}</td><td>{{ if .Error }}<span class="error">{{ .Error }}</span>{{ else }}{{ .Rows }}{{ end }}</td><td><a href="/?query={{ .Query }}">{{ .Query }}</a></td></tr>
+	{{ end }}
+	</table>
+{{ end }}
+</div>
+
+<script>
+var el = document.getElementById("query_text");
+if (el != null) {
+	el.select();
+}
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,222 @@
+package txdbg
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dynport/dgtk/gosql/dialect"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestConsole(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	for _, q := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR NOT NULL, email VARCHAR)",
+		"INSERT INTO users (name, email) VALUES ('Hans', 'hans@example.com'), ('Grete', NULL)",
+	} {
+		if _, err := tx.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	closed, addr := StartServer(tx, "", WithDialect(dialect.SQLite))
+
+	get := func(path string, params url.Values) (int, string) {
+		rsp, err := http.Get(addr + path + "?" + params.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rsp.Body.Close()
+		b, err := ioutil.ReadAll(rsp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rsp.StatusCode, string(b)
+	}
+
+	tests := []struct {
+		Description string
+		Path        string
+		Params      url.Values
+		Status      int
+		Expected    []string
+	}{
+		{"tables", "/", nil, 200, []string{`<a href="/table?schema=main&amp;name=users">main.users</a>`}},
+		{"error", "/", url.Values{"query": {"SELECT * FROM missing"}}, 200, []string{"no such table: missing"}},
+		{"query after error", "/", url.Values{"query": {"SELECT name FROM users ORDER BY id"}}, 200, []string{"2 rows", "<td>Hans</td>", "<td>Grete</td>"}},
+		{"explain", "/", url.Values{"query": {"SELECT * FROM users WHERE id = 1"}, "explain": {"true"}}, 200, []string{"SEARCH users USING INTEGER PRIMARY KEY"}},
+		{"columns", "/table", url.Values{"schema": {"main"}, "name": {"users"}}, 200, []string{"<td>email</td>", "SELECT * FROM main.users LIMIT 100"}},
+		{"history", "/", url.Values{"query": {"SELECT 1"}}, 200, []string{"History", "SELECT * FROM missing", "SELECT name FROM users ORDER BY id"}},
+		{"statement over get", "/", url.Values{"query": {"DELETE FROM users"}}, 200, []string{"statements are only executed when submitted with Run", "DELETE FROM users</textarea>"}},
+		{"csv", "/export", url.Values{"query": {"SELECT name, email FROM users ORDER BY id"}, "format": {"csv"}}, 200, []string{"name,email\nHans,hans@example.com\nGrete,\n"}},
+		{"json", "/export", url.Values{"query": {"SELECT name, email FROM users ORDER BY id"}, "format": {"json"}}, 200, []string{`[{"email":"hans@example.com","name":"Hans"},{"email":null,"name":"Grete"}]`}},
+		{"export error", "/export", url.Values{"query": {"SELECT * FROM missing"}, "format": {"csv"}}, 500, []string{"no such table: missing"}},
+		{"export format", "/export", url.Values{"query": {"SELECT 1"}, "format": {"xml"}}, 400, nil},
+		{"export statement", "/export", url.Values{"query": {"DELETE FROM users"}, "format": {"csv"}}, 400, []string{"only SELECT statements"}},
+	}
+	for _, tst := range tests {
+		status, body := get(tst.Path, tst.Params)
+		if status != tst.Status {
+			t.Errorf("%s: expected status to be %d, was %d: %s", tst.Description, tst.Status, status, body)
+		}
+		for _, s := range tst.Expected {
+			if !strings.Contains(body, s) {
+				t.Errorf("%s: expected body to contain %q, was\n%s", tst.Description, s, body)
+			}
+		}
+	}
+
+	var cnt int
+	if err := tx.QueryRow("SELECT COUNT(1) FROM users").Scan(&cnt); err != nil {
+		t.Fatalf("expected transaction to be usable after errors: %s", err)
+	}
+	if cnt != 2 {
+		t.Errorf("expected 2 users, got %d", cnt)
+	}
+
+	rsp, err := http.PostForm(addr+"/", url.Values{"quit": {"true"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	select {
+	case <-closed:
+	default:
+		t.Errorf("expected server to be closed after quit")
+	}
+}
+
+func get(t *testing.T, addr string, params url.Values) string {
+	rsp, err := http.Get(addr + "/?" + params.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	b, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestConsoleDefaultQuery(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("CREATE TABLE events (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	c := newConsole(tx, "INSERT INTO events DEFAULT VALUES RETURNING id", WithDialect(dialect.SQLite))
+	s := httptest.NewServer(c.handler())
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+		if body := get(t, s.URL, nil); !strings.Contains(body, "INSERT INTO events") {
+			t.Errorf("expected default query in the textarea, was\n%s", body)
+		}
+	}
+	var cnt int
+	if err := tx.QueryRow("SELECT COUNT(1) FROM events").Scan(&cnt); err != nil {
+		t.Fatal(err)
+	}
+	if cnt != 1 {
+		t.Errorf("expected default query to run once, ran %d times", cnt)
+	}
+}
+
+func testDatabaseURL() string {
+	if env := os.Getenv("TEST_DATABASE_URL"); env != "" {
+		return env
+	}
+	return "postgres://127.0.0.1/template1?sslmode=disable"
+}
+
+func TestConsolePostgres(t *testing.T) {
+	if os.Getenv("TEST_WITH_DB") != "true" {
+		t.SkipNow()
+	}
+	db, err := sql.Open("postgres", testDatabaseURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("CREATE TEMPORARY TABLE txdbg_users (id SERIAL PRIMARY KEY, name VARCHAR NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("INSERT INTO txdbg_users (name) VALUES ('Hans'), ('Grete')"); err != nil {
+		t.Fatal(err)
+	}
+	_, addr := StartServer(tx, "")
+
+	if body := get(t, addr, url.Values{"query": {"SELECT * FROM missing"}}); !strings.Contains(body, `relation &#34;missing&#34; does not exist`) {
+		t.Errorf("expected error to be rendered, was\n%s", body)
+	}
+	body := get(t, addr, url.Values{"query": {"SELECT name FROM txdbg_users ORDER BY id"}})
+	for _, s := range []string{"2 rows", "<td>Hans</td>", "<td>Grete</td>"} {
+		if !strings.Contains(body, s) {
+			t.Errorf("expected body to contain %q after a failed query, was\n%s", s, body)
+		}
+	}
+}
+
+func TestConsoleStatements(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("CREATE TABLE events (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	_, addr := StartServer(tx, "", WithDialect(dialect.SQLite))
+	insert := url.Values{"query": {"INSERT INTO events DEFAULT VALUES"}}
+
+	// e.g. a history link or a reload
+	get(t, addr, insert)
+	rsp, err := http.PostForm(addr+"/", insert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+
+	var cnt int
+	if err := tx.QueryRow("SELECT COUNT(1) FROM events").Scan(&cnt); err != nil {
+		t.Fatal(err)
+	}
+	if cnt != 1 {
+		t.Errorf("expected statement to only run when posted, ran %d times", cnt)
+	}
+}
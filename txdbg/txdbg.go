@@ -3,23 +3,19 @@
 package txdbg
 
 import (
-	"bytes"
 	"database/sql"
 	"fmt"
-	"io"
-	"net/http"
 	"net/http/httptest"
 	"os/exec"
-	"text/template"
 	"time"
 )
 
-func Open(tx *sql.Tx) error {
-	return OpenWithQuery(tx, "")
+func Open(tx *sql.Tx, opts ...Option) error {
+	return OpenWithQuery(tx, "", opts...)
 }
 
-func OpenWithQuery(tx *sql.Tx, query string) error {
-	cc, a := StartServer(tx, query)
+func OpenWithQuery(tx *sql.Tx, query string, opts ...Option) error {
+	cc, a := StartServer(tx, query, opts...)
 	cmd, err := openCommand()
 	if err != nil {
 		return err
@@ -29,78 +25,15 @@ func OpenWithQuery(tx *sql.Tx, query string) error {
 	return nil
 }
 
-// StartServer starts a new http server for the given transaction
+// StartServer starts a new http server with a SQL console for the given transaction
 //
-// The server shuts down when a) the user presses the "quit" button or b) on the first db error (as we can not recover from that error inside the transaction)
-func StartServer(tx *sql.Tx, query string) (waitForClose chan struct{}, address string) {
-	cc := make(chan struct{})
-	var s *httptest.Server
-	handler := func(w http.ResponseWriter, r *http.Request) {
-		doClose := func(err error) {
-			msg := "Quit"
-			if err != nil {
-				msg = "Abort due to error: " + err.Error()
-			}
-			io.WriteString(w, msg)
-			close(cc)
-		}
-		err := func() error {
-			err := r.ParseForm()
-			if err != nil {
-				return err
-			}
-			if r.Form.Get("quit") == "true" {
-				doClose(nil)
-				return nil
-			}
-			ctx := &debugContext{
-				Query: r.Form.Get("query"),
-			}
-			if ctx.Query == "" {
-				ctx.Query = query
-			}
-			if ctx.Query != "" {
-				ctx.Table, err = loadQuery(tx, ctx.Query)
-				if err != nil {
-					doClose(err)
-					return nil
-				}
-			}
-			tpl, err := template.New("index").Parse(txDebugTpl)
-			if err != nil {
-				return err
-			}
-			buf := &bytes.Buffer{}
-			err = tpl.Execute(buf, ctx)
-			if err != nil {
-				return err
-			}
-			io.Copy(w, buf)
-			return nil
-		}()
-		if err != nil {
-			http.Error(w, err.Error(), 500)
-			return
-		}
-	}
-	s = httptest.NewServer(http.HandlerFunc(handler))
-	return cc, s.URL
-}
-
-func loadQuery(tx *sql.Tx, q string, args ...interface{}) (*table, error) {
-	values, names, err := LoadQuery(tx, q, args...)
-	if err != nil {
-		return nil, err
-	}
-	t := &table{Header: names}
-	for _, v := range values {
-		r := row{}
-		for _, n := range names {
-			r = append(r, valueToString(v[n]))
-		}
-		t.Rows = append(t.Rows, r)
-	}
-	return t, nil
+// Every query runs inside a savepoint which is rolled back on errors so the transaction stays usable. The console lists
+// the tables of the transaction, shows EXPLAIN output, keeps a history of all queries and exports results as CSV or JSON.
+// The server shuts down when the user presses the "quit" button.
+func StartServer(tx *sql.Tx, query string, opts ...Option) (waitForClose chan struct{}, address string) {
+	c := newConsole(tx, query, opts...)
+	s := httptest.NewServer(c.handler())
+	return c.closed, s.URL
 }
 
 func LoadQuery(tx *sql.Tx, q string, args ...interface{}) (values []value, names []string, err error) {
@@ -142,11 +75,6 @@ func LoadQuery(tx *sql.Tx, q string, args ...interface{}) (values []value, names
 
 type value map[string]interface{}
 
-type debugContext struct {
-	Query string
-	Table *table
-}
-
 type table struct {
 	Header []string
 	Rows   []row
@@ -154,37 +82,6 @@ type table struct {
 
 type row []string
 
-const txDebugTpl = `
-<html>
-<body>
-
-<form method="get" id="query_form">
-<textarea id="query_text" style="width:100%;height:300px" name="query">{{ .Query }}</textarea>
-<input type="submit" value="Send" />
-</form>
-
-<form method="post">
-<input type="submit" value="Quit" />
-<input type="hidden" name="quit" value="true" />
-</form>
-
-{{ with .Table }}
-	<table border=1>
-	<tr>{{ range .Header }}<th>{{ . }}{{ end }}</tr>
-	{{ range .Rows }}
-		<tr>{{ range . }}<td>{{ . }}{{ end }}
-	{{ end }}
-	</table>
-{{ end }}
-
-<script>
-var el = document.getElementById("query_text");
-if (el != null) {
-	el.select();
-}
-</script>
-`
-
 func valueToString(i interface{}) string {
 	switch c := i.(type) {
 	case []uint8:
@@ -50,7 +50,7 @@ func ExampleStartServer() {
 
 	waitForClose, addr := StartServer(tx, "")
 	openURL(addr)
-	<-waitForClose // blocks until the QUIT button is pressed or first error occurs on tx
+	<-waitForClose // blocks until the QUIT button is pressed
 }
 
 // openURL tries to open the url with open, xdg-open, etc.
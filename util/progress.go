@@ -1,62 +1,64 @@
 package util
 
 import (
-	"fmt"
 	"io"
 	"os"
-	"strconv"
-	"strings"
 	"time"
+
+	"github.com/dynport/dgtk/progress"
 )
 
 func NewProgress() *Progress {
 	return &Progress{}
 }
 
+// Progress is a shim over progress.Progress. Counts sent to the channel
+// returned by Start are added to the progress, strings sent to Suffix are
+// printed after the status.
 type Progress struct {
-	Total         int64
-	Writer        io.Writer
-	Frequency     time.Duration
-	Suffix        chan string
-	current       int64
-	c             chan int64
-	finished      chan struct{}
-	started       time.Time
-	last          string
-	currentSuffix string
-	closed        bool
+	Total     int64
+	Writer    io.Writer
+	Frequency time.Duration
+	Suffix    chan string
+	c         chan int64
+	finished  chan struct{}
+	progress  *progress.Progress
+	closed    bool
 }
 
 func (p *Progress) Start() chan int64 {
 	p.c = make(chan int64)
 	p.finished = make(chan struct{})
 	p.Suffix = make(chan string)
-	p.started = time.Now()
 	if p.Writer == nil {
 		p.Writer = os.Stdout
 	}
+	f := p.Frequency
+	if f == 0 {
+		f = 100 * time.Millisecond
+	}
+	bars := progress.NewBars(p.Writer)
+	bars.LogInterval = f // also print a line every Frequency when not on a terminal
+	p.progress = progress.New(
+		progress.WithTotal(int(p.Total)),
+		progress.WithInterval(f),
+		progress.WithPrinter(bars.Bar("")),
+	)
+	p.progress.Start()
 	go func() {
-		f := p.Frequency
-		if f == 0 {
-			f = 100 * time.Millisecond
-		}
-		t := time.Tick(f)
 		defer func() {
-			p.flush()
-			fmt.Fprintf(p.Writer, "\n")
+			p.progress.Close()
 			close(p.finished)
 		}()
 		for {
 			select {
-			case <-t:
-				p.flush()
 			case s := <-p.Suffix:
-				p.currentSuffix = s
+				p.progress.SetMessage(s)
 			case i, ok := <-p.c:
-				p.current += int64(i)
 				if !ok {
 					return
 				}
+				p.progress.IncBy(int(i))
 			}
 		}
 	}()
@@ -72,27 +74,3 @@ func (p *Progress) Close() error {
 	p.closed = true
 	return nil
 }
-
-func (p *Progress) flush() error {
-	diff := time.Since(p.started)
-	perSecond := float64(p.current) / diff.Seconds()
-	parts := []string{
-		fmt.Sprintf("%.0f", diff.Seconds()),
-		strconv.FormatInt(p.current, 10),
-		fmt.Sprintf("%.03f/second", perSecond),
-	}
-	if p.Total > 0 {
-		perc := fmt.Sprintf("%.1f%%", 100.0*float64(p.current)/float64(p.Total))
-		pending := p.Total - p.current
-		toGo := time.Duration(float64(pending)/perSecond) * time.Second
-		parts = append(parts, perc, toGo.String())
-	}
-	line := fmt.Sprint(strings.Join(parts, " "))
-	if p.currentSuffix != "" {
-		line += " " + p.currentSuffix
-	}
-	clear := fmt.Sprintf("\r%s\r", strings.Repeat(" ", len(p.last)))
-	p.last = line
-	_, e := fmt.Fprint(p.Writer, clear+line)
-	return e
-}
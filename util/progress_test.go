@@ -0,0 +1,25 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressFrequency(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := &Progress{Total: 100, Writer: buf, Frequency: 5 * time.Millisecond}
+	c := p.Start()
+	for i := 0; i < 10; i++ {
+		c <- 1
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// lines are printed every Frequency, not every 10s like with the default
+	if lines := strings.Count(buf.String(), "\n"); lines < 3 {
+		t.Errorf("expected at least 3 lines, got %d: %q", lines, buf.String())
+	}
+}